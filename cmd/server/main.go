@@ -9,9 +9,9 @@ import (
 	"syscall"
 	"time"
 
+	"chat_app/internal/app"
 	"chat_app/internal/config"
 	"chat_app/internal/handlers"
-	"chat_app/internal/ws"
 	"chat_app/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -36,14 +36,22 @@ func main() {
 	}
 	defer config.CloseDatabase(db)
 
+	redisClient := config.NewRedisClient(cfg.Redis)
+	defer redisClient.Close()
+
+	container, err := app.NewContainer(cfg, db, redisClient, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize application: ", err)
+	}
+
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	container.Start(background)
+
 	router := gin.New()
 	router.Use(gin.Recovery())
 
-	handlers.SetupRoutes(router, db, nil, logger)
-
-	redisClient := config.NewRedisClient(cfg.Redis)
-	_ = redisClient
-	_ = ws.NewHub
+	handlers.SetupRoutes(router, container)
 
 	server := &http.Server{
 		Addr:         cfg.Server.Host + ":" + cfg.Server.Port,
@@ -72,6 +80,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
+	stopBackground()
 
 	logger.Info("Server exited")
 }
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"chat_app/internal/auth"
+	"chat_app/internal/config"
+	"chat_app/internal/repositories"
+	"chat_app/internal/services"
+	"chat_app/internal/storage"
+	"chat_app/internal/ws"
+	"chat_app/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Container owns the shared infrastructure clients and every repository and service built
+// on top of them, so each dependency is constructed exactly once per process.
+type Container struct {
+	Config *config.Config
+	DB     *sql.DB
+	Redis  *redis.Client
+	Logger *logger.Logger
+	// Hub fans realtime events out to WebSocket connections; services push through it too
+	Hub *ws.Hub
+
+	Repositories Repositories
+	Services     Services
+}
+
+type Repositories struct {
+	Users         repositories.UserRepository
+	Sessions      repositories.SessionRepository
+	Rooms         repositories.RoomRepository
+	Messages      repositories.MessageRepository
+	RoomMembers   repositories.RoomMemberRepository
+	Invites       repositories.InviteRepository
+	JoinRequests  repositories.JoinRequestRepository
+	Sanctions     repositories.SanctionRepository
+	Audit         repositories.AuditRepository
+	Reactions     repositories.ReactionRepository
+	Mentions      repositories.MentionRepository
+	Notifications repositories.NotificationRepository
+	Search        repositories.SearchRepository
+	Attachments   repositories.AttachmentRepository
+}
+
+type Services struct {
+	Auth          services.AuthService
+	Users         services.UserService
+	Rooms         services.RoomService
+	Invites       services.InviteService
+	Messages      services.MessageService
+	Audit         services.AuditService
+	Conversations services.ConversationService
+	Search        services.SearchService
+	Attachments   services.AttachmentService
+	Images        services.ImageProcessor
+	Notifications services.NotificationService
+}
+
+func NewContainer(cfg *config.Config, db *sql.DB, redisClient *redis.Client, logger *logger.Logger) (*Container, error) {
+	tokens, err := auth.NewTokenManager(cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize token manager: %w", err)
+	}
+
+	repos := Repositories{
+		Users:         repositories.NewUserRepository(db),
+		Sessions:      repositories.NewSessionRepository(db),
+		Rooms:         repositories.NewRoomRepository(db),
+		Messages:      repositories.NewMessageRepository(db),
+		RoomMembers:   repositories.NewRoomMemberRepository(db),
+		Invites:       repositories.NewInviteRepository(db),
+		JoinRequests:  repositories.NewJoinRequestRepository(db),
+		Sanctions:     repositories.NewSanctionRepository(db),
+		Audit:         repositories.NewAuditRepository(db),
+		Reactions:     repositories.NewReactionRepository(db),
+		Mentions:      repositories.NewMentionRepository(db),
+		Notifications: repositories.NewNotificationRepository(db),
+		Attachments:   repositories.NewAttachmentRepository(db),
+	}
+	switch cfg.Search.Backend {
+	case "", "mysql":
+		repos.Search = repositories.NewMySQLSearchRepository(db)
+	case "memory":
+		repos.Search = repositories.NewMemorySearchRepository()
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", cfg.Search.Backend)
+	}
+
+	store, err := newStorage(cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize attachment storage: %w", err)
+	}
+	signer, err := auth.NewDownloadSigner(cfg.Storage, cfg.JWT.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize download signer: %w", err)
+	}
+
+	hub := ws.NewHub()
+	audit := services.NewAuditService(repos.Audit, repos.Users, repos.RoomMembers, logger)
+	notifications := services.NewNotificationService(repos.Notifications, repos.RoomMembers, hub, logger)
+	rooms := services.NewRoomService(repos.Rooms, repos.RoomMembers, repos.JoinRequests, repos.Sanctions, notifications, audit, hub, logger)
+	images := services.NewImageProcessor(repos.Attachments, store, hub, logger, cfg.Storage)
+	attachments := services.NewAttachmentService(repos.Attachments, repos.Messages, repos.RoomMembers, repos.Sanctions, store, signer, images, cfg.Storage)
+	svcs := Services{
+		Auth:          services.NewAuthService(repos.Users, repos.Sessions, tokens, logger),
+		Users:         services.NewUserService(repos.Users, repos.Sessions, rooms),
+		Rooms:         rooms,
+		Invites:       services.NewInviteService(repos.Invites, repos.Rooms, repos.RoomMembers, repos.Sanctions, audit),
+		Messages:      services.NewMessageService(repos.Messages, repos.Rooms, repos.RoomMembers, repos.Users, repos.Sanctions, repos.Reactions, repos.Mentions, repos.Search, attachments, notifications, audit, hub, redisClient),
+		Audit:         audit,
+		Conversations: services.NewConversationService(repos.Rooms, repos.RoomMembers, repos.Users, hub),
+		Search:        services.NewSearchService(repos.Search, repos.Messages, repos.RoomMembers, repos.Users),
+		Attachments:   attachments,
+		Images:        images,
+		Notifications: notifications,
+	}
+
+	return &Container{
+		Config:       cfg,
+		DB:           db,
+		Redis:        redisClient,
+		Logger:       logger,
+		Hub:          hub,
+		Repositories: repos,
+		Services:     svcs,
+	}, nil
+}
+
+// Start runs the background work the services rely on: the hub, its fan-out to other
+// instances and image processing. All of it stops when ctx ends.
+func (c *Container) Start(ctx context.Context) {
+	if err := c.Hub.EnableRedis(ctx, c.Redis, c.Config.Redis); err != nil {
+		c.Logger.WithError(err).Warn("Redis fan-out disabled; realtime delivery is limited to this instance")
+	}
+	go c.Hub.Run(ctx)
+	c.Services.Images.Start(ctx)
+	if err := c.Services.Rooms.RepairOwnerlessRooms(ctx); err != nil {
+		c.Logger.WithError(err).Error("Failed to repair rooms without an owner")
+	}
+}
+
+func newStorage(cfg config.StorageConfig) (storage.Storage, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return storage.NewLocalStorage(cfg.LocalDir)
+	case "s3":
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
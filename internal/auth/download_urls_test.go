@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"chat_app/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadSignatureRoundTrip(t *testing.T) {
+	signer, err := NewDownloadSigner(config.StorageConfig{URLTTL: time.Minute}, "secret")
+	require.NoError(t, err)
+
+	expires, signature := signer.Sign(7, 3)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expires, 2*time.Second)
+	assert.NoError(t, signer.Verify(7, 3, expires.Unix(), signature))
+
+	// The signature is bound to the attachment, the user and the expiry
+	assert.Error(t, signer.Verify(8, 3, expires.Unix(), signature))
+	assert.Error(t, signer.Verify(7, 4, expires.Unix(), signature))
+	assert.Error(t, signer.Verify(7, 3, expires.Unix()+60, signature))
+}
+
+func TestDownloadSignatureExpires(t *testing.T) {
+	signer, err := NewDownloadSigner(config.StorageConfig{URLTTL: time.Minute}, "secret")
+	require.NoError(t, err)
+
+	expires, signature := signer.Sign(7, 3)
+	signer.now = func() time.Time { return expires.Add(time.Second) }
+	assert.Error(t, signer.Verify(7, 3, expires.Unix(), signature))
+}
+
+func TestDownloadSignerKeys(t *testing.T) {
+	_, err := NewDownloadSigner(config.StorageConfig{}, "")
+	assert.Error(t, err)
+
+	derived, err := NewDownloadSigner(config.StorageConfig{}, "secret")
+	require.NoError(t, err)
+	explicit, err := NewDownloadSigner(config.StorageConfig{URLSigningKey: "secret"}, "secret")
+	require.NoError(t, err)
+
+	// The derived key is not the JWT secret itself
+	expires, signature := derived.Sign(1, 1)
+	assert.Error(t, explicit.Verify(1, 1, expires.Unix(), signature))
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"chat_app/internal/config"
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+type Claims struct {
+	UserID    int    `json:"uid"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+type TokenManager struct {
+	accessMethod    jwt.SigningMethod
+	accessSignKey   interface{}
+	accessVerifyKey interface{}
+	refreshKey      []byte
+	issuer          string
+	accessTTL       time.Duration
+	refreshTTL      time.Duration
+}
+
+func NewTokenManager(cfg config.JWTConfig) (*TokenManager, error) {
+	m := &TokenManager{
+		issuer:     cfg.Issuer,
+		accessTTL:  cfg.Expiration,
+		refreshTTL: cfg.RefreshExpiry,
+	}
+
+	if err := m.loadAccessKeys(cfg); err != nil {
+		return nil, err
+	}
+
+	m.refreshKey = []byte(cfg.RefreshSecretKey)
+	if len(m.refreshKey) == 0 {
+		// Derive a distinct key so a leaked access secret cannot mint refresh tokens on its own
+		// and an access token can never verify as a refresh token.
+		if cfg.SecretKey == "" {
+			return nil, fmt.Errorf("JWT_REFRESH_SECRET or JWT_SECRET is required")
+		}
+		mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+		mac.Write([]byte("refresh-token-key"))
+		m.refreshKey = mac.Sum(nil)
+	}
+
+	return m, nil
+}
+
+func (m *TokenManager) loadAccessKeys(cfg config.JWTConfig) error {
+	switch cfg.Algorithm {
+	case "", "HS256":
+		if cfg.SecretKey == "" {
+			return fmt.Errorf("JWT_SECRET is required for HS256")
+		}
+		m.accessMethod = jwt.SigningMethodHS256
+		m.accessSignKey = []byte(cfg.SecretKey)
+		m.accessVerifyKey = m.accessSignKey
+		return nil
+
+	case "RS256":
+		privatePEM, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read JWT private key: %w", err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return fmt.Errorf("failed to parse RSA private key: %w", err)
+		}
+		m.accessMethod = jwt.SigningMethodRS256
+		m.accessSignKey = privateKey
+		m.accessVerifyKey = &privateKey.PublicKey
+
+		if cfg.PublicKeyFile != "" {
+			publicPEM, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read JWT public key: %w", err)
+			}
+			if m.accessVerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM); err != nil {
+				return fmt.Errorf("failed to parse RSA public key: %w", err)
+			}
+		}
+		return nil
+
+	case "EdDSA":
+		privatePEM, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read JWT private key: %w", err)
+		}
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return fmt.Errorf("failed to parse Ed25519 private key: %w", err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("unsupported Ed25519 private key")
+		}
+		m.accessMethod = jwt.SigningMethodEdDSA
+		m.accessSignKey = privateKey
+		m.accessVerifyKey = signer.Public()
+
+		if cfg.PublicKeyFile != "" {
+			publicPEM, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read JWT public key: %w", err)
+			}
+			if m.accessVerifyKey, err = jwt.ParseEdPublicKeyFromPEM(publicPEM); err != nil {
+				return fmt.Errorf("failed to parse Ed25519 public key: %w", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported JWT algorithm: %s", cfg.Algorithm)
+	}
+}
+
+func (m *TokenManager) AccessTTL() time.Duration  { return m.accessTTL }
+func (m *TokenManager) RefreshTTL() time.Duration { return m.refreshTTL }
+
+func (m *TokenManager) IssueAccessToken(user *models.User, sessionID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(m.accessTTL)
+	token := jwt.NewWithClaims(m.accessMethod, m.newClaims(user, sessionID, TokenTypeAccess, expiresAt))
+
+	signed, err := token.SignedString(m.accessSignKey)
+	if err != nil {
+		return "", time.Time{}, errors.NewInternalError("failed to sign access token", err)
+	}
+	return signed, expiresAt, nil
+}
+
+func (m *TokenManager) IssueRefreshToken(user *models.User, sessionID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(m.refreshTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, m.newClaims(user, sessionID, TokenTypeRefresh, expiresAt))
+
+	signed, err := token.SignedString(m.refreshKey)
+	if err != nil {
+		return "", time.Time{}, errors.NewInternalError("failed to sign refresh token", err)
+	}
+	return signed, expiresAt, nil
+}
+
+func (m *TokenManager) ParseAccessToken(tokenString string) (*Claims, error) {
+	return m.parse(tokenString, TokenTypeAccess, m.accessMethod.Alg(), m.accessVerifyKey)
+}
+
+func (m *TokenManager) ParseRefreshToken(tokenString string) (*Claims, error) {
+	return m.parse(tokenString, TokenTypeRefresh, jwt.SigningMethodHS256.Alg(), m.refreshKey)
+}
+
+func (m *TokenManager) parse(tokenString, tokenType, alg string, key interface{}) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims,
+		func(*jwt.Token) (interface{}, error) { return key, nil },
+		jwt.WithValidMethods([]string{alg}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(m.issuer),
+	)
+	if err != nil {
+		return nil, errors.NewUnauthorizedError("invalid "+tokenType+" token", err)
+	}
+	if claims.TokenType != tokenType || claims.SessionID == "" || claims.UserID == 0 {
+		return nil, errors.NewUnauthorizedError("invalid "+tokenType+" token", nil)
+	}
+	return claims, nil
+}
+
+func (m *TokenManager) newClaims(user *models.User, sessionID, tokenType string, expiresAt time.Time) *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		SessionID: sessionID,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
+			Issuer:    m.issuer,
+			Subject:   strconv.Itoa(user.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+}
+
+// HashToken returns the hex SHA-256 digest used to persist tokens without storing them in clear.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTokenID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
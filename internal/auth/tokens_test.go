@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chat_app/internal/config"
+	"chat_app/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		SecretKey:     "test-secret",
+		Algorithm:     "HS256",
+		Issuer:        "chat_app",
+		Expiration:    time.Minute,
+		RefreshExpiry: time.Hour,
+	}
+}
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	manager, err := NewTokenManager(testJWTConfig())
+	require.NoError(t, err)
+
+	user := &models.User{ID: 7, Username: "alice"}
+	token, expiresAt, err := manager.IssueAccessToken(user, "session-1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, time.Second)
+
+	claims, err := manager.ParseAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 7, claims.UserID)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, "session-1", claims.SessionID)
+}
+
+func TestTokenTypesAreNotInterchangeable(t *testing.T) {
+	manager, err := NewTokenManager(testJWTConfig())
+	require.NoError(t, err)
+
+	user := &models.User{ID: 7, Username: "alice"}
+	access, _, err := manager.IssueAccessToken(user, "session-1")
+	require.NoError(t, err)
+	refresh, _, err := manager.IssueRefreshToken(user, "session-1")
+	require.NoError(t, err)
+
+	_, err = manager.ParseRefreshToken(access)
+	assert.Error(t, err)
+	_, err = manager.ParseAccessToken(refresh)
+	assert.Error(t, err)
+}
+
+func TestExpiredAndTamperedTokensAreRejected(t *testing.T) {
+	cfg := testJWTConfig()
+	cfg.Expiration = -time.Minute
+	manager, err := NewTokenManager(cfg)
+	require.NoError(t, err)
+
+	expired, _, err := manager.IssueAccessToken(&models.User{ID: 1, Username: "bob"}, "s")
+	require.NoError(t, err)
+	_, err = manager.ParseAccessToken(expired)
+	assert.Error(t, err)
+
+	other, err := NewTokenManager(config.JWTConfig{SecretKey: "other", Issuer: "chat_app", Expiration: time.Minute, RefreshExpiry: time.Hour})
+	require.NoError(t, err)
+	forged, _, err := other.IssueAccessToken(&models.User{ID: 1, Username: "bob"}, "s")
+	require.NoError(t, err)
+	_, err = manager.ParseAccessToken(forged)
+	assert.Error(t, err)
+}
+
+func TestEdDSAKeyFile(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	cfg := testJWTConfig()
+	cfg.Algorithm = "EdDSA"
+	cfg.PrivateKeyFile = keyFile
+	manager, err := NewTokenManager(cfg)
+	require.NoError(t, err)
+
+	token, _, err := manager.IssueAccessToken(&models.User{ID: 3, Username: "carol"}, "session-3")
+	require.NoError(t, err)
+	claims, err := manager.ParseAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 3, claims.UserID)
+}
@@ -19,6 +19,8 @@ type Config struct {
 	Redis    RedisConfig
 	JWT      JWTConfig
 	Logging  LoggingConfig
+	Search   SearchConfig
+	Storage  StorageConfig
 }
 
 type ServerConfig struct {
@@ -45,12 +47,27 @@ type RedisConfig struct {
 	Port     string
 	Password string
 	DB       int
+
+	// Transport selects how WebSocket hubs share broadcasts: "pubsub" or "streams"
+	Transport string
+	// StreamMaxLen is the approximate number of entries kept per stream
+	StreamMaxLen int
+	// StreamShards spreads rooms over that many streams; 0 gives every room its own stream
+	StreamShards int
+	// ConsumerGroup names this instance's consumer group. It must be unique per replica and
+	// stay the same across its restarts, so the replica resumes where it left off.
+	ConsumerGroup string
 }
 
 type JWTConfig struct {
-	SecretKey     string
-	Expiration    time.Duration
-	RefreshExpiry time.Duration
+	SecretKey        string
+	RefreshSecretKey string
+	Algorithm        string
+	PrivateKeyFile   string
+	PublicKeyFile    string
+	Issuer           string
+	Expiration       time.Duration
+	RefreshExpiry    time.Duration
 }
 
 type LoggingConfig struct {
@@ -58,6 +75,39 @@ type LoggingConfig struct {
 	Format string
 }
 
+type SearchConfig struct {
+	// Backend selects the message search backend: "mysql" uses the FULLTEXT index, "memory"
+	// an in-process index of messages written since startup
+	Backend string
+}
+
+type StorageConfig struct {
+	// Backend selects where attachment contents are kept: "local" or "s3"
+	Backend  string
+	LocalDir string
+
+	// S3 settings also fit S3-compatible services such as MinIO
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+
+	// MaxUploadBytes caps a single attachment; RoomQuotaBytes caps all of a room's attachments
+	MaxUploadBytes int64
+	RoomQuotaBytes int64
+	// URLTTL is how long a signed download URL stays valid
+	URLTTL time.Duration
+	// URLSigningKey signs download URLs; when empty a key is derived from the JWT secret
+	URLSigningKey string
+
+	// ImageWorkers process uploaded images in the background, making thumbnails at most
+	// ThumbnailSize pixels a side. Images over MaxImagePixels get none.
+	ImageWorkers   int
+	ThumbnailSize  int
+	MaxImagePixels int
+}
+
 func Load() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
@@ -98,17 +148,57 @@ func loadConfig() *Config {
 			Port:     getEnv("REDIS_PORT", "6379"),
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getIntEnv("REDIS_DB", 0),
+
+			Transport:     getEnv("REDIS_TRANSPORT", "pubsub"),
+			StreamMaxLen:  getIntEnv("REDIS_STREAM_MAXLEN", 10000),
+			StreamShards:  getIntEnv("REDIS_STREAM_SHARDS", 0),
+			ConsumerGroup: getEnv("REDIS_CONSUMER_GROUP", defaultConsumerGroup()),
 		},
 		JWT: JWTConfig{
-			SecretKey:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-			Expiration:    getDurationEnv("JWT_EXPIRATION", "24h"),
-			RefreshExpiry: getDurationEnv("JWT_REFRESH_EXPIRY", "168h"),
+			SecretKey:        getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+			RefreshSecretKey: getEnv("JWT_REFRESH_SECRET", ""),
+			Algorithm:        getEnv("JWT_ALGORITHM", "HS256"),
+			PrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			PublicKeyFile:    getEnv("JWT_PUBLIC_KEY_FILE", ""),
+			Issuer:           getEnv("JWT_ISSUER", "chat_app"),
+			Expiration:       getDurationEnv("JWT_EXPIRATION", "24h"),
+			RefreshExpiry:    getDurationEnv("JWT_REFRESH_EXPIRY", "168h"),
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
+		Search: SearchConfig{
+			Backend: getEnv("SEARCH_BACKEND", "mysql"),
+		},
+		Storage: StorageConfig{
+			Backend:  getEnv("STORAGE_BACKEND", "local"),
+			LocalDir: getEnv("STORAGE_LOCAL_DIR", "data/attachments"),
+
+			S3Endpoint:  getEnv("STORAGE_S3_ENDPOINT", ""),
+			S3Bucket:    getEnv("STORAGE_S3_BUCKET", ""),
+			S3Region:    getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3AccessKey: getEnv("STORAGE_S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("STORAGE_S3_SECRET_KEY", ""),
+
+			MaxUploadBytes: int64(getIntEnv("ATTACHMENT_MAX_SIZE_MB", 25)) << 20,
+			RoomQuotaBytes: int64(getIntEnv("ATTACHMENT_ROOM_QUOTA_MB", 1024)) << 20,
+			URLTTL:         getDurationEnv("ATTACHMENT_URL_TTL", "5m"),
+			URLSigningKey:  getEnv("ATTACHMENT_URL_SIGNING_KEY", ""),
+
+			ImageWorkers:   getIntEnv("IMAGE_WORKERS", 2),
+			ThumbnailSize:  getIntEnv("IMAGE_THUMBNAIL_SIZE", 320),
+			MaxImagePixels: getIntEnv("IMAGE_MAX_PIXELS", 50_000_000),
+		},
+	}
+}
+
+// defaultConsumerGroup uses the hostname, which is unique per container or pod
+func defaultConsumerGroup() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
 	}
+	return "chat_app"
 }
 
 func getEnv(key, defaultValue string) string {
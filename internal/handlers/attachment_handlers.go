@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"chat_app/internal/models"
+	"chat_app/internal/services"
+	"chat_app/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for the multipart framing around an upload of the largest size
+const multipartOverhead = 1 << 20
+
+type AttachmentHandlers struct {
+	attachmentService services.AttachmentService
+	maxUploadSize     int64
+}
+
+func NewAttachmentHandlers(attachmentService services.AttachmentService, maxUploadSize int64) *AttachmentHandlers {
+	return &AttachmentHandlers{attachmentService: attachmentService, maxUploadSize: maxUploadSize}
+}
+
+// MaxRequestSize is the largest request body an upload may have
+func (h *AttachmentHandlers) MaxRequestSize() int64 {
+	return h.maxUploadSize + multipartOverhead
+}
+
+// Upload stores the multipart "file" field as an attachment to send in the room
+func (h *AttachmentHandlers) Upload(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	// Content-Length can be absent, so the body itself is capped too
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxRequestSize())
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			ErrorResponse(c, errors.NewTooLargeError("file is too large", err))
+			return
+		}
+		ValidationErrorResponse(c, "A file is required in the \"file\" field", err.Error())
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		ErrorResponse(c, errors.NewInternalError("failed to read upload", err))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(c.Request.Context(), roomID, userIDInt, &models.AttachmentUpload{
+		Filename: header.Filename,
+		Size:     header.Size,
+		Content:  file,
+	})
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreatedResponse(c, attachment, "File uploaded successfully")
+}
+
+// GetAttachment returns an attachment with a fresh download URL
+func (h *AttachmentHandlers) GetAttachment(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid attachment ID", err.Error())
+		return
+	}
+
+	attachment, err := h.attachmentService.GetAttachment(c.Request.Context(), attachmentID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, attachment, "Attachment retrieved successfully")
+}
+
+// Download serves an attachment's contents to whoever holds a valid signed URL. It needs no
+// bearer token, so URLs work directly in img tags and download links.
+func (h *AttachmentHandlers) Download(c *gin.Context) {
+	attachmentID, userID, expires, ok := parseDownloadLink(c)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.attachmentService.OpenAttachment(c.Request.Context(), attachmentID, userID, expires, c.Query("sig"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+	defer content.Close()
+
+	// Only raster images are shown inline; anything else is downloaded, never rendered
+	disposition := "attachment"
+	if attachment.IsImage() {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, downloadHeaders(disposition, attachment.Filename))
+}
+
+// DownloadThumbnail serves an image attachment's thumbnail under the same signed URL rules
+func (h *AttachmentHandlers) DownloadThumbnail(c *gin.Context) {
+	attachmentID, userID, expires, ok := parseDownloadLink(c)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.attachmentService.OpenThumbnail(c.Request.Context(), attachmentID, userID, expires, c.Query("sig"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "image/jpeg", content, downloadHeaders("inline", attachment.Filename))
+}
+
+func parseDownloadLink(c *gin.Context) (attachmentID, userID int, expires int64, ok bool) {
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid attachment ID", err.Error())
+		return 0, 0, 0, false
+	}
+	userID, err = strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid download link", err.Error())
+		return 0, 0, 0, false
+	}
+	expires, err = strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid download link", err.Error())
+		return 0, 0, 0, false
+	}
+	return attachmentID, userID, expires, true
+}
+
+func downloadHeaders(disposition, filename string) map[string]string {
+	return map[string]string{
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": filename}),
+		"Content-Security-Policy": "default-src 'none'; sandbox",
+		"X-Content-Type-Options":  "nosniff",
+		"Cache-Control":           "private, max-age=300",
+	}
+}
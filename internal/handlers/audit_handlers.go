@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+type AuditHandlers struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandlers(auditService services.AuditService) *AuditHandlers {
+	return &AuditHandlers{
+		auditService: auditService,
+	}
+}
+
+// GetRoomAuditLog returns a room's moderation history (room owner or site administrator)
+func (h *AuditHandlers) GetRoomAuditLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid audit log filter", err.Error())
+		return
+	}
+
+	entries, err := h.auditService.GetRoomLog(c.Request.Context(), roomID, userIDInt, filter)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, entries, "Audit log retrieved successfully")
+}
+
+// GetAuditLog returns moderation history across every room (site administrators only)
+func (h *AuditHandlers) GetAuditLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid audit log filter", err.Error())
+		return
+	}
+	if value := c.Query("room_id"); value != "" {
+		if filter.RoomID, err = strconv.Atoi(value); err != nil {
+			ValidationErrorResponse(c, "Invalid audit log filter", "room_id must be an integer")
+			return
+		}
+	}
+
+	entries, err := h.auditService.GetLog(c.Request.Context(), userIDInt, filter)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, entries, "Audit log retrieved successfully")
+}
+
+// parseAuditFilter reads action, actor_id, target_user_id, since, until (RFC 3339), limit
+// and offset from the query string
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, error) {
+	var filter models.AuditFilter
+	var err error
+
+	filter.Limit, filter.Offset, err = parsePagination(c, defaultAuditLimit, maxAuditLimit)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.Action = models.AuditAction(c.Query("action"))
+
+	if value := c.Query("actor_id"); value != "" {
+		if filter.ActorID, err = strconv.Atoi(value); err != nil {
+			return filter, fmt.Errorf("actor_id must be an integer")
+		}
+	}
+	if value := c.Query("target_user_id"); value != "" {
+		if filter.TargetUserID, err = strconv.Atoi(value); err != nil {
+			return filter, fmt.Errorf("target_user_id must be an integer")
+		}
+	}
+
+	for name, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+		}
+		*target = &parsed
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"strconv"
+
+	"chat_app/internal/models"
+	"chat_app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationHandlers struct {
+	conversationService services.ConversationService
+}
+
+func NewConversationHandlers(conversationService services.ConversationService) *ConversationHandlers {
+	return &ConversationHandlers{
+		conversationService: conversationService,
+	}
+}
+
+// OpenConversation returns the conversation with the given users, creating it if needed
+func (h *ConversationHandlers) OpenConversation(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	var req models.OpenConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	conversation, err := h.conversationService.OpenConversation(c.Request.Context(), userIDInt, req.Usernames)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, conversation, "Conversation opened successfully")
+}
+
+// GetConversations lists the user's direct and group conversations
+func (h *ConversationHandlers) GetConversations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	conversations, err := h.conversationService.GetConversations(c.Request.Context(), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, conversations, "Conversations retrieved successfully")
+}
+
+// GetConversation returns one of the user's conversations with its participants
+func (h *ConversationHandlers) GetConversation(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid conversation ID", err.Error())
+		return
+	}
+
+	conversation, err := h.conversationService.GetConversation(c.Request.Context(), conversationID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, conversation, "Conversation retrieved successfully")
+}
+
+// LeaveConversation removes the user from a conversation
+func (h *ConversationHandlers) LeaveConversation(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid conversation ID", err.Error())
+		return
+	}
+
+	if err := h.conversationService.LeaveConversation(c.Request.Context(), conversationID, userIDInt); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Left conversation successfully")
+}
@@ -1,26 +1,30 @@
 package handlers
 
 import (
+	"io"
 	"strconv"
 
+	"chat_app/internal/models"
 	"chat_app/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type InviteHandlers struct {
-	roomService services.RoomService
-	userService services.UserService
+	roomService   services.RoomService
+	userService   services.UserService
+	inviteService services.InviteService
 }
 
-func NewInviteHandlers(roomService services.RoomService, userService services.UserService) *InviteHandlers {
+func NewInviteHandlers(roomService services.RoomService, userService services.UserService, inviteService services.InviteService) *InviteHandlers {
 	return &InviteHandlers{
-		roomService: roomService,
-		userService: userService,
+		roomService:   roomService,
+		userService:   userService,
+		inviteService: inviteService,
 	}
 }
 
-// InviteUser invites a user to a private room
+// InviteUser invites a user to a room (requires the invite permission)
 func (h *InviteHandlers) InviteUser(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userIDInt := userID.(int)
@@ -41,18 +45,6 @@ func (h *InviteHandlers) InviteUser(c *gin.Context) {
 		return
 	}
 
-	// Check if the requesting user is the room creator
-	room, err := h.roomService.GetRoom(c.Request.Context(), roomID)
-	if err != nil {
-		ErrorResponse(c, err)
-		return
-	}
-
-	if room.CreatedBy != userIDInt {
-		ForbiddenResponse(c, "Only room creator can invite users")
-		return
-	}
-
 	// Get the user to invite by username
 	userToInvite, err := h.userService.GetUserByUsername(c.Request.Context(), req.Username)
 	if err != nil {
@@ -61,7 +53,7 @@ func (h *InviteHandlers) InviteUser(c *gin.Context) {
 	}
 
 	// Add the user to the room
-	err = h.roomService.JoinRoom(c.Request.Context(), roomID, userToInvite.ID)
+	err = h.roomService.InviteMember(c.Request.Context(), roomID, userIDInt, userToInvite.ID)
 	if err != nil {
 		ErrorResponse(c, err)
 		return
@@ -91,18 +83,11 @@ func (h *InviteHandlers) InviteMultipleUsers(c *gin.Context) {
 		return
 	}
 
-	// Check if the requesting user is the room creator
-	room, err := h.roomService.GetRoom(c.Request.Context(), roomID)
-	if err != nil {
+	if err := h.roomService.RequirePermission(c.Request.Context(), roomID, userIDInt, models.PermInvite); err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	if room.CreatedBy != userIDInt {
-		ForbiddenResponse(c, "Only room creator can invite users")
-		return
-	}
-
 	var successCount int
 	var failedInvites []string
 
@@ -115,7 +100,7 @@ func (h *InviteHandlers) InviteMultipleUsers(c *gin.Context) {
 		}
 
 		// Add the user to the room
-		err = h.roomService.JoinRoom(c.Request.Context(), roomID, userToInvite.ID)
+		err = h.roomService.InviteMember(c.Request.Context(), roomID, userIDInt, userToInvite.ID)
 		if err != nil {
 			failedInvites = append(failedInvites, username)
 			continue
@@ -145,18 +130,12 @@ func (h *InviteHandlers) GetInvitableUsers(c *gin.Context) {
 		return
 	}
 
-	// Check if the requesting user is the room creator
-	room, err := h.roomService.GetRoom(c.Request.Context(), roomID)
-	if err != nil {
+	// Only members allowed to invite may browse candidates
+	if err := h.roomService.RequirePermission(c.Request.Context(), roomID, userIDInt, models.PermInvite); err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	if room.CreatedBy != userIDInt {
-		ForbiddenResponse(c, "Only room creator can view invitable users")
-		return
-	}
-
 	// Get all users (simplified - in production, you'd want pagination and search)
 	users, err := h.userService.GetAllUsers(c.Request.Context(), 100, 0)
 	if err != nil {
@@ -190,3 +169,93 @@ func (h *InviteHandlers) GetInvitableUsers(c *gin.Context) {
 
 	SuccessResponse(c, invitableUsers, "Invitable users retrieved successfully")
 }
+
+// CreateInviteLink issues a shareable invite code for the room
+func (h *InviteHandlers) CreateInviteLink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	// Every field is optional, so an empty body asks for an unlimited member invite
+	var req models.CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	invite, err := h.inviteService.CreateInvite(c.Request.Context(), roomID, userIDInt, &req)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreatedResponse(c, invite, "Invite created successfully")
+}
+
+// ListInviteLinks returns the room's invite codes (requires managing the room)
+func (h *InviteHandlers) ListInviteLinks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	invites, err := h.inviteService.ListInvites(c.Request.Context(), roomID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, invites, "Invites retrieved successfully")
+}
+
+// RevokeInviteLink disables an invite code
+func (h *InviteHandlers) RevokeInviteLink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	inviteIDStr := c.Param("invite_id")
+	inviteID, err := strconv.Atoi(inviteIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid invite ID", err.Error())
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(c.Request.Context(), roomID, inviteID, userIDInt); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Invite revoked successfully")
+}
+
+// RedeemInvite joins the room an invite code belongs to
+func (h *InviteHandlers) RedeemInvite(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	room, err := h.inviteService.RedeemInvite(c.Request.Context(), c.Param("code"), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, room, "Successfully joined room")
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"chat_app/internal/models"
+	"chat_app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 100
+	defaultMentionLimit = 20
+	maxMentionLimit     = 50
+)
+
+type MessageHandlers struct {
+	messageService services.MessageService
+}
+
+func NewMessageHandlers(messageService services.MessageService) *MessageHandlers {
+	return &MessageHandlers{messageService: messageService}
+}
+
+// GetMessages returns a page of a room's messages, oldest first. With after_seq it returns
+// the messages following that sequence number instead, for clients catching up after a gap.
+// With a before, after or around parameter it returns a cursor page instead of a list.
+func (h *MessageHandlers) GetMessages(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomID, err := strconv.Atoi(c.Query("room_id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	limit, offset, err := parsePagination(c, defaultMessageLimit, maxMessageLimit)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid pagination parameters", err.Error())
+		return
+	}
+
+	query, paged, err := parsePageQuery(c, limit)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid around parameter", err.Error())
+		return
+	}
+	if paged {
+		page, err := h.messageService.GetMessagePage(c.Request.Context(), roomID, userIDInt, query)
+		if err != nil {
+			ErrorResponse(c, err)
+			return
+		}
+		SuccessResponse(c, page, "Messages retrieved successfully")
+		return
+	}
+
+	var messages []*models.Message
+	if value := c.Query("after_seq"); value != "" {
+		afterSeq, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil || afterSeq < 0 {
+			ValidationErrorResponse(c, "Invalid after_seq", "after_seq must be a non-negative integer")
+			return
+		}
+		messages, err = h.messageService.GetMessagesAfter(c.Request.Context(), roomID, userIDInt, afterSeq, limit)
+	} else {
+		messages, err = h.messageService.GetMessages(c.Request.Context(), roomID, userIDInt, limit, offset)
+	}
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, messages, "Messages retrieved successfully")
+}
+
+// SendMessage posts a message to a room the user belongs to
+func (h *MessageHandlers) SendMessage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	var req models.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	message, err := h.messageService.SendMessage(c.Request.Context(), userIDInt, &req)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreatedResponse(c, message, "Message sent successfully")
+}
+
+// GetMessage returns a single message
+func (h *MessageHandlers) GetMessage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	message, err := h.messageService.GetMessage(c.Request.Context(), messageID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, message, "Message retrieved successfully")
+}
+
+// GetThread returns a message and a page of its replies
+func (h *MessageHandlers) GetThread(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	limit, offset, err := parsePagination(c, defaultMessageLimit, maxMessageLimit)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid pagination parameters", err.Error())
+		return
+	}
+
+	thread, err := h.messageService.GetThread(c.Request.Context(), messageID, userIDInt, limit, offset)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, thread, "Thread retrieved successfully")
+}
+
+// EditMessage lets the author change a message's content
+func (h *MessageHandlers) EditMessage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	message, err := h.messageService.EditMessage(c.Request.Context(), messageID, userIDInt, req.Content)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, message, "Message updated successfully")
+}
+
+// DeleteMessage lets the author delete a message
+func (h *MessageHandlers) DeleteMessage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	message, err := h.messageService.DeleteMessage(c.Request.Context(), messageID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, message, "Message deleted successfully")
+}
+
+// GetRevisions lets moderators see a message's earlier contents
+func (h *MessageHandlers) GetRevisions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	revisions, err := h.messageService.GetRevisions(c.Request.Context(), messageID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, revisions, "Revisions retrieved successfully")
+}
+
+// AddReaction adds the user's emoji to a message
+func (h *MessageHandlers) AddReaction(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	var req models.ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	update, err := h.messageService.AddReaction(c.Request.Context(), messageID, userIDInt, req.Emoji)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreatedResponse(c, update, "Reaction added successfully")
+}
+
+// RemoveReaction takes back the user's emoji, given URL-encoded in the path
+func (h *MessageHandlers) RemoveReaction(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	messageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid message ID", err.Error())
+		return
+	}
+
+	update, err := h.messageService.RemoveReaction(c.Request.Context(), messageID, userIDInt, c.Param("emoji"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, update, "Reaction removed successfully")
+}
+
+// GetMentions lists the mentions addressed to the user, newest first. before takes the ID of
+// the last mention seen to continue from it.
+func (h *MessageHandlers) GetMentions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	limit, _, err := parsePagination(c, defaultMentionLimit, maxMentionLimit)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid pagination parameters", err.Error())
+		return
+	}
+	before := 0
+	if value := c.Query("before"); value != "" {
+		if before, err = strconv.Atoi(value); err != nil || before <= 0 {
+			ValidationErrorResponse(c, "Invalid pagination parameters", "before must be a mention ID")
+			return
+		}
+	}
+
+	mentions, err := h.messageService.GetMentions(c.Request.Context(), userIDInt, before, limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, mentions, "Mentions retrieved successfully")
+}
+
+// parsePageQuery reads cursor pagination parameters. An empty before= asks for the newest
+// page, which is where a client starts paging back from.
+func parsePageQuery(c *gin.Context, limit int) (*models.MessagePageQuery, bool, error) {
+	before, hasBefore := c.GetQuery("before")
+	after, hasAfter := c.GetQuery("after")
+	around, hasAround := c.GetQuery("around")
+	if !hasBefore && !hasAfter && !hasAround {
+		return nil, false, nil
+	}
+
+	query := &models.MessagePageQuery{Before: before, After: after, Limit: limit}
+	if hasAround {
+		id, err := strconv.Atoi(around)
+		if err != nil || id <= 0 {
+			return nil, false, fmt.Errorf("around must be a message ID")
+		}
+		query.Around = id
+	}
+	return query, true, nil
+}
+
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (int, int, error) {
+	limit := defaultLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, err
+		}
+		limit = parsed
+	}
+	if limit <= 0 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, err
+		}
+		if parsed > 0 {
+			offset = parsed
+		}
+	}
+
+	return limit, offset, nil
+}
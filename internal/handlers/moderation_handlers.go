@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 
+	"chat_app/internal/models"
 	"chat_app/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -20,7 +21,7 @@ func NewModerationHandlers(roomService services.RoomService, userService service
 	}
 }
 
-// RemoveUser removes a user from a room (only room creator can do this)
+// RemoveUser removes a user from a room (requires the kick permission)
 func (h *ModerationHandlers) RemoveUser(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userIDInt := userID.(int)
@@ -34,6 +35,7 @@ func (h *ModerationHandlers) RemoveUser(c *gin.Context) {
 
 	var req struct {
 		Username string `json:"username" binding:"required"`
+		Reason   string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -48,30 +50,43 @@ func (h *ModerationHandlers) RemoveUser(c *gin.Context) {
 		return
 	}
 
-	// Check if the requesting user is the room creator
-	room, err := h.roomService.GetRoom(c.Request.Context(), roomID)
+	// Remove the user from the room
+	err = h.roomService.KickMember(c.Request.Context(), roomID, userIDInt, userToRemove.ID, req.Reason)
 	if err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	if room.CreatedBy != userIDInt {
-		ForbiddenResponse(c, "Only room creator can remove users")
+	SuccessResponse(c, nil, "User removed from room successfully")
+}
+
+// ResetRoom removes all members below the requester's role (for semester end)
+func (h *ModerationHandlers) ResetRoom(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
 		return
 	}
 
-	// Remove the user from the room
-	err = h.roomService.LeaveRoom(c.Request.Context(), roomID, userToRemove.ID)
+	reset, err := h.roomService.ResetRoom(c.Request.Context(), roomID, userIDInt)
 	if err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	SuccessResponse(c, nil, "User removed from room successfully")
+	if reset.Failed > 0 {
+		SuccessResponse(c, reset, "Room reset partially - some members below your role could not be removed")
+		return
+	}
+	SuccessResponse(c, reset, "Room reset successfully - all members below your role removed")
 }
 
-// ResetRoom removes all members except the creator (for semester end)
-func (h *ModerationHandlers) ResetRoom(c *gin.Context) {
+// GetRoomPermissions returns the user's permissions in a room
+func (h *ModerationHandlers) GetRoomPermissions(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userIDInt := userID.(int)
 
@@ -82,41 +97,171 @@ func (h *ModerationHandlers) ResetRoom(c *gin.Context) {
 		return
 	}
 
-	// Check if the requesting user is the room creator
-	room, err := h.roomService.GetRoom(c.Request.Context(), roomID)
+	permissions, err := h.roomService.GetRoomPermissions(c.Request.Context(), roomID, userIDInt)
 	if err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	if room.CreatedBy != userIDInt {
-		ForbiddenResponse(c, "Only room creator can reset room")
+	SuccessResponse(c, permissions, "Room permissions retrieved successfully")
+}
+
+// TransferOwnership hands the room to another member (owner only)
+func (h *ModerationHandlers) TransferOwnership(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
 		return
 	}
 
-	// Get all room members
-	members, err := h.roomService.GetRoomMembers(c.Request.Context(), roomID)
+	var req struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	newOwner, err := h.userService.GetUserByUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	member, err := h.roomService.TransferOwnership(c.Request.Context(), roomID, userIDInt, newOwner.ID)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, member, "Room ownership transferred successfully")
+}
+
+// GetMemberRoles lists the room's members with their roles
+func (h *ModerationHandlers) GetMemberRoles(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	members, err := h.roomService.GetMemberRoles(c.Request.Context(), roomID, userIDInt)
 	if err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	// Remove all members except the creator
-	for _, member := range members {
-		if member.ID != userIDInt {
-			err = h.roomService.LeaveRoom(c.Request.Context(), roomID, member.ID)
-			if err != nil {
-				// Log error but continue with other members
-				continue
-			}
+	SuccessResponse(c, members, "Room roles retrieved successfully")
+}
+
+// SetMemberRole changes a member's role (admins and owners, for members below them)
+func (h *ModerationHandlers) SetMemberRole(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	targetIDStr := c.Param("user_id")
+	targetID, err := strconv.Atoi(targetIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid user ID", err.Error())
+		return
+	}
+
+	var req struct {
+		Role models.RoomRole `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	member, err := h.roomService.SetMemberRole(c.Request.Context(), roomID, userIDInt, targetID, req.Role)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, member, "Member role updated successfully")
+}
+
+// BanUser bans a user from the room, removing them if they are a member (requires the ban permission)
+func (h *ModerationHandlers) BanUser(c *gin.Context) {
+	h.sanctionUser(c, models.SanctionBan)
+}
+
+// MuteUser stops a member posting in the room (requires the kick permission)
+func (h *ModerationHandlers) MuteUser(c *gin.Context) {
+	h.sanctionUser(c, models.SanctionMute)
+}
+
+func (h *ModerationHandlers) sanctionUser(c *gin.Context, kind models.SanctionKind) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	var req models.SanctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	target, err := h.userService.GetUserByUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if kind == models.SanctionBan {
+		sanction, err := h.roomService.BanMember(c.Request.Context(), roomID, userIDInt, target.ID, req.Reason, req.ExpiresAt)
+		if err != nil {
+			ErrorResponse(c, err)
+			return
 		}
+		CreatedResponse(c, sanction, "User banned successfully")
+		return
+	}
+
+	sanction, err := h.roomService.MuteMember(c.Request.Context(), roomID, userIDInt, target.ID, req.Reason, req.ExpiresAt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
 	}
+	CreatedResponse(c, sanction, "User muted successfully")
+}
 
-	SuccessResponse(c, nil, "Room reset successfully - all members removed except creator")
+// ListBans lists the room's active bans
+func (h *ModerationHandlers) ListBans(c *gin.Context) {
+	h.listSanctions(c, models.SanctionBan)
 }
 
-// GetRoomPermissions returns the user's permissions in a room
-func (h *ModerationHandlers) GetRoomPermissions(c *gin.Context) {
+// ListMutes lists the room's active mutes
+func (h *ModerationHandlers) ListMutes(c *gin.Context) {
+	h.listSanctions(c, models.SanctionMute)
+}
+
+func (h *ModerationHandlers) listSanctions(c *gin.Context, kind models.SanctionKind) {
 	userID, _ := c.Get("user_id")
 	userIDInt := userID.(int)
 
@@ -127,18 +272,37 @@ func (h *ModerationHandlers) GetRoomPermissions(c *gin.Context) {
 		return
 	}
 
-	room, err := h.roomService.GetRoom(c.Request.Context(), roomID)
+	sanctions, err := h.roomService.GetSanctions(c.Request.Context(), roomID, userIDInt, kind)
 	if err != nil {
 		ErrorResponse(c, err)
 		return
 	}
 
-	permissions := map[string]bool{
-		"is_creator":       room.CreatedBy == userIDInt,
-		"can_moderate":     room.CreatedBy == userIDInt,
-		"can_remove_users": room.CreatedBy == userIDInt,
-		"can_reset_room":   room.CreatedBy == userIDInt,
+	SuccessResponse(c, sanctions, "Sanctions retrieved successfully")
+}
+
+// LiftSanction ends a ban or mute early
+func (h *ModerationHandlers) LiftSanction(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	sanctionID, err := strconv.Atoi(c.Param("sanction_id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid sanction ID", err.Error())
+		return
 	}
 
-	SuccessResponse(c, permissions, "Room permissions retrieved successfully")
+	if err := h.roomService.LiftSanction(c.Request.Context(), roomID, sanctionID, userIDInt); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Sanction lifted successfully")
 }
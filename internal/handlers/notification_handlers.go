@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"strconv"
+
+	"chat_app/internal/models"
+	"chat_app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNotificationLimit = 20
+	maxNotificationLimit     = 100
+)
+
+type NotificationHandlers struct {
+	notificationService services.NotificationService
+}
+
+func NewNotificationHandlers(notificationService services.NotificationService) *NotificationHandlers {
+	return &NotificationHandlers{notificationService: notificationService}
+}
+
+// GetNotifications returns a page of the user's notifications, newest first, with their
+// unread count. before takes the ID of the last notification seen; unread=true leaves out
+// those already read.
+func (h *NotificationHandlers) GetNotifications(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	limit, _, err := parsePagination(c, defaultNotificationLimit, maxNotificationLimit)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid pagination parameters", err.Error())
+		return
+	}
+	query := models.NotificationQuery{Limit: limit}
+
+	if value := c.Query("before"); value != "" {
+		if query.BeforeID, err = strconv.Atoi(value); err != nil || query.BeforeID <= 0 {
+			ValidationErrorResponse(c, "Invalid pagination parameters", "before must be a notification ID")
+			return
+		}
+	}
+	if value := c.Query("unread"); value != "" {
+		if query.UnreadOnly, err = strconv.ParseBool(value); err != nil {
+			ValidationErrorResponse(c, "Invalid filter", "unread must be true or false")
+			return
+		}
+	}
+
+	list, err := h.notificationService.GetNotifications(c.Request.Context(), userIDInt, query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, list, "Notifications retrieved successfully")
+}
+
+// GetUnreadCount returns how many of the user's notifications are unread
+func (h *NotificationHandlers) GetUnreadCount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	unread, err := h.notificationService.GetUnreadCount(c.Request.Context(), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, gin.H{"unread_count": unread}, "Unread count retrieved successfully")
+}
+
+// MarkRead marks one notification read
+func (h *NotificationHandlers) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	notificationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid notification ID", err.Error())
+		return
+	}
+
+	read, err := h.notificationService.MarkRead(c.Request.Context(), userIDInt, notificationID)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, read, "Notification marked read")
+}
+
+// MarkAllRead marks every notification of the user read
+func (h *NotificationHandlers) MarkAllRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	read, err := h.notificationService.MarkAllRead(c.Request.Context(), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, read, "Notifications marked read")
+}
+
+// GetMutes lists the rooms the user has muted
+func (h *NotificationHandlers) GetMutes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	mutes, err := h.notificationService.GetMutes(c.Request.Context(), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, mutes, "Muted rooms retrieved successfully")
+}
+
+// MuteRoom stops mentions and replies in a room from notifying the user
+func (h *NotificationHandlers) MuteRoom(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	if err := h.notificationService.MuteRoom(c.Request.Context(), roomID, userIDInt); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Room notifications muted")
+}
+
+// UnmuteRoom lets a muted room notify the user again
+func (h *NotificationHandlers) UnmuteRoom(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	if err := h.notificationService.UnmuteRoom(c.Request.Context(), roomID, userIDInt); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Room notifications unmuted")
+}
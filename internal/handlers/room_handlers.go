@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"io"
 	"strconv"
+	"strings"
 
 	"chat_app/internal/services"
 
@@ -200,3 +202,135 @@ func (h *RoomHandlers) GetRoomMembers(c *gin.Context) {
 
 	SuccessResponse(c, members, "Room members retrieved successfully")
 }
+
+// GetUnreadCounts returns the user's unread message count in every room they belong to
+func (h *RoomHandlers) GetUnreadCounts(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	states, err := h.roomService.GetUnreadCounts(c.Request.Context(), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, states, "Unread counts retrieved successfully")
+}
+
+// MarkRoomRead moves the user's read marker in a room up to the given sequence number
+func (h *RoomHandlers) MarkRoomRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	var req struct {
+		Seq int64 `json:"seq"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	state, err := h.roomService.MarkRead(c.Request.Context(), roomID, userIDInt, req.Seq)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, state, "Room marked as read")
+}
+
+// RequestToJoin asks to join a private room, with an optional message for its admins
+func (h *RoomHandlers) RequestToJoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	var req struct {
+		Message string `json:"message,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	request, err := h.roomService.RequestToJoin(c.Request.Context(), roomID, userIDInt, strings.TrimSpace(req.Message))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreatedResponse(c, request, "Join request sent")
+}
+
+// GetJoinRequests lists a room's pending join requests
+func (h *RoomHandlers) GetJoinRequests(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	requests, err := h.roomService.GetJoinRequests(c.Request.Context(), roomID, userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, requests, "Join requests retrieved successfully")
+}
+
+// ApproveJoinRequest admits the requester to the room
+func (h *RoomHandlers) ApproveJoinRequest(c *gin.Context) {
+	h.decideJoinRequest(c, true, "Join request approved")
+}
+
+// DenyJoinRequest turns the request down
+func (h *RoomHandlers) DenyJoinRequest(c *gin.Context) {
+	h.decideJoinRequest(c, false, "Join request denied")
+}
+
+func (h *RoomHandlers) decideJoinRequest(c *gin.Context, approve bool, message string) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	roomIDStr := c.Param("id")
+	roomID, err := strconv.Atoi(roomIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid room ID", err.Error())
+		return
+	}
+
+	requestIDStr := c.Param("request_id")
+	requestID, err := strconv.Atoi(requestIDStr)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid request ID", err.Error())
+		return
+	}
+
+	request, err := h.roomService.DecideJoinRequest(c.Request.Context(), roomID, requestID, userIDInt, approve)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, request, message)
+}
@@ -1,41 +1,50 @@
 package handlers
 
 import (
-	"chat_app/internal/config"
+	"time"
+
+	"chat_app/internal/app"
 	"chat_app/internal/middleware"
 	"chat_app/internal/ws"
-	"chat_app/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, db interface{}, redis interface{}, logger *logger.Logger) {
+func SetupRoutes(router *gin.Engine, container *app.Container) {
+	logger := container.Logger
+	svc := container.Services
+
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(nil, logger) // TODO: inject auth service
+	authMiddleware := middleware.NewAuthMiddleware(svc.Auth, logger)
 	validationMiddleware := middleware.NewValidationMiddleware(logger)
-	rateLimitMiddleware := middleware.NewRateLimitMiddleware(100, 60, logger)
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(100, time.Minute, logger)
 	securityMiddleware := middleware.NewSecurityMiddleware(logger)
 	loggingMiddleware := middleware.NewLoggingMiddleware(logger)
 
-	// Initialize services (simplified - in production, use dependency injection)
-	// TODO: Properly initialize services with repositories
-	roomService := &mockRoomService{}
-	userService := &mockUserService{}
-
 	// Initialize handlers
-	roomHandlers := NewRoomHandlers(roomService, userService)
-	moderationHandlers := NewModerationHandlers(roomService, userService)
-	inviteHandlers := NewInviteHandlers(roomService, userService)
+	authHandlers := NewAuthHandlers(svc.Auth)
+	userHandlers := NewUserHandlers(svc.Users)
+	roomHandlers := NewRoomHandlers(svc.Rooms, svc.Users)
+	moderationHandlers := NewModerationHandlers(svc.Rooms, svc.Users)
+	inviteHandlers := NewInviteHandlers(svc.Rooms, svc.Users, svc.Invites)
+	messageHandlers := NewMessageHandlers(svc.Messages)
+	auditHandlers := NewAuditHandlers(svc.Audit)
+	conversationHandlers := NewConversationHandlers(svc.Conversations)
+	searchHandlers := NewSearchHandlers(svc.Search)
+	notificationHandlers := NewNotificationHandlers(svc.Notifications)
+	attachmentHandlers := NewAttachmentHandlers(svc.Attachments, container.Config.Storage.MaxUploadBytes)
 
 	// Apply global middleware
 	router.Use(loggingMiddleware.RequestLogger())
 	router.Use(securityMiddleware.SecurityHeaders())
 	router.Use(securityMiddleware.CORS())
-	router.Use(securityMiddleware.RequestSizeLimit(10 * 1024 * 1024)) // 10MB limit
+	router.Use(securityMiddleware.RequestSizeLimitByRoute(10*1024*1024, map[string]int64{ // 10MB limit, except uploads
+		"/api/v1/rooms/:id/attachments": attachmentHandlers.MaxRequestSize(),
+	}))
 	router.Use(securityMiddleware.BlockSuspiciousRequests())
 
 	// Health check endpoints
-	healthHandler := NewHealthHandler(nil, nil, logger) // TODO: inject db and redis
+	healthHandler := NewHealthHandler(container.DB, container.Redis, logger)
 	router.GET("/health", healthHandler.HealthCheck)
 	router.GET("/health/ready", healthHandler.ReadinessCheck)
 	router.GET("/health/live", healthHandler.LivenessCheck)
@@ -47,8 +56,13 @@ func SetupRoutes(router *gin.Engine, db interface{}, redis interface{}, logger *
 		// Public routes
 		public := v1.Group("/")
 		{
-			public.POST("/register", validationMiddleware.ValidateEmail(), validationMiddleware.ValidatePassword())
-			public.POST("/login", validationMiddleware.ValidateEmail(), validationMiddleware.ValidatePassword())
+			public.POST("/register", validationMiddleware.ValidateUsername(), validationMiddleware.ValidateEmail(), validationMiddleware.ValidatePassword(), authHandlers.Register)
+			public.POST("/login", validationMiddleware.ValidateUsername(), authHandlers.Login)
+			public.POST("/refresh", authHandlers.RefreshToken)
+
+			// Attachment downloads authenticate with the URL's signature instead of a bearer token
+			public.GET("/attachments/:id/content", attachmentHandlers.Download)
+			public.GET("/attachments/:id/thumbnail", attachmentHandlers.DownloadThumbnail)
 		}
 
 		// Protected routes
@@ -56,11 +70,13 @@ func SetupRoutes(router *gin.Engine, db interface{}, redis interface{}, logger *
 		protected.Use(authMiddleware.RequireAuth())
 		protected.Use(rateLimitMiddleware.RateLimitPerUser())
 		{
+			protected.POST("/logout", authHandlers.Logout)
+
 			// User routes
-			protected.GET("/profile")
-			protected.PUT("/profile")
-			protected.DELETE("/profile")
-			protected.POST("/change-password", validationMiddleware.ValidatePassword())
+			protected.GET("/profile", userHandlers.GetProfile)
+			protected.PUT("/profile", userHandlers.UpdateProfile)
+			protected.DELETE("/profile", userHandlers.DeactivateAccount)
+			protected.POST("/change-password", validationMiddleware.ValidatePassword(), userHandlers.ChangePassword)
 
 			// Room routes
 			rooms := protected.Group("/rooms")
@@ -68,6 +84,7 @@ func SetupRoutes(router *gin.Engine, db interface{}, redis interface{}, logger *
 			{
 				// Room management
 				rooms.GET("/", roomHandlers.GetUserRooms)              // Get user's rooms
+				rooms.GET("/unread", roomHandlers.GetUnreadCounts)     // Get unread counts per room
 				rooms.POST("/", roomHandlers.CreateRoom)               // Create new room
 				rooms.GET("/:id", roomHandlers.GetRoom)                // Get room details
 				rooms.PUT("/:id", roomHandlers.UpdateRoom)             // Update room
@@ -75,43 +92,106 @@ func SetupRoutes(router *gin.Engine, db interface{}, redis interface{}, logger *
 				rooms.POST("/:id/join", roomHandlers.JoinRoom)         // Join room
 				rooms.DELETE("/:id/leave", roomHandlers.LeaveRoom)     // Leave room
 				rooms.GET("/:id/members", roomHandlers.GetRoomMembers) // Get room members
+				rooms.POST("/:id/read", roomHandlers.MarkRoomRead)     // Mark room read up to a seq
+
+				// Files to attach to a message; send the returned IDs as attachment_ids
+				rooms.POST("/:id/attachments", attachmentHandlers.Upload)
+
+				// Mute or unmute mentions and replies in a room
+				rooms.PUT("/:id/notifications/mute", notificationHandlers.MuteRoom)
+				rooms.DELETE("/:id/notifications/mute", notificationHandlers.UnmuteRoom)
+
+				// Join requests for private rooms
+				rooms.POST("/:id/join-requests", roomHandlers.RequestToJoin)                          // Ask to join
+				rooms.GET("/:id/join-requests", roomHandlers.GetJoinRequests)                         // List pending requests
+				rooms.POST("/:id/join-requests/:request_id/approve", roomHandlers.ApproveJoinRequest) // Approve a request
+				rooms.POST("/:id/join-requests/:request_id/deny", roomHandlers.DenyJoinRequest)       // Deny a request
 
 				// Moderation routes
 				moderation := rooms.Group("/:id/moderation")
 				{
-					moderation.POST("/remove", moderationHandlers.RemoveUser)             // Remove user from room
-					moderation.POST("/reset", moderationHandlers.ResetRoom)               // Reset room (remove all members)
-					moderation.GET("/permissions", moderationHandlers.GetRoomPermissions) // Get user permissions
+					moderation.POST("/remove", moderationHandlers.RemoveUser)                     // Remove user from room
+					moderation.POST("/reset", moderationHandlers.ResetRoom)                       // Reset room (remove all members)
+					moderation.GET("/permissions", moderationHandlers.GetRoomPermissions)         // Get user permissions
+					moderation.GET("/roles", moderationHandlers.GetMemberRoles)                   // List members with roles
+					moderation.PUT("/roles/:user_id", moderationHandlers.SetMemberRole)           // Change a member's role
+					moderation.POST("/transfer", moderationHandlers.TransferOwnership)            // Transfer room ownership
+					moderation.POST("/bans", moderationHandlers.BanUser)                          // Ban user, optionally until a time
+					moderation.GET("/bans", moderationHandlers.ListBans)                          // List active bans
+					moderation.POST("/mutes", moderationHandlers.MuteUser)                        // Mute user, optionally until a time
+					moderation.GET("/mutes", moderationHandlers.ListMutes)                        // List active mutes
+					moderation.DELETE("/sanctions/:sanction_id", moderationHandlers.LiftSanction) // Lift a ban or mute
+					moderation.GET("/audit", auditHandlers.GetRoomAuditLog)                       // Room audit log
 				}
 
-				// Invite routes (for private rooms)
+				// Invite routes
 				invites := rooms.Group("/:id/invites")
 				{
 					invites.POST("/", inviteHandlers.InviteUser)              // Invite single user
 					invites.POST("/bulk", inviteHandlers.InviteMultipleUsers) // Invite multiple users
 					invites.GET("/users", inviteHandlers.GetInvitableUsers)   // Get invitable users
+
+					// Shareable invite codes
+					invites.POST("/links", inviteHandlers.CreateInviteLink)              // Create invite code
+					invites.GET("/links", inviteHandlers.ListInviteLinks)                // List invite codes
+					invites.DELETE("/links/:invite_id", inviteHandlers.RevokeInviteLink) // Revoke invite code
 				}
 			}
 
+			// Invite codes are redeemed outside any room the user already belongs to
+			protected.POST("/invites/:code/redeem", inviteHandlers.RedeemInvite)
+
+			// Direct and group conversations; their messages use the message routes by room_id
+			conversations := protected.Group("/conversations")
+			conversations.Use(rateLimitMiddleware.RateLimit())
+			{
+				conversations.POST("/", conversationHandlers.OpenConversation)             // Open or create a conversation
+				conversations.GET("/", conversationHandlers.GetConversations)              // List your conversations
+				conversations.GET("/:id", conversationHandlers.GetConversation)            // Get a conversation
+				conversations.DELETE("/:id/leave", conversationHandlers.LeaveConversation) // Leave a conversation
+			}
+
+			// A fresh signed download URL for an attachment
+			protected.GET("/attachments/:id", attachmentHandlers.GetAttachment)
+
+			// Site-wide audit log for administrators
+			protected.GET("/audit", auditHandlers.GetAuditLog)
+
+			// Notification center: what happened while you were away
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("/", notificationHandlers.GetNotifications)     // List notifications with the unread count
+				notifications.GET("/unread", notificationHandlers.GetUnreadCount) // Unread count only
+				notifications.POST("/read", notificationHandlers.MarkAllRead)     // Mark everything read
+				notifications.POST("/:id/read", notificationHandlers.MarkRead)    // Mark one read
+				notifications.GET("/mutes", notificationHandlers.GetMutes)        // Rooms you have muted
+			}
+
+			// Mentions of you, and @room mentions in your rooms
+			protected.GET("/mentions", messageHandlers.GetMentions)
+
+			// Full-text search over the rooms and conversations you belong to
+			protected.GET("/search/messages", rateLimitMiddleware.RateLimit(), searchHandlers.SearchMessages)
+
 			// Message routes
 			messages := protected.Group("/messages")
 			messages.Use(rateLimitMiddleware.RateLimitPerRoom())
 			{
-				messages.GET("/")
-				messages.POST("/", validationMiddleware.ValidateMessage())
-				messages.GET("/:id")
-				messages.PUT("/:id")
-				messages.DELETE("/:id")
+				messages.GET("/", messageHandlers.GetMessages)
+				messages.POST("/", validationMiddleware.ValidateMessage(), messageHandlers.SendMessage)
+				messages.GET("/:id", messageHandlers.GetMessage)
+				messages.GET("/:id/thread", messageHandlers.GetThread)
+				messages.GET("/:id/revisions", messageHandlers.GetRevisions)
+				messages.PUT("/:id", messageHandlers.EditMessage)
+				messages.DELETE("/:id", messageHandlers.DeleteMessage)
+				messages.POST("/:id/reactions", messageHandlers.AddReaction)
+				messages.DELETE("/:id/reactions/:emoji", messageHandlers.RemoveReaction)
 			}
 		}
 	}
 
-	hub := ws.NewHub()
-	cfg := config.Load()
-	redisClient := config.NewRedisClient(cfg.Redis)
-	hub.EnableRedis(redisClient)
-	go hub.Run()
-	router.GET("/ws", ws.ServeWS(hub))
+	gateway := ws.NewGateway(container.Hub, container.Services.Auth, container.Services.Messages, container.Repositories.Rooms, container.Repositories.RoomMembers, container.Repositories.Sanctions, container.Logger)
+	router.GET("/ws", gateway.ServeWS)
 
 	router.Static("/static", "./static")
 	router.StaticFile("/", "./static/index.html")
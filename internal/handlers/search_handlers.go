@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+type SearchHandlers struct {
+	searchService services.SearchService
+}
+
+func NewSearchHandlers(searchService services.SearchService) *SearchHandlers {
+	return &SearchHandlers{searchService: searchService}
+}
+
+// SearchMessages searches the history of the user's rooms
+func (h *SearchHandlers) SearchMessages(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		ValidationErrorResponse(c, "Invalid search parameters", err.Error())
+		return
+	}
+
+	results, err := h.searchService.SearchMessages(c.Request.Context(), userIDInt, query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, results, "Search completed successfully")
+}
+
+// parseSearchQuery reads q, room_id, author, since, until (RFC 3339), has_attachment, cursor
+// and limit from the query string
+func parseSearchQuery(c *gin.Context) (models.MessageSearchQuery, error) {
+	query := models.MessageSearchQuery{
+		Query:  c.Query("q"),
+		Author: c.Query("author"),
+		Cursor: c.Query("cursor"),
+	}
+	var err error
+
+	query.Limit, _, err = parsePagination(c, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		return query, err
+	}
+
+	if value := c.Query("room_id"); value != "" {
+		if query.RoomID, err = strconv.Atoi(value); err != nil {
+			return query, fmt.Errorf("room_id must be an integer")
+		}
+	}
+
+	if value := c.Query("has_attachment"); value != "" {
+		hasAttachment, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("has_attachment must be true or false")
+		}
+		query.HasAttachment = &hasAttachment
+	}
+
+	for name, target := range map[string]**time.Time{"since": &query.Since, "until": &query.Until} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+		}
+		*target = &parsed
+	}
+
+	return query, nil
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"chat_app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandlers struct {
+	userService services.UserService
+}
+
+func NewUserHandlers(userService services.UserService) *UserHandlers {
+	return &UserHandlers{userService: userService}
+}
+
+// GetProfile returns the authenticated user's profile
+func (h *UserHandlers) GetProfile(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	user, err := h.userService.GetProfile(c.Request.Context(), userIDInt)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, user, "Profile retrieved successfully")
+}
+
+// UpdateProfile updates the authenticated user's username or email
+func (h *UserHandlers) UpdateProfile(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	var req struct {
+		Username string `json:"username,omitempty"`
+		Email    string `json:"email,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Username != "" {
+		updates["username"] = req.Username
+	}
+	if req.Email != "" {
+		updates["email"] = req.Email
+	}
+
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userIDInt, updates)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, user, "Profile updated successfully")
+}
+
+// DeactivateAccount deactivates the authenticated user's account and revokes their sessions
+func (h *UserHandlers) DeactivateAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	if err := h.userService.DeactivateAccount(c.Request.Context(), userIDInt); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Account deactivated successfully")
+}
+
+// ChangePassword replaces the authenticated user's password after verifying the current one
+func (h *UserHandlers) ChangePassword(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDInt := userID.(int)
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		Password        string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ValidationErrorResponse(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.userService.ChangePassword(c.Request.Context(), userIDInt, req.CurrentPassword, req.Password); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	SuccessResponse(c, nil, "Password changed successfully")
+}
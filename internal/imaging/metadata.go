@@ -0,0 +1,262 @@
+// Package imaging prepares uploaded images: it strips identifying metadata without
+// re-encoding, and decodes images to produce thumbnails and placeholders.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrMalformed is returned for an image whose structure cannot be followed
+var ErrMalformed = errors.New("imaging: malformed image")
+
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+	TypeWebP = "image/webp"
+)
+
+// Supported reports whether images of a content type are stripped and thumbnailed
+func Supported(contentType string) bool {
+	switch contentType {
+	case TypeJPEG, TypePNG, TypeGIF, TypeWebP:
+		return true
+	}
+	return false
+}
+
+// StripMetadata removes EXIF, XMP, IPTC and text metadata, which can carry the location a
+// photo was taken at, the device and its owner. Pixel data is copied untouched. A JPEG keeps
+// its orientation, re-written as the only EXIF field, so it still displays upright. GIFs have
+// no such metadata and are returned as they are.
+func StripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case TypeJPEG:
+		return stripJPEG(data)
+	case TypePNG:
+		return stripPNG(data)
+	case TypeWebP:
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+const (
+	markerSOI   = 0xD8
+	markerSOS   = 0xDA
+	markerAPP0  = 0xE0
+	markerAPP1  = 0xE1
+	markerAPP2  = 0xE2
+	markerAPP14 = 0xEE
+	markerAPP15 = 0xEF
+	markerCOM   = 0xFE
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// stripJPEG drops APP1, APP3 to APP13 and APP15 segments and comments before the scan data. APP0
+// (JFIF), APP2 (ICC colour profiles) and APP14 (Adobe colour transform) affect how the image
+// decodes and are kept.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil, ErrMalformed
+	}
+
+	// JFIF requires APP0 straight after SOI, so the kept EXIF goes between them and the rest
+	var jfif, kept []byte
+	orientation := 1
+
+	for pos := 2; ; {
+		// Markers may be preceded by any number of 0xFF fill bytes
+		for pos+1 < len(data) && data[pos] == 0xFF && data[pos+1] == 0xFF {
+			pos++
+		}
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, ErrMalformed
+		}
+		marker := data[pos+1]
+		if marker == markerSOS {
+			// Everything from the first scan on is image data
+			out := make([]byte, 0, len(data))
+			out = append(out, 0xFF, markerSOI)
+			out = append(out, jfif...)
+			out = append(out, orientationSegment(orientation)...)
+			out = append(out, kept...)
+			return append(out, data[pos:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrMalformed
+		}
+		segment := data[pos:end]
+
+		switch {
+		case marker == markerAPP0:
+			jfif = append(jfif, segment...)
+		case marker == markerAPP1:
+			if body := segment[4:]; bytes.HasPrefix(body, exifHeader) && orientation == 1 {
+				orientation = exifOrientation(body[len(exifHeader):])
+			}
+		case marker == markerAPP2, marker == markerAPP14:
+			kept = append(kept, segment...)
+		case marker > markerAPP2 && marker <= markerAPP15, marker == markerCOM:
+		default:
+			kept = append(kept, segment...)
+		}
+		pos = end
+	}
+}
+
+// orientationSegment builds an APP1 segment whose EXIF holds nothing but the orientation, or
+// nothing at all for the default orientation
+func orientationSegment(orientation int) []byte {
+	if orientation == 1 {
+		return nil
+	}
+
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big-endian header, first IFD at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, // Orientation, SHORT, count 1
+		0, 0, 0, 0, // no next IFD
+	}
+	segment := []byte{0xFF, markerAPP1, 0, 0}
+	segment = append(segment, exifHeader...)
+	segment = append(segment, tiff...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+	return segment
+}
+
+// exifOrientation reads the Orientation tag from IFD0 of a TIFF structure, returning 1 when
+// it is absent or invalid
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// Orientation returns the EXIF orientation of a JPEG, 1 to 8, as written by StripMetadata
+func Orientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		marker := data[pos+1]
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == markerSOS || end > len(data) {
+			break
+		}
+		if body := data[pos+4 : end]; marker == markerAPP1 && bytes.HasPrefix(body, exifHeader) {
+			return exifOrientation(body[len(exifHeader):])
+		}
+		pos = end
+	}
+	return 1
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks hold EXIF, free text (where XMP also lives) and the modification time
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for pos := len(pngSignature); pos < len(data); {
+		if pos+12 > len(data) {
+			return nil, ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrMalformed
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+const (
+	vp8xFlagEXIF = 0x08
+	vp8xFlagXMP  = 0x04
+)
+
+// stripWebP drops the EXIF and XMP chunks of an extended WebP and clears their flags
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, ErrMalformed
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= vp8xFlagEXIF | vp8xFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,143 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment builds an APP1 segment with an orientation and a GPS IFD pointer, followed by
+// a marker string standing in for the coordinates
+func exifSegment(orientation int) []byte {
+	tiff := []byte{
+		'I', 'I', 42, 0, 8, 0, 0, 0,
+		2, 0,
+		0x12, 0x01, 3, 0, 1, 0, 0, 0, byte(orientation), 0, 0, 0,
+		0x25, 0x88, 4, 0, 1, 0, 0, 0, 38, 0, 0, 0,
+		0, 0, 0, 0,
+	}
+	tiff = append(tiff, []byte("GPS 51.5007N 0.1246W")...)
+
+	segment := []byte{0xFF, markerAPP1, 0, 0}
+	segment = append(segment, exifHeader...)
+	segment = append(segment, tiff...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+	return segment
+}
+
+func jpegWithEXIF(t *testing.T, img image.Image, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	encoded := buf.Bytes()
+
+	comment := []byte{0xFF, markerCOM, 0, 10, 'm', 'y', ' ', 'p', 'h', 'o', 'n', 'e'}
+	data := append([]byte{0xFF, markerSOI}, exifSegment(orientation)...)
+	data = append(data, comment...)
+	return append(data, encoded[2:]...)
+}
+
+func TestStripJPEGKeepsOnlyOrientation(t *testing.T) {
+	data := jpegWithEXIF(t, testImage(16, 8), 6)
+	require.Equal(t, 6, Orientation(data))
+
+	stripped, err := StripMetadata(TypeJPEG, data)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "my phone")
+	assert.Equal(t, 6, Orientation(stripped))
+
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 16, 8), img.Bounds())
+}
+
+func TestStripJPEGWithoutOrientationDropsEXIFEntirely(t *testing.T) {
+	stripped, err := StripMetadata(TypeJPEG, jpegWithEXIF(t, testImage(8, 8), 1))
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "Exif")
+	assert.Equal(t, 1, Orientation(stripped))
+}
+
+func TestStripJPEGRejectsTruncatedFiles(t *testing.T) {
+	data := jpegWithEXIF(t, testImage(8, 8), 1)
+	_, err := StripMetadata(TypeJPEG, data[:20])
+	assert.ErrorIs(t, err, ErrMalformed)
+	_, err = StripMetadata(TypeJPEG, []byte("not a jpeg"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripPNGDropsTextAndEXIFChunks(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(4, 4)))
+	encoded := buf.Bytes()
+
+	// Metadata chunks go after IHDR, which is the first chunk
+	ihdrEnd := len(pngSignature) + 12 + 13
+	data := append([]byte(nil), encoded[:ihdrEnd]...)
+	data = append(data, pngChunk("tEXt", []byte("Author\x00Jane"))...)
+	data = append(data, pngChunk("eXIf", []byte("MM\x00\x2aGPS"))...)
+	data = append(data, encoded[ihdrEnd:]...)
+
+	stripped, err := StripMetadata(TypePNG, data)
+	require.NoError(t, err)
+	assert.Equal(t, encoded, stripped)
+}
+
+func webpChunk(fourCC string, data []byte) []byte {
+	chunk := []byte(fourCC)
+	chunk = binary.LittleEndian.AppendUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func TestStripWebPDropsMetadataChunksAndFlags(t *testing.T) {
+	vp8x := make([]byte, 10)
+	vp8x[0] = vp8xFlagEXIF | vp8xFlagXMP | 0x10 // with alpha
+	body := []byte("WEBP")
+	body = append(body, webpChunk("VP8X", vp8x)...)
+	body = append(body, webpChunk("VP8L", []byte{0x2f, 1, 2})...)
+	body = append(body, webpChunk("EXIF", []byte("GPS data"))...)
+	body = append(body, webpChunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := StripMetadata(TypeWebP, data)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "xmpmeta")
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:]))
+	assert.Equal(t, byte(0x10), stripped[20])
+	assert.Contains(t, string(stripped), "VP8L")
+}
@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	_ "image/gif" // register decoders for Process
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const thumbnailQuality = 80
+
+// placeholderSize is the side of the small image a placeholder is computed from; more
+// pixels would not change the blurred result
+const placeholderSize = 32
+
+// Result is what processing an image produces. Dimensions are as displayed, after the EXIF
+// orientation is applied.
+type Result struct {
+	Width           int
+	Height          int
+	Thumbnail       []byte
+	ThumbnailWidth  int
+	ThumbnailHeight int
+	Placeholder     string
+}
+
+// Process decodes an image and renders its JPEG thumbnail, at most thumbnailSize pixels a
+// side, and its BlurHash placeholder. Images over maxPixels are refused before decoding so a
+// small file cannot claim gigabytes of memory.
+func Process(data []byte, maxPixels, thumbnailSize int) (*Result, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("imaging: %dx%d image exceeds %d pixels", cfg.Width, cfg.Height, maxPixels)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Orienting the thumbnail rather than the original is far cheaper and gives the same result
+	orientation := Orientation(data)
+	thumb := Orient(Thumbnail(img, thumbnailSize), orientation)
+	encoded, err := EncodeJPEG(thumb)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Width:           cfg.Width,
+		Height:          cfg.Height,
+		Thumbnail:       encoded,
+		ThumbnailWidth:  thumb.Bounds().Dx(),
+		ThumbnailHeight: thumb.Bounds().Dy(),
+		Placeholder:     BlurHash(Thumbnail(thumb, placeholderSize), 4, 3),
+	}
+	if orientation >= 5 {
+		result.Width, result.Height = cfg.Height, cfg.Width
+	}
+	return result, nil
+}
+
+// Fit scales width and height down to fit within a square of side size, keeping the aspect
+// ratio. Images already small enough are left as they are.
+func Fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// Thumbnail scales an image to fit within size pixels a side. Transparent areas are flattened
+// onto white, as thumbnails are JPEGs.
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := Fit(bounds.Dx(), bounds.Dy(), size)
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	stddraw.Draw(thumb, thumb.Bounds(), image.NewUniform(color.White), image.Point{}, stddraw.Src)
+	draw.BiLinear.Scale(thumb, thumb.Bounds(), img, bounds, draw.Over, nil)
+	return thumb
+}
+
+// EncodeJPEG encodes a thumbnail
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Orient applies an EXIF orientation, 1 to 8, so the image reads upright
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := img.Bounds()
+	w, h := src.Dx(), src.Dy()
+	// Orientations 5 to 8 swap the axes
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			out.Set(dx, dy, img.At(src.Min.X+x, src.Min.Y+y))
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFit(t *testing.T) {
+	cases := []struct{ w, h, size, ew, eh int }{
+		{4000, 3000, 320, 320, 240},
+		{3000, 4000, 320, 240, 320},
+		{100, 50, 320, 100, 50},
+		{10000, 1, 320, 320, 1},
+	}
+	for _, tc := range cases {
+		w, h := Fit(tc.w, tc.h, tc.size)
+		assert.Equal(t, []int{tc.ew, tc.eh}, []int{w, h})
+	}
+}
+
+func TestOrientMovesCorners(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	marked := color.RGBA{R: 255, A: 255}
+	img.Set(0, 0, marked)
+
+	// Where the top-left pixel ends up for each orientation of a 3x2 image
+	expected := map[int]image.Point{2: {2, 0}, 3: {2, 1}, 4: {0, 1}, 5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2}}
+	for orientation, at := range expected {
+		out := Orient(img, orientation)
+		if orientation >= 5 {
+			assert.Equal(t, image.Rect(0, 0, 2, 3), out.Bounds(), orientation)
+		}
+		r, _, _, _ := out.At(at.X, at.Y).RGBA()
+		assert.Equal(t, uint32(0xffff), r, "orientation %d", orientation)
+	}
+}
+
+func TestProcessRotatesDimensionsAndBoundsThumbnail(t *testing.T) {
+	data := jpegWithEXIF(t, testImage(400, 200), 6)
+	data, err := StripMetadata(TypeJPEG, data)
+	require.NoError(t, err)
+
+	result, err := Process(data, 1_000_000, 100)
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, result.Width)
+	assert.Equal(t, 400, result.Height)
+	assert.Equal(t, 50, result.ThumbnailWidth)
+	assert.Equal(t, 100, result.ThumbnailHeight)
+	assert.Len(t, result.Placeholder, 28)
+
+	thumb, err := jpeg.Decode(bytes.NewReader(result.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 50, 100), thumb.Bounds())
+}
+
+func TestProcessRefusesOversizedImages(t *testing.T) {
+	_, err := Process(jpegWithEXIF(t, testImage(400, 200), 1), 400*200-1, 100)
+	assert.Error(t, err)
+
+	_, err = Process([]byte("not an image"), 1_000_000, 100)
+	assert.Error(t, err)
+}
+
+func TestBlurHashEncodesSizeAndAverageColour(t *testing.T) {
+	solid := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			solid.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	hash := BlurHash(solid, 4, 3)
+	assert.Len(t, hash, 28)
+	// Size flag 3+2*9, then after the AC scale the average colour, pure red
+	assert.Equal(t, "L", hash[:1])
+	assert.Equal(t, "TI:j", hash[2:6])
+
+	assert.Len(t, BlurHash(solid, 1, 1), 6)
+}
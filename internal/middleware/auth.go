@@ -45,6 +45,7 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		c.Set("user", user)
 		c.Set("user_id", user.ID)
 		c.Set("username", user.Username)
+		c.Set("token", token)
 
 		c.Next()
 	}
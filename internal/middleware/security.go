@@ -84,7 +84,18 @@ func (m *SecurityMiddleware) CORS() gin.HandlerFunc {
 }
 
 func (m *SecurityMiddleware) RequestSizeLimit(maxSize int64) gin.HandlerFunc {
+	return m.RequestSizeLimitByRoute(maxSize, nil)
+}
+
+// RequestSizeLimitByRoute applies maxSize to every route except those listed by full path,
+// such as uploads, which get their own limit
+func (m *SecurityMiddleware) RequestSizeLimitByRoute(maxSize int64, routes map[string]int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		maxSize := maxSize
+		if routeMax, ok := routes[c.FullPath()]; ok {
+			maxSize = routeMax
+		}
+
 		if c.Request.ContentLength > maxSize {
 			m.logger.Warn("Request too large", "size", c.Request.ContentLength, "max", maxSize)
 			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request too large"})
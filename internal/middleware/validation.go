@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -9,6 +11,7 @@ import (
 	"chat_app/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type ValidationMiddleware struct {
@@ -55,7 +58,7 @@ func (m *ValidationMiddleware) ValidateUsername() gin.HandlerFunc {
 			Username string `json:"username" binding:"required"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := bindJSONPreservingBody(c, &req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			c.Abort()
 			return
@@ -78,7 +81,7 @@ func (m *ValidationMiddleware) ValidateEmail() gin.HandlerFunc {
 			Email string `json:"email" binding:"required"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := bindJSONPreservingBody(c, &req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			c.Abort()
 			return
@@ -101,7 +104,7 @@ func (m *ValidationMiddleware) ValidatePassword() gin.HandlerFunc {
 			Password string `json:"password" binding:"required"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := bindJSONPreservingBody(c, &req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			c.Abort()
 			return
@@ -121,23 +124,28 @@ func (m *ValidationMiddleware) ValidatePassword() gin.HandlerFunc {
 func (m *ValidationMiddleware) ValidateMessage() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			Content string `json:"content" binding:"required"`
-			Room    string `json:"room" binding:"required"`
+			Content       string `json:"content"`
+			RoomID        int    `json:"room_id"`
+			Room          string `json:"room"`
+			AttachmentIDs []int  `json:"attachment_ids"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := bindJSONPreservingBody(c, &req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			c.Abort()
 			return
 		}
 
-		if !m.isValidMessage(req.Content) {
+		// A message carrying attachments may go without text
+		withoutText := len(req.AttachmentIDs) > 0 && strings.TrimSpace(req.Content) == ""
+		if !withoutText && !m.isValidMessage(req.Content) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message content"})
 			c.Abort()
 			return
 		}
 
-		if !m.isValidRoomName(req.Room) {
+		// Conversations have no name, so a message may name its room by ID instead
+		if req.RoomID <= 0 && !m.isValidRoomName(req.Room) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room name"})
 			c.Abort()
 			return
@@ -149,6 +157,18 @@ func (m *ValidationMiddleware) ValidateMessage() gin.HandlerFunc {
 	}
 }
 
+// bindJSONPreservingBody decodes the request body and then restores it, so several validators
+// and the route handler can each bind the same JSON payload.
+func bindJSONPreservingBody(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindBodyWith(obj, binding.JSON); err != nil {
+		return err
+	}
+	if body, ok := c.Get(gin.BodyBytesKey); ok {
+		c.Request.Body = io.NopCloser(bytes.NewReader(body.([]byte)))
+	}
+	return nil
+}
+
 func (m *ValidationMiddleware) sanitizeString(input string) string {
 	input = strings.TrimSpace(input)
 
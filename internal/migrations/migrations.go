@@ -50,6 +50,120 @@ var migrations = []Migration{
 		Up:      createIndexes,
 		Down:    dropIndexes,
 	},
+	{
+		Version: 7,
+		Name:    "create_refresh_tokens_table",
+		Up:      createRefreshTokensTable,
+		Down:    dropRefreshTokensTable,
+	},
+	{
+		Version: 8,
+		Name:    "add_message_sequences",
+		Up:      addMessageSequences,
+		Down:    dropMessageSequences,
+	},
+	{
+		Version: 9,
+		Name:    "add_room_member_read_markers",
+		Up:      addRoomMemberReadMarkers,
+		Down:    dropRoomMemberReadMarkers,
+	},
+	{
+		Version: 10,
+		Name:    "add_room_member_roles",
+		Up:      addRoomMemberRoles,
+		Down:    dropRoomMemberRoles,
+	},
+	{
+		Version: 11,
+		Name:    "keep_rooms_when_creator_deleted",
+		Up:      keepRoomsWhenCreatorDeleted,
+		Down:    cascadeRoomsWithCreator,
+	},
+	{
+		Version: 12,
+		Name:    "create_room_invites_table",
+		Up:      createRoomInvitesTable,
+		Down:    dropRoomInvitesTable,
+	},
+	{
+		Version: 13,
+		Name:    "create_room_join_requests_table",
+		Up:      createRoomJoinRequestsTable,
+		Down:    dropRoomJoinRequestsTable,
+	},
+	{
+		Version: 14,
+		Name:    "create_room_sanctions_table",
+		Up:      createRoomSanctionsTable,
+		Down:    dropRoomSanctionsTable,
+	},
+	{
+		Version: 15,
+		Name:    "add_user_admin_flag",
+		Up:      addUserAdminFlag,
+		Down:    dropUserAdminFlag,
+	},
+	{
+		Version: 16,
+		Name:    "create_audit_log_table",
+		Up:      createAuditLogTable,
+		Down:    dropAuditLogTable,
+	},
+	{
+		Version: 17,
+		Name:    "add_room_conversations",
+		Up:      addRoomConversations,
+		Down:    dropRoomConversations,
+	},
+	{
+		Version: 18,
+		Name:    "add_message_threads",
+		Up:      addMessageThreads,
+		Down:    dropMessageThreads,
+	},
+	{
+		Version: 19,
+		Name:    "create_message_reactions_table",
+		Up:      createMessageReactionsTable,
+		Down:    dropMessageReactionsTable,
+	},
+	{
+		Version: 20,
+		Name:    "add_message_revisions",
+		Up:      addMessageRevisions,
+		Down:    dropMessageRevisions,
+	},
+	{
+		Version: 21,
+		Name:    "add_message_search",
+		Up:      addMessageSearch,
+		Down:    dropMessageSearch,
+	},
+	{
+		Version: 22,
+		Name:    "create_attachments_table",
+		Up:      createAttachmentsTable,
+		Down:    dropAttachmentsTable,
+	},
+	{
+		Version: 23,
+		Name:    "add_attachment_images",
+		Up:      addAttachmentImages,
+		Down:    dropAttachmentImages,
+	},
+	{
+		Version: 24,
+		Name:    "create_mentions_table",
+		Up:      createMentionsTable,
+		Down:    dropMentionsTable,
+	},
+	{
+		Version: 25,
+		Name:    "create_notifications_tables",
+		Up:      createNotificationsTables,
+		Down:    dropNotificationsTables,
+	},
 }
 
 func RunMigrations(db *sql.DB) error {
@@ -296,6 +410,575 @@ func dropIndexes(db *sql.DB) error {
 	return nil
 }
 
+func createRefreshTokensTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			session_id VARCHAR(255) NOT NULL,
+			user_id INT NOT NULL,
+			family_id VARCHAR(64) NOT NULL,
+			token_hash CHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			rotated_at TIMESTAMP NULL,
+			revoked_at TIMESTAMP NULL,
+			INDEX idx_refresh_tokens_family_id (family_id),
+			INDEX idx_refresh_tokens_session_id (session_id),
+			INDEX idx_refresh_tokens_expires_at (expires_at),
+			FOREIGN KEY (session_id) REFERENCES user_sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropRefreshTokensTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS refresh_tokens")
+	return err
+}
+
+// addMessageSequences gives every message a per-room sequence number. rooms.last_seq is the
+// counter new messages draw from; existing history is numbered in creation order.
+func addMessageSequences(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE rooms ADD COLUMN last_seq BIGINT NOT NULL DEFAULT 0",
+		"ALTER TABLE messages ADD COLUMN seq BIGINT NOT NULL DEFAULT 0",
+		`UPDATE messages m
+			INNER JOIN (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY room_id ORDER BY created_at, id) AS seq
+				FROM messages
+			) numbered ON numbered.id = m.id
+			SET m.seq = numbered.seq`,
+		`UPDATE rooms r
+			SET r.last_seq = (SELECT COALESCE(MAX(m.seq), 0) FROM messages m WHERE m.room_id = r.id)`,
+		"CREATE UNIQUE INDEX idx_messages_room_seq ON messages(room_id, seq)",
+	}
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropMessageSequences(db *sql.DB) error {
+	statements := []string{
+		"DROP INDEX idx_messages_room_seq ON messages",
+		"ALTER TABLE messages DROP COLUMN seq",
+		"ALTER TABLE rooms DROP COLUMN last_seq",
+	}
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRoomMemberReadMarkers records the last sequence number each member has read, which
+// unread counts are derived from
+func addRoomMemberReadMarkers(db *sql.DB) error {
+	_, err := db.Exec("ALTER TABLE room_members ADD COLUMN last_read_seq BIGINT NOT NULL DEFAULT 0")
+	return err
+}
+
+func dropRoomMemberReadMarkers(db *sql.DB) error {
+	_, err := db.Exec("ALTER TABLE room_members DROP COLUMN last_read_seq")
+	return err
+}
+
+// addRoomMemberRoles gives every member a role; room creators become owners so existing
+// rooms keep the permissions they had
+func addRoomMemberRoles(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE room_members ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member'",
+		`UPDATE room_members rm
+			INNER JOIN rooms r ON rm.room_id = r.id
+			SET rm.role = 'owner'
+			WHERE rm.user_id = r.created_by`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropRoomMemberRoles(db *sql.DB) error {
+	_, err := db.Exec("ALTER TABLE room_members DROP COLUMN role")
+	return err
+}
+
+// roomsCreatorForeignKey finds the generated name of the rooms.created_by foreign key
+func roomsCreatorForeignKey(db *sql.DB) (string, error) {
+	var name string
+	err := db.QueryRow(`
+		SELECT CONSTRAINT_NAME FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'rooms'
+			AND COLUMN_NAME = 'created_by' AND REFERENCED_TABLE_NAME = 'users'`).Scan(&name)
+	return name, err
+}
+
+// keepRoomsWhenCreatorDeleted stops deleting a creator's account from cascading into their
+// rooms and every message in them; created_by is cleared instead
+func keepRoomsWhenCreatorDeleted(db *sql.DB) error {
+	name, err := roomsCreatorForeignKey(db)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		"ALTER TABLE rooms DROP FOREIGN KEY " + name,
+		"ALTER TABLE rooms MODIFY created_by INT NULL",
+		"ALTER TABLE rooms ADD CONSTRAINT fk_rooms_created_by FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cascadeRoomsWithCreator(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE rooms DROP FOREIGN KEY fk_rooms_created_by",
+		"DELETE FROM rooms WHERE created_by IS NULL",
+		"ALTER TABLE rooms MODIFY created_by INT NOT NULL",
+		"ALTER TABLE rooms ADD CONSTRAINT fk_rooms_created_by FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createRoomInvitesTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS room_invites (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			room_id INT NOT NULL,
+			code VARCHAR(64) UNIQUE NOT NULL,
+			created_by INT NULL,
+			role VARCHAR(20) NOT NULL DEFAULT 'member',
+			max_uses INT NULL,
+			uses INT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NULL,
+			revoked_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_room_invites_room_id (room_id),
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropRoomInvitesTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS room_invites")
+	return err
+}
+
+func createRoomJoinRequestsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS room_join_requests (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			room_id INT NOT NULL,
+			user_id INT NOT NULL,
+			message VARCHAR(500) NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			decided_by INT NULL,
+			decided_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE KEY unique_room_join_request (room_id, user_id),
+			INDEX idx_room_join_requests_status (room_id, status),
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropRoomJoinRequestsTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS room_join_requests")
+	return err
+}
+
+func createRoomSanctionsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS room_sanctions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			room_id INT NOT NULL,
+			user_id INT NOT NULL,
+			kind VARCHAR(10) NOT NULL,
+			reason VARCHAR(500) NOT NULL DEFAULT '',
+			created_by INT NULL,
+			expires_at TIMESTAMP NULL,
+			lifted_at TIMESTAMP NULL,
+			lifted_by INT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_room_sanctions_lookup (room_id, user_id, kind),
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+			FOREIGN KEY (lifted_by) REFERENCES users(id) ON DELETE SET NULL
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropRoomSanctionsTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS room_sanctions")
+	return err
+}
+
+func addUserAdminFlag(db *sql.DB) error {
+	_, err := db.Exec("ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE")
+	return err
+}
+
+func dropUserAdminFlag(db *sql.DB) error {
+	_, err := db.Exec("ALTER TABLE users DROP COLUMN is_admin")
+	return err
+}
+
+// createAuditLogTable has no foreign keys on purpose: the log has to keep naming rooms,
+// users and messages after they are deleted
+func createAuditLogTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			room_id INT NULL,
+			actor_id INT NULL,
+			actor_username VARCHAR(50) NOT NULL DEFAULT '',
+			action VARCHAR(50) NOT NULL,
+			target_user_id INT NULL,
+			target_username VARCHAR(50) NOT NULL DEFAULT '',
+			target_message_id INT NULL,
+			reason VARCHAR(500) NOT NULL DEFAULT '',
+			before_state JSON NULL,
+			after_state JSON NULL,
+			created_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3),
+			INDEX idx_audit_log_room (room_id, created_at),
+			INDEX idx_audit_log_actor (actor_id, created_at),
+			INDEX idx_audit_log_target (target_user_id, created_at),
+			INDEX idx_audit_log_created (created_at)
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropAuditLogTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS audit_log")
+	return err
+}
+
+// addRoomConversations lets rooms double as direct and group conversations. These have no
+// name, so names become nullable, and are found by their participant set instead.
+func addRoomConversations(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE rooms MODIFY name VARCHAR(100) NULL",
+		"ALTER TABLE rooms ADD COLUMN kind VARCHAR(10) NOT NULL DEFAULT 'room'",
+		"ALTER TABLE rooms ADD COLUMN participant_key VARCHAR(255) NULL",
+		"ALTER TABLE rooms ADD UNIQUE INDEX idx_rooms_participant_key (participant_key)",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropRoomConversations(db *sql.DB) error {
+	statements := []string{
+		"DELETE FROM rooms WHERE kind <> 'room'",
+		"ALTER TABLE rooms DROP INDEX idx_rooms_participant_key",
+		"ALTER TABLE rooms DROP COLUMN participant_key",
+		"ALTER TABLE rooms DROP COLUMN kind",
+		"ALTER TABLE rooms MODIFY name VARCHAR(100) NOT NULL",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addMessageThreads(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE messages ADD COLUMN parent_id INT NULL",
+		"ALTER TABLE messages ADD COLUMN reply_count INT NOT NULL DEFAULT 0",
+		"ALTER TABLE messages ADD COLUMN last_reply_at TIMESTAMP NULL",
+		"ALTER TABLE messages ADD INDEX idx_messages_parent_seq (parent_id, seq)",
+		"ALTER TABLE messages ADD CONSTRAINT fk_messages_parent FOREIGN KEY (parent_id) REFERENCES messages(id) ON DELETE SET NULL",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropMessageThreads(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE messages DROP FOREIGN KEY fk_messages_parent",
+		"ALTER TABLE messages DROP INDEX idx_messages_parent_seq",
+		"ALTER TABLE messages DROP COLUMN last_reply_at",
+		"ALTER TABLE messages DROP COLUMN reply_count",
+		"ALTER TABLE messages DROP COLUMN parent_id",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The binary collation keeps distinct emoji distinct; the general ones compare many as equal
+func createMessageReactionsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS message_reactions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			message_id INT NOT NULL,
+			user_id INT NOT NULL,
+			emoji VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE KEY uniq_message_reactions (message_id, user_id, emoji),
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropMessageReactionsTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS message_reactions")
+	return err
+}
+
+func addMessageRevisions(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE messages ADD COLUMN edited_at TIMESTAMP NULL",
+		"ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMP NULL",
+		"ALTER TABLE messages ADD COLUMN deleted_by INT NULL",
+		"ALTER TABLE messages ADD CONSTRAINT fk_messages_deleted_by FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL",
+		`CREATE TABLE IF NOT EXISTS message_revisions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			message_id INT NOT NULL,
+			action VARCHAR(10) NOT NULL,
+			content TEXT NOT NULL,
+			edited_by INT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_message_revisions_message (message_id),
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+			FOREIGN KEY (edited_by) REFERENCES users(id) ON DELETE SET NULL
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropMessageRevisions(db *sql.DB) error {
+	statements := []string{
+		"DROP TABLE IF EXISTS message_revisions",
+		"ALTER TABLE messages DROP FOREIGN KEY fk_messages_deleted_by",
+		"ALTER TABLE messages DROP COLUMN deleted_by",
+		"ALTER TABLE messages DROP COLUMN deleted_at",
+		"ALTER TABLE messages DROP COLUMN edited_at",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMessageSearch indexes message content for full-text search. attachment_count lets search
+// filter on attachments without joining whatever stores them.
+func addMessageSearch(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE messages ADD FULLTEXT INDEX idx_messages_content (content)",
+		"ALTER TABLE messages ADD COLUMN attachment_count INT NOT NULL DEFAULT 0",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropMessageSearch(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE messages DROP COLUMN attachment_count",
+		"ALTER TABLE messages DROP INDEX idx_messages_content",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createAttachmentsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS attachments (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			room_id INT NOT NULL,
+			uploader_id INT NULL,
+			message_id INT NULL,
+			filename VARCHAR(255) NOT NULL,
+			content_type VARCHAR(255) NOT NULL,
+			size BIGINT NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			storage_key VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_attachments_room (room_id),
+			INDEX idx_attachments_message (message_id),
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (uploader_id) REFERENCES users(id) ON DELETE SET NULL,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropAttachmentsTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS attachments")
+	return err
+}
+
+func addAttachmentImages(db *sql.DB) error {
+	statements := []string{
+		"ALTER TABLE attachments ADD COLUMN image_status VARCHAR(16) NOT NULL DEFAULT ''",
+		"ALTER TABLE attachments ADD COLUMN width INT NOT NULL DEFAULT 0",
+		"ALTER TABLE attachments ADD COLUMN height INT NOT NULL DEFAULT 0",
+		"ALTER TABLE attachments ADD COLUMN thumbnail_key VARCHAR(255) NOT NULL DEFAULT ''",
+		"ALTER TABLE attachments ADD COLUMN thumbnail_width INT NOT NULL DEFAULT 0",
+		"ALTER TABLE attachments ADD COLUMN thumbnail_height INT NOT NULL DEFAULT 0",
+		"ALTER TABLE attachments ADD COLUMN placeholder VARCHAR(64) NOT NULL DEFAULT ''",
+		"CREATE INDEX idx_attachments_image_status ON attachments(image_status)",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropAttachmentImages(db *sql.DB) error {
+	statements := []string{
+		"DROP INDEX idx_attachments_image_status ON attachments",
+		"ALTER TABLE attachments DROP COLUMN placeholder",
+		"ALTER TABLE attachments DROP COLUMN thumbnail_height",
+		"ALTER TABLE attachments DROP COLUMN thumbnail_width",
+		"ALTER TABLE attachments DROP COLUMN thumbnail_key",
+		"ALTER TABLE attachments DROP COLUMN height",
+		"ALTER TABLE attachments DROP COLUMN width",
+		"ALTER TABLE attachments DROP COLUMN image_status",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createMentionsTable stores one row per mention in a message. Room and here mentions have no
+// user, so mentioning a whole room is a single row however many members it has.
+func createMentionsTable(db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS mentions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			message_id INT NOT NULL,
+			room_id INT NOT NULL,
+			kind VARCHAR(8) NOT NULL,
+			user_id INT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE KEY unique_mention (message_id, kind, user_id),
+			INDEX idx_mentions_user (user_id, id),
+			INDEX idx_mentions_room_kind (room_id, kind, id),
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func dropMentionsTable(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS mentions")
+	return err
+}
+
+func createNotificationsTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			room_id INT NULL,
+			message_id INT NULL,
+			actor_id INT NULL,
+			reason VARCHAR(500) NOT NULL DEFAULT '',
+			read_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_notifications_user (user_id, id),
+			INDEX idx_notifications_user_unread (user_id, read_at),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL,
+			FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS notification_mutes (
+			user_id INT NOT NULL,
+			room_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, room_id),
+			INDEX idx_notification_mutes_room (room_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropNotificationsTables(db *sql.DB) error {
+	statements := []string{
+		"DROP TABLE IF EXISTS notification_mutes",
+		"DROP TABLE IF EXISTS notifications",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetCurrentVersion(db *sql.DB) (int, error) {
 	return getCurrentVersion(db)
 }
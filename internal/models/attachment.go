@@ -0,0 +1,96 @@
+package models
+
+import (
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxMessageAttachments bounds how many attachments one message may reference
+const MaxMessageAttachments = 10
+
+const maxFilenameBytes = 255
+
+// Attachment is an uploaded file. It belongs to the room it was uploaded to and, once a
+// message references it, to that message. ContentType is sniffed from the contents rather
+// than taken from the client, and Checksum is the hex SHA-256 of the contents.
+type Attachment struct {
+	ID          int       `json:"id" db:"id"`
+	RoomID      int       `json:"room_id" db:"room_id"`
+	UploaderID  *int      `json:"uploader_id" db:"uploader_id"`
+	MessageID   *int      `json:"message_id,omitempty" db:"message_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	Checksum    string    `json:"checksum" db:"checksum"`
+	StorageKey  string    `json:"-" db:"storage_key"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+
+	// Images are processed after upload; until ImageReady they have no dimensions or thumbnail
+	ImageStatus     ImageStatus `json:"image_status,omitempty" db:"image_status"`
+	Width           int         `json:"width,omitempty" db:"width"`
+	Height          int         `json:"height,omitempty" db:"height"`
+	ThumbnailKey    string      `json:"-" db:"thumbnail_key"`
+	ThumbnailWidth  int         `json:"thumbnail_width,omitempty" db:"thumbnail_width"`
+	ThumbnailHeight int         `json:"thumbnail_height,omitempty" db:"thumbnail_height"`
+	// Placeholder is a BlurHash of the image to show while it loads
+	Placeholder string `json:"placeholder,omitempty" db:"placeholder"`
+
+	// URL and ThumbnailURL are download links signed for the user reading the attachment
+	URL          string     `json:"url,omitempty" db:"-"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty" db:"-"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty" db:"-"`
+}
+
+// ImageStatus tracks an image attachment through processing; other files have none
+type ImageStatus string
+
+const (
+	ImagePending ImageStatus = "pending"
+	ImageReady   ImageStatus = "ready"
+	ImageFailed  ImageStatus = "failed"
+)
+
+// IsImage reports whether the attachment is a raster image that can be shown inline. SVG is
+// left out as it can carry scripts.
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/") && !strings.HasPrefix(a.ContentType, "image/svg")
+}
+
+// SanitizeFilename keeps the last path element of a client-supplied name and drops characters
+// that could break a Content-Disposition header or confuse a file manager
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r == utf8.RuneError, unicode.IsControl(r), r == '"', r == '/', r == '\\':
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	for len(name) > maxFilenameBytes {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// AttachmentUpload is a file as received from a client. Size is what the client declared and
+// the stored file must match it.
+type AttachmentUpload struct {
+	Filename string
+	Size     int64
+	Content  io.Reader
+}
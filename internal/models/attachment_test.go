@@ -0,0 +1,29 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := map[string]string{
+		"report.pdf":            "report.pdf",
+		"../../etc/passwd":      "passwd",
+		`C:\Users\me\photo.jpg`: "photo.jpg",
+		"quote\"d\r\nname.txt":  "quotedname.txt",
+		"tab\tseparated.txt":    "tab separated.txt",
+		"  ":                    "file",
+		"..":                    "file",
+		"":                      "file",
+		"résumé 2026.docx":      "résumé 2026.docx",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, SanitizeFilename(input), input)
+	}
+
+	long := SanitizeFilename(strings.Repeat("é", 200))
+	assert.LessOrEqual(t, len(long), 255)
+	assert.Equal(t, strings.Repeat("é", 127), long)
+}
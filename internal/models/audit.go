@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction names a moderation or administrative action recorded in the audit log
+type AuditAction string
+
+const (
+	AuditRoomUpdate        AuditAction = "room.update"
+	AuditRoomDelete        AuditAction = "room.delete"
+	AuditRoomReset         AuditAction = "room.reset"
+	AuditOwnershipTransfer AuditAction = "room.transfer_ownership"
+	AuditMemberInvite      AuditAction = "member.invite"
+	AuditMemberKick        AuditAction = "member.kick"
+	AuditMemberRoleChange  AuditAction = "member.role_change"
+	AuditMemberBan         AuditAction = "member.ban"
+	AuditMemberMute        AuditAction = "member.mute"
+	AuditSanctionLift      AuditAction = "sanction.lift"
+	AuditJoinRequestDecide AuditAction = "join_request.decide"
+	AuditInviteCreate      AuditAction = "invite.create"
+	AuditInviteRevoke      AuditAction = "invite.revoke"
+	AuditInviteRedeem      AuditAction = "invite.redeem"
+	AuditMessageEdit       AuditAction = "message.edit"
+	AuditMessageDelete     AuditAction = "message.delete"
+)
+
+// AuditEntry is one append-only audit record. Before and After are JSON snapshots of what
+// the action changed. IDs are kept as plain values so entries outlive the rows they name.
+type AuditEntry struct {
+	ID              int64           `json:"id" db:"id"`
+	RoomID          *int            `json:"room_id,omitempty" db:"room_id"`
+	ActorID         *int            `json:"actor_id,omitempty" db:"actor_id"`
+	ActorUsername   string          `json:"actor_username,omitempty" db:"actor_username"`
+	Action          AuditAction     `json:"action" db:"action"`
+	TargetUserID    *int            `json:"target_user_id,omitempty" db:"target_user_id"`
+	TargetUsername  string          `json:"target_username,omitempty" db:"target_username"`
+	TargetMessageID *int            `json:"target_message_id,omitempty" db:"target_message_id"`
+	Reason          string          `json:"reason,omitempty" db:"reason"`
+	Before          json.RawMessage `json:"before,omitempty" db:"before_state"`
+	After           json.RawMessage `json:"after,omitempty" db:"after_state"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter narrows an audit log query; zero fields match everything
+type AuditFilter struct {
+	RoomID       int
+	ActorID      int
+	TargetUserID int
+	Action       AuditAction
+	Since        *time.Time
+	Until        *time.Time
+	Limit        int
+	Offset       int
+}
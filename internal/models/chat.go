@@ -4,51 +4,227 @@ import (
 	"time"
 )
 
+// RoomKind separates named rooms from direct and group conversations, which share their
+// storage, messages and realtime delivery but have no name
+type RoomKind string
+
+const (
+	RoomKindRoom   RoomKind = "room"
+	RoomKindDirect RoomKind = "direct"
+	RoomKindGroup  RoomKind = "group"
+)
+
+// Room is a chat room or conversation. CreatedBy is nil once the creator's account has been
+// deleted; the room itself outlives them. ParticipantKey identifies a conversation by the
+// exact set of users in it.
 type Room struct {
-	ID          int       `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	IsPrivate   bool      `json:"is_private" db:"is_private"`
-	CreatedBy   int       `json:"created_by" db:"created_by"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
+	ID             int       `json:"id" db:"id"`
+	Name           string    `json:"name" db:"name"`
+	Description    string    `json:"description" db:"description"`
+	IsPrivate      bool      `json:"is_private" db:"is_private"`
+	Kind           RoomKind  `json:"kind" db:"kind"`
+	ParticipantKey string    `json:"-" db:"participant_key"`
+	CreatedBy      *int      `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	IsActive       bool      `json:"is_active" db:"is_active"`
+}
+
+// Conversation is a direct or group conversation together with its participants
+type Conversation struct {
+	Room
+	Participants []*RoomMember `json:"participants"`
 }
 
+type OpenConversationRequest struct {
+	Usernames []string `json:"usernames" binding:"required,min=1"`
+}
+
+// Message is a message in a room. A reply names the message that started its thread in
+// ParentID; the thread's first message carries the reply count and last reply time.
+// A deleted message stays behind as a tombstone: DeletedAt is set and Content is emptied,
+// its earlier text surviving only in its revisions. Reactions are filled in for the user
+// reading the message and are not stored with it.
 type Message struct {
-	ID        int       `json:"id" db:"id"`
-	RoomID    int       `json:"room_id" db:"room_id"`
-	UserID    int       `json:"user_id" db:"user_id"`
-	Username  string    `json:"username" db:"username"`
-	Content   string    `json:"content" db:"content"`
-	Type      string    `json:"type" db:"type"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          int        `json:"id" db:"id"`
+	RoomID      int        `json:"room_id" db:"room_id"`
+	Seq         int64      `json:"seq" db:"seq"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	Username    string     `json:"username" db:"username"`
+	Content     string     `json:"content" db:"content"`
+	Type        string     `json:"type" db:"type"`
+	ParentID    *int       `json:"parent_id,omitempty" db:"parent_id"`
+	ReplyCount  int        `json:"reply_count" db:"reply_count"`
+	LastReplyAt *time.Time `json:"last_reply_at,omitempty" db:"last_reply_at"`
+	EditedAt    *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy   *int       `json:"deleted_by,omitempty" db:"deleted_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+
+	AttachmentCount int `json:"attachment_count,omitempty" db:"attachment_count"`
+
+	Reactions   []ReactionSummary `json:"reactions,omitempty" db:"-"`
+	Attachments []*Attachment     `json:"attachments,omitempty" db:"-"`
+	Mentions    []*Mention        `json:"mentions,omitempty" db:"-"`
+	// AttachmentIDs are uploads a new message claims when it is created
+	AttachmentIDs []int `json:"-" db:"-"`
+}
+
+// RevisionAction is what replaced a revision's content
+type RevisionAction string
+
+const (
+	RevisionEdit   RevisionAction = "edit"
+	RevisionDelete RevisionAction = "delete"
+)
+
+// MessageRevision is a message's content as it was before an edit or deletion. EditedBy is
+// whoever made that change, nil once their account is gone.
+type MessageRevision struct {
+	ID               int            `json:"id" db:"id"`
+	MessageID        int            `json:"message_id" db:"message_id"`
+	Action           RevisionAction `json:"action" db:"action"`
+	Content          string         `json:"content" db:"content"`
+	EditedBy         *int           `json:"edited_by" db:"edited_by"`
+	EditedByUsername string         `json:"edited_by_username,omitempty" db:"-"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+}
+
+// MessageDeleted announces that a message became a tombstone
+type MessageDeleted struct {
+	MessageID int       `json:"message_id"`
+	RoomID    int       `json:"room_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ThreadUpdate is a thread's summary after a reply was added to or removed from it, so clients
+// can update it without loading the replies
+type ThreadUpdate struct {
+	RoomID      int        `json:"room_id"`
+	ParentID    int        `json:"parent_id"`
+	ReplyCount  int        `json:"reply_count"`
+	LastReplyAt *time.Time `json:"last_reply_at,omitempty"`
+}
+
+// Thread is a message together with a page of its replies, oldest first
+type Thread struct {
+	Parent  *Message   `json:"parent"`
+	Replies []*Message `json:"replies"`
 }
 
 type RoomMember struct {
 	ID       int       `json:"id" db:"id"`
 	RoomID   int       `json:"room_id" db:"room_id"`
 	UserID   int       `json:"user_id" db:"user_id"`
+	Username string    `json:"username,omitempty" db:"username"`
+	Role     RoomRole  `json:"role" db:"role"`
 	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
 	IsActive bool      `json:"is_active" db:"is_active"`
 }
 
-type WebSocketMessage struct {
-	Type      string                 `json:"type"`
-	Room      string                 `json:"room,omitempty"`
-	Content   string                 `json:"content,omitempty"`
-	Sender    string                 `json:"sender,omitempty"`
-	Timestamp time.Time              `json:"timestamp,omitempty"`
-	Data      map[string]interface{} `json:"data,omitempty"`
+// RoomReadState is a member's position in a room: the newest message, the newest one they
+// have read, and how many lie in between
+type RoomReadState struct {
+	RoomID      int    `json:"room_id"`
+	RoomName    string `json:"room_name"`
+	LastSeq     int64  `json:"last_seq"`
+	LastReadSeq int64  `json:"last_read_seq"`
+	Unread      int64  `json:"unread"`
+}
+
+// RoomInvite is a shareable code that lets whoever holds it join a room with Role. MaxUses
+// and ExpiresAt are optional limits.
+type RoomInvite struct {
+	ID        int        `json:"id" db:"id"`
+	RoomID    int        `json:"room_id" db:"room_id"`
+	Code      string     `json:"code" db:"code"`
+	CreatedBy *int       `json:"created_by" db:"created_by"`
+	Role      RoomRole   `json:"role" db:"role"`
+	MaxUses   *int       `json:"max_uses,omitempty" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending  JoinRequestStatus = "pending"
+	JoinRequestApproved JoinRequestStatus = "approved"
+	JoinRequestDenied   JoinRequestStatus = "denied"
+)
+
+// JoinRequest asks to join a private room. A user has at most one request per room; asking
+// again after a decision reopens it.
+type JoinRequest struct {
+	ID        int               `json:"id" db:"id"`
+	RoomID    int               `json:"room_id" db:"room_id"`
+	UserID    int               `json:"user_id" db:"user_id"`
+	Username  string            `json:"username" db:"username"`
+	Message   string            `json:"message,omitempty" db:"message"`
+	Status    JoinRequestStatus `json:"status" db:"status"`
+	DecidedBy *int              `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt *time.Time        `json:"decided_at,omitempty" db:"decided_at"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+}
+
+type SanctionKind string
+
+const (
+	// SanctionBan removes a user from a room and keeps them out
+	SanctionBan SanctionKind = "ban"
+	// SanctionMute lets a user keep reading a room but not post in it
+	SanctionMute SanctionKind = "mute"
+)
+
+// RoomSanction is a ban or mute. It is active until ExpiresAt, if set, or until lifted.
+type RoomSanction struct {
+	ID        int          `json:"id" db:"id"`
+	RoomID    int          `json:"room_id" db:"room_id"`
+	UserID    int          `json:"user_id" db:"user_id"`
+	Username  string       `json:"username" db:"username"`
+	Kind      SanctionKind `json:"kind" db:"kind"`
+	Reason    string       `json:"reason,omitempty" db:"reason"`
+	CreatedBy *int         `json:"created_by" db:"created_by"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty" db:"expires_at"`
+	LiftedAt  *time.Time   `json:"lifted_at,omitempty" db:"lifted_at"`
+	LiftedBy  *int         `json:"lifted_by,omitempty" db:"lifted_by"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+// RoomReset counts the members a room reset removed, and those it failed to remove and who are
+// still in the room
+type RoomReset struct {
+	Removed int `json:"removed"`
+	Failed  int `json:"failed"`
+}
+
+type SanctionRequest struct {
+	Username  string     `json:"username" binding:"required"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type CreateInviteRequest struct {
+	Role      RoomRole   `json:"role,omitempty"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type JoinRoomRequest struct {
 	RoomName string `json:"room_name" validate:"required,min=1,max=100"`
 }
 
+// SendMessageRequest names its room by ID or, for named rooms, by name. ParentID makes the
+// message a reply in that message's thread. AttachmentIDs reference the sender's uploads to
+// the room; a message with attachments may have no text.
 type SendMessageRequest struct {
-	Room    string `json:"room" validate:"required"`
-	Content string `json:"content" validate:"required,min=1,max=1000"`
-	Type    string `json:"type,omitempty"`
+	RoomID        int    `json:"room_id,omitempty"`
+	Room          string `json:"room,omitempty"`
+	Content       string `json:"content" validate:"max=1000"`
+	Type          string `json:"type,omitempty"`
+	ParentID      *int   `json:"parent_id,omitempty"`
+	AttachmentIDs []int  `json:"attachment_ids,omitempty"`
 }
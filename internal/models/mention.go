@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxMessageMentions bounds how many distinct users one message can mention
+const MaxMessageMentions = 20
+
+// maxMentionLength matches the longest username
+const maxMentionLength = 50
+
+// MentionKind says who a mention addresses: one user, every member of the room, or the
+// members connected to it at the time
+type MentionKind string
+
+const (
+	MentionUser MentionKind = "user"
+	MentionRoom MentionKind = "room"
+	MentionHere MentionKind = "here"
+)
+
+// Mention is one @mention in a message. UserID and Username are set for MentionUser only.
+// Message is filled in when mentions are listed for the user they address.
+type Mention struct {
+	ID        int         `json:"id" db:"id"`
+	MessageID int         `json:"message_id" db:"message_id"`
+	RoomID    int         `json:"room_id" db:"room_id"`
+	Kind      MentionKind `json:"kind" db:"kind"`
+	UserID    *int        `json:"user_id,omitempty" db:"user_id"`
+	Username  string      `json:"username,omitempty" db:"username"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+
+	Message *Message `json:"message,omitempty" db:"-"`
+}
+
+// ParsedMentions is what a message's text mentions, before it is checked against the room
+type ParsedMentions struct {
+	// Usernames are lower-cased and in order of first appearance
+	Usernames []string
+	Room      bool
+	Here      bool
+}
+
+// Empty reports whether the text mentions nobody
+func (p ParsedMentions) Empty() bool {
+	return len(p.Usernames) == 0 && !p.Room && !p.Here
+}
+
+// ParseMentions finds @username, @room and @here in message text. A mention starts at an @
+// that does not follow a username character, so addresses like a@example.com are not
+// mentions, and runs over username characters. Repeats are dropped.
+func ParseMentions(content string) ParsedMentions {
+	var parsed ParsedMentions
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(content); i++ {
+		if content[i] != '@' || (i > 0 && isUsernameByte(content[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(content) && isUsernameByte(content[end]) {
+			end++
+		}
+		name := strings.ToLower(content[i+1 : end])
+		i = end - 1
+
+		switch {
+		case name == "" || len(name) > maxMentionLength:
+		case name == string(MentionRoom):
+			parsed.Room = true
+		case name == string(MentionHere):
+			parsed.Here = true
+		case !seen[name]:
+			seen[name] = true
+			parsed.Usernames = append(parsed.Usernames, name)
+		}
+	}
+	return parsed
+}
+
+func isUsernameByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
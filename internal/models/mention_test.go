@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := map[string]ParsedMentions{
+		"hi @alice and @Bob":           {Usernames: []string{"alice", "bob"}},
+		"@alice @ALICE @alice!":        {Usernames: []string{"alice"}},
+		"@room heads up":               {Room: true},
+		"(@here) anyone?":              {Here: true},
+		"mail me at dev@example.com":   {},
+		"@ alone and trailing @":       {},
+		"@rooms is a user, not @room.": {Usernames: []string{"rooms"}, Room: true},
+	}
+
+	for content, want := range cases {
+		t.Run(content, func(t *testing.T) {
+			assert.Equal(t, want, ParseMentions(content))
+		})
+	}
+}
+
+func TestParseMentionsIgnoresOverlongNames(t *testing.T) {
+	assert.True(t, ParseMentions("@"+strings.Repeat("a", 51)).Empty())
+	assert.False(t, ParseMentions("@a").Empty())
+}
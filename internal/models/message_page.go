@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MessagePageQuery selects a page of a room's timeline by keyset rather than offset, so pages
+// stay fast deep into history and do not shift as new messages arrive. At most one of Before,
+// After and Around is set; with none, the page ends at the newest message. Around is the ID
+// of a message to center the page on.
+type MessagePageQuery struct {
+	Before string
+	After  string
+	Around int
+	Limit  int
+}
+
+// MessagePage is a run of consecutive timeline messages, oldest first. OlderCursor and
+// NewerCursor continue the timeline in either direction as before and after.
+type MessagePage struct {
+	Messages    []*Message `json:"messages"`
+	OlderCursor string     `json:"older_cursor,omitempty"`
+	NewerCursor string     `json:"newer_cursor,omitempty"`
+	HasOlder    bool       `json:"has_older"`
+	HasNewer    bool       `json:"has_newer"`
+	// AnchorID is the message an around page was centered on: the requested message, or
+	// the start of its thread when it is a reply
+	AnchorID int `json:"anchor_id,omitempty"`
+}
+
+// EncodeMessageCursor makes an opaque cursor for a position in a room's timeline
+func EncodeMessageCursor(roomID int, seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", roomID, seq)))
+}
+
+// DecodeMessageCursor returns the sequence number a cursor points at. Cursors are bound to
+// their room, so one from another room is rejected rather than silently misread.
+func DecodeMessageCursor(roomID int, cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	cursorRoom, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	seq, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	if cursorRoom != roomID {
+		return 0, fmt.Errorf("cursor belongs to another room")
+	}
+
+	return seq, nil
+}
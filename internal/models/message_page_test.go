@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCursorRoundTrip(t *testing.T) {
+	cursor := EncodeMessageCursor(7, 1234)
+
+	seq, err := DecodeMessageCursor(7, cursor)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), seq)
+}
+
+func TestDecodeMessageCursorRejectsInvalidCursors(t *testing.T) {
+	cases := map[string]string{
+		"not base64":   "%%%",
+		"no separator": EncodeMessageCursor(7, 1)[:2],
+		"other room":   EncodeMessageCursor(8, 1),
+		"empty":        "",
+	}
+
+	for name, cursor := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeMessageCursor(7, cursor)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ExcerptLength bounds, in characters, how much of a message a notification quotes
+const ExcerptLength = 200
+
+// NotificationKind is what happened to the user a notification is for
+type NotificationKind string
+
+const (
+	NotificationMention      NotificationKind = "mention"
+	NotificationReply        NotificationKind = "reply"
+	NotificationInvite       NotificationKind = "invite"
+	NotificationJoinApproved NotificationKind = "join_approved"
+	NotificationJoinDenied   NotificationKind = "join_denied"
+	NotificationKicked       NotificationKind = "kicked"
+	NotificationBanned       NotificationKind = "banned"
+	NotificationMuted        NotificationKind = "muted"
+	// NotificationMessageRemoved is a moderator deleting one of the user's messages
+	NotificationMessageRemoved NotificationKind = "message_removed"
+)
+
+// IsRoomActivity reports whether the kind comes from conversation in a room, which muting the
+// room silences. Actions taken on the user themselves are never silenced.
+func (k NotificationKind) IsRoomActivity() bool {
+	return k == NotificationMention || k == NotificationReply
+}
+
+// Notification is something a user should learn about even if they were offline when it
+// happened. RoomName, ActorName and Excerpt are filled in when it is read; the excerpt
+// quotes the message it is about and is empty once that message is deleted.
+type Notification struct {
+	ID        int              `json:"id" db:"id"`
+	UserID    int              `json:"-" db:"user_id"`
+	Kind      NotificationKind `json:"kind" db:"kind"`
+	RoomID    *int             `json:"room_id,omitempty" db:"room_id"`
+	RoomName  string           `json:"room_name,omitempty" db:"-"`
+	MessageID *int             `json:"message_id,omitempty" db:"message_id"`
+	ActorID   *int             `json:"actor_id,omitempty" db:"actor_id"`
+	ActorName string           `json:"actor_name,omitempty" db:"-"`
+	Reason    string           `json:"reason,omitempty" db:"reason"`
+	Excerpt   string           `json:"excerpt,omitempty" db:"-"`
+	ReadAt    *time.Time       `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// NotificationQuery pages through a user's notifications, newest first, continuing below
+// BeforeID when it is set
+type NotificationQuery struct {
+	BeforeID   int
+	UnreadOnly bool
+	Limit      int
+}
+
+// NotificationList is a page of notifications with the user's total unread count, everything
+// a client needs on startup
+type NotificationList struct {
+	Notifications []*Notification `json:"notifications"`
+	UnreadCount   int             `json:"unread_count"`
+}
+
+// NotificationsRead tells a user's other sessions that notifications were read: one, or all
+// of them when NotificationID is zero
+type NotificationsRead struct {
+	NotificationID int `json:"notification_id,omitempty"`
+	UnreadCount    int `json:"unread_count"`
+}
+
+// NotificationMute is a room the user has silenced
+type NotificationMute struct {
+	RoomID    int       `json:"room_id" db:"room_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Excerpt shortens message text for quoting in a notification, on a character boundary and
+// with whitespace runs collapsed
+func Excerpt(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(content) <= ExcerptLength {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[:ExcerptLength-1]) + "…"
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "see you at noon", Excerpt("  see you\n\tat   noon "))
+
+	long := Excerpt(strings.Repeat("é", ExcerptLength+10))
+	assert.Equal(t, ExcerptLength, utf8.RuneCountInString(long))
+	assert.True(t, strings.HasSuffix(long, "…"))
+	assert.True(t, utf8.ValidString(long))
+
+	exact := strings.Repeat("a", ExcerptLength)
+	assert.Equal(t, exact, Excerpt(exact))
+}
+
+func TestNotificationKindIsRoomActivity(t *testing.T) {
+	assert.True(t, NotificationMention.IsRoomActivity())
+	assert.True(t, NotificationReply.IsRoomActivity())
+	assert.False(t, NotificationBanned.IsRoomActivity())
+	assert.False(t, NotificationInvite.IsRoomActivity())
+}
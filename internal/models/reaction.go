@@ -0,0 +1,70 @@
+package models
+
+import (
+	"regexp"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEmojiRunes bounds a unicode reaction. Sequences joined with zero-width joiners, skin
+// tones and flags take several code points but stay well under it.
+const maxEmojiRunes = 16
+
+// keycapRune combines with a digit, # or * into a keycap emoji
+const keycapRune = '\u20e3'
+
+var shortCodePattern = regexp.MustCompile(`^:[a-z0-9_+\-]{1,32}:$`)
+
+// Reaction is one user's emoji on a message. Emoji is either a unicode emoji or a custom
+// short code such as ":shipit:".
+type Reaction struct {
+	ID        int       `json:"id" db:"id"`
+	MessageID int       `json:"message_id" db:"message_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Emoji     string    `json:"emoji" db:"emoji"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ReactionSummary aggregates one emoji on a message for the user reading it
+type ReactionSummary struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// ReactionUpdate describes a reaction being added or removed, with the emoji's new count
+type ReactionUpdate struct {
+	RoomID    int    `json:"room_id"`
+	MessageID int    `json:"message_id"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+}
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// ValidReaction reports whether s is a custom short code or a single unicode emoji. It does
+// not check s against the emoji tables, only that it is made of symbols rather than text.
+func ValidReaction(s string) bool {
+	if shortCodePattern.MatchString(s) {
+		return true
+	}
+	if s == "" || !utf8.ValidString(s) || utf8.RuneCountInString(s) > maxEmojiRunes {
+		return false
+	}
+
+	hasSymbol := false
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r) || r == keycapRune:
+			hasSymbol = true
+		case unicode.IsLetter(r) || unicode.IsSpace(r) || unicode.IsControl(r):
+			return false
+		}
+	}
+	return hasSymbol
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidReaction(t *testing.T) {
+	cases := map[string]bool{
+		"👍":           true,
+		"👍🏽":          true,
+		"👨‍👩‍👧":       true,
+		"🇳🇱":          true,
+		"1️⃣":         true,
+		"❤️":          true,
+		":shipit:":    true,
+		":+1:":        true,
+		"":            false,
+		"ok":          false,
+		"1":           false,
+		"👍 yes":       false,
+		":Shipit:":    false,
+		"::":          false,
+		":has space:": false,
+		"🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉": false,
+	}
+
+	for input, valid := range cases {
+		assert.Equal(t, valid, ValidReaction(input), "%q", input)
+	}
+}
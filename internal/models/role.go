@@ -0,0 +1,100 @@
+package models
+
+// RoomRole is a member's standing in a room. Roles are ordered: each one can do at least
+// everything the roles below it can.
+type RoomRole string
+
+const (
+	RoleOwner     RoomRole = "owner"
+	RoleAdmin     RoomRole = "admin"
+	RoleModerator RoomRole = "moderator"
+	RoleMember    RoomRole = "member"
+	RoleGuest     RoomRole = "guest"
+)
+
+// Permission is an action within a room that depends on the member's role
+type Permission string
+
+const (
+	PermPost           Permission = "post"
+	PermEditOthers     Permission = "edit_others"
+	PermDeleteOthers   Permission = "delete_others"
+	PermInvite         Permission = "invite"
+	PermKick           Permission = "kick"
+	PermBan            Permission = "ban"
+	PermPin            Permission = "pin"
+	PermManageSettings Permission = "manage_settings"
+	PermViewRevisions  Permission = "view_revisions"
+	PermMentionRoom    Permission = "mention_room"
+)
+
+// AllPermissions lists every permission in a stable order
+var AllPermissions = []Permission{
+	PermPost, PermEditOthers, PermDeleteOthers, PermInvite, PermKick, PermBan, PermPin, PermManageSettings,
+	PermViewRevisions, PermMentionRoom,
+}
+
+var roleRanks = map[RoomRole]int{
+	RoleGuest:     1,
+	RoleMember:    2,
+	RoleModerator: 3,
+	RoleAdmin:     4,
+	RoleOwner:     5,
+}
+
+// rolePermissions is the permission matrix; a role not listed for a permission lacks it
+var rolePermissions = map[Permission][]RoomRole{
+	PermPost:           {RoleOwner, RoleAdmin, RoleModerator, RoleMember},
+	PermEditOthers:     {RoleOwner, RoleAdmin},
+	PermDeleteOthers:   {RoleOwner, RoleAdmin, RoleModerator},
+	PermInvite:         {RoleOwner, RoleAdmin, RoleModerator},
+	PermKick:           {RoleOwner, RoleAdmin, RoleModerator},
+	PermBan:            {RoleOwner, RoleAdmin},
+	PermPin:            {RoleOwner, RoleAdmin, RoleModerator},
+	PermManageSettings: {RoleOwner, RoleAdmin},
+	PermViewRevisions:  {RoleOwner, RoleAdmin, RoleModerator},
+	PermMentionRoom:    {RoleOwner, RoleAdmin, RoleModerator},
+}
+
+// IsValid reports whether r is one of the defined roles
+func (r RoomRole) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Outranks reports whether r is strictly above other
+func (r RoomRole) Outranks(other RoomRole) bool {
+	return roleRanks[r] > roleRanks[other]
+}
+
+// Can reports whether the role grants a permission
+func (r RoomRole) Can(p Permission) bool {
+	for _, role := range rolePermissions[p] {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomPermissions describes what a user may do in a room
+type RoomPermissions struct {
+	RoomID      int                 `json:"room_id"`
+	Role        RoomRole            `json:"role"`
+	IsOwner     bool                `json:"is_owner"`
+	Permissions map[Permission]bool `json:"permissions"`
+}
+
+// PermissionsFor expands a role into the full permission set
+func PermissionsFor(roomID int, role RoomRole) *RoomPermissions {
+	permissions := make(map[Permission]bool, len(AllPermissions))
+	for _, p := range AllPermissions {
+		permissions[p] = role.Can(p)
+	}
+	return &RoomPermissions{
+		RoomID:      roomID,
+		Role:        role,
+		IsOwner:     role == RoleOwner,
+		Permissions: permissions,
+	}
+}
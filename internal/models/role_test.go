@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissionMatrix(t *testing.T) {
+	cases := map[RoomRole][]Permission{
+		RoleOwner:     AllPermissions,
+		RoleAdmin:     {PermPost, PermEditOthers, PermDeleteOthers, PermInvite, PermKick, PermBan, PermPin, PermManageSettings, PermViewRevisions, PermMentionRoom},
+		RoleModerator: {PermPost, PermDeleteOthers, PermInvite, PermKick, PermPin, PermViewRevisions, PermMentionRoom},
+		RoleMember:    {PermPost},
+		RoleGuest:     nil,
+	}
+
+	for role, granted := range cases {
+		t.Run(string(role), func(t *testing.T) {
+			var got []Permission
+			for _, p := range AllPermissions {
+				if role.Can(p) {
+					got = append(got, p)
+				}
+			}
+			assert.Equal(t, granted, got)
+		})
+	}
+}
+
+func TestRoleOrdering(t *testing.T) {
+	assert.True(t, RoleOwner.Outranks(RoleAdmin))
+	assert.True(t, RoleModerator.Outranks(RoleGuest))
+	assert.False(t, RoleAdmin.Outranks(RoleAdmin))
+	assert.False(t, RoomRole("superuser").IsValid())
+	assert.False(t, RoomRole("superuser").Can(PermPost))
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MinSearchTermLength matches MySQL's default innodb_ft_min_token_size; shorter words are not
+// in the FULLTEXT index, so every backend ignores them alike
+const MinSearchTermLength = 3
+
+const (
+	snippetRadius  = 60
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+)
+
+// MessageSearchQuery is a user's search. RoomID and Author narrow it; otherwise it covers
+// every room the user belongs to. Since is inclusive and Until exclusive.
+type MessageSearchQuery struct {
+	Query         string
+	RoomID        int
+	Author        string
+	Since         *time.Time
+	Until         *time.Time
+	HasAttachment *bool
+	Cursor        string
+	Limit         int
+}
+
+// SearchCriteria is a search as a backend runs it. Every term must match the start of a word
+// in the message. Results are newest first, continuing below BeforeID when it is set.
+type SearchCriteria struct {
+	Terms         []string
+	RoomIDs       []int
+	UserID        int
+	Since         *time.Time
+	Until         *time.Time
+	HasAttachment *bool
+	BeforeID      int
+	Limit         int
+}
+
+// SearchDocument is what a backend that keeps its own index needs to know about a message
+type SearchDocument struct {
+	MessageID     int
+	RoomID        int
+	UserID        int
+	Content       string
+	HasAttachment bool
+	CreatedAt     time.Time
+}
+
+// SearchHit is a matching message with an HTML-escaped excerpt around the first match, the
+// matched words wrapped in <mark>
+type SearchHit struct {
+	Message *Message `json:"message"`
+	Snippet string   `json:"snippet"`
+}
+
+type SearchResults struct {
+	Hits       []*SearchHit `json:"hits"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// SearchTerms splits text into the lower-cased words a search matches on, in order and
+// without repeats
+func SearchTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range splitWords(text) {
+		term := strings.ToLower(word.text)
+		if len([]rune(term)) < MinSearchTermLength || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// HighlightSnippet cuts an excerpt of content around the first word matching a term and
+// marks every matching word in it. Words match when they start with a term, as in search.
+func HighlightSnippet(content string, terms []string) string {
+	words := splitWords(content)
+	matches := make([]word, 0, len(words))
+	for _, w := range words {
+		lower := strings.ToLower(w.text)
+		for _, term := range terms {
+			if strings.HasPrefix(lower, term) {
+				matches = append(matches, w)
+				break
+			}
+		}
+	}
+
+	runes := []rune(content)
+	start, end := 0, len(runes)
+	if len(matches) > 0 {
+		start = max(0, matches[0].start-snippetRadius)
+		end = min(len(runes), matches[0].end+snippetRadius)
+	} else if end > 2*snippetRadius {
+		end = 2 * snippetRadius
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m.start])))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(string(runes[m.start:m.end])))
+		b.WriteString(highlightClose)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// EncodeSearchCursor makes an opaque cursor continuing a search after messageID
+func EncodeSearchCursor(messageID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("search:" + strconv.Itoa(messageID)))
+}
+
+// DecodeSearchCursor returns the message ID a search cursor continues after
+func DecodeSearchCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), "search:") {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(string(raw), "search:"))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("malformed cursor")
+	}
+	return id, nil
+}
+
+// word is a run of letters and digits, located by rune offsets
+type word struct {
+	text       string
+	start, end int
+}
+
+func splitWords(text string) []word {
+	var words []word
+	runes := []rune(text)
+	start := -1
+	for i, r := range runes {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			words = append(words, word{text: string(runes[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, word{text: string(runes[start:]), start: start, end: len(runes)})
+	}
+	return words
+}
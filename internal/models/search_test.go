@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTermsLowercasesAndDropsShortAndRepeatedWords(t *testing.T) {
+	assert.Equal(t, []string{"when", "the", "exam", "date", "été"}, SearchTerms("When is the EXAM date? exam, été"))
+	assert.Empty(t, SearchTerms("a is ?!"))
+}
+
+func TestHighlightSnippetMarksPrefixMatchesAndEscapes(t *testing.T) {
+	snippet := HighlightSnippet("The <b>exam</b> dates: Examination on Friday", []string{"exam"})
+
+	assert.Equal(t, "The &lt;b&gt;<mark>exam</mark>&lt;/b&gt; dates: <mark>Examination</mark> on Friday", snippet)
+}
+
+func TestHighlightSnippetCutsAroundFirstMatch(t *testing.T) {
+	content := strings.Repeat("lorem ", 30) + "deadline " + strings.Repeat("ipsum ", 30)
+
+	snippet := HighlightSnippet(content, []string{"deadline"})
+
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, "<mark>deadline</mark>")
+	assert.Less(t, len([]rune(snippet)), len([]rune(content)))
+}
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	id, err := DecodeSearchCursor(EncodeSearchCursor(42))
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+
+	_, err = DecodeSearchCursor(EncodeMessageCursor(1, 42))
+	assert.Error(t, err)
+}
@@ -12,6 +12,8 @@ type User struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	IsActive  bool      `json:"is_active" db:"is_active"`
+	// IsAdmin marks a site administrator, who can read every room's audit log
+	IsAdmin bool `json:"is_admin" db:"is_admin"`
 }
 
 type UserSession struct {
@@ -23,6 +25,20 @@ type UserSession struct {
 	IsActive  bool      `json:"is_active" db:"is_active"`
 }
 
+// RefreshToken is one link in a rotation chain. Every token issued for a login shares the
+// FamilyID, so replaying a rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID        int        `json:"id" db:"id"`
+	SessionID string     `json:"session_id" db:"session_id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	FamilyID  string     `json:"family_id" db:"family_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
 type LoginRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Password string `json:"password" validate:"required,min=6"`
@@ -35,8 +51,9 @@ type RegisterRequest struct {
 }
 
 type AuthResponse struct {
-	User         *User  `json:"user"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
+	User             *User  `json:"user"`
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
 }
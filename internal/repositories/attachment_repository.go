@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type attachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+// Create stores an attachment unless it would take the room past roomQuota bytes; a quota of
+// zero is unlimited. The room's row stays locked from the usage check to the insert, so
+// concurrent uploads to one room cannot together overrun it.
+func (r *attachmentRepository) Create(ctx context.Context, attachment *models.Attachment, roomQuota int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if roomQuota > 0 {
+		var roomID int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM rooms WHERE id = ? FOR UPDATE`, attachment.RoomID).Scan(&roomID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewNotFoundError("room not found", err)
+			}
+			return errors.NewDatabaseError("failed to lock room", err)
+		}
+
+		var usage int64
+		err = tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE room_id = ?`, attachment.RoomID).Scan(&usage)
+		if err != nil {
+			return errors.NewDatabaseError("failed to get room storage usage", err)
+		}
+		if usage+attachment.Size > roomQuota {
+			return errors.NewTooLargeError("room has reached its storage quota", nil)
+		}
+	}
+
+	query := `
+		INSERT INTO attachments (room_id, uploader_id, filename, content_type, size, checksum, storage_key, image_status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	attachment.CreatedAt = time.Now()
+	result, err := tx.ExecContext(ctx, query,
+		attachment.RoomID, attachment.UploaderID, attachment.Filename, attachment.ContentType,
+		attachment.Size, attachment.Checksum, attachment.StorageKey, attachment.ImageStatus, attachment.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to create attachment", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get attachment ID", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit attachment", err)
+	}
+	attachment.ID = int(id)
+
+	return nil
+}
+
+func (r *attachmentRepository) GetByID(ctx context.Context, id int) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = ?`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("attachment not found", err)
+		}
+		return nil, errors.NewDatabaseError("failed to get attachment", err)
+	}
+	return attachment, nil
+}
+
+// GetByMessageIDs loads the attachments of a page of messages in a single query
+func (r *attachmentRepository) GetByMessageIDs(ctx context.Context, messageIDs []int) (map[int][]*models.Attachment, error) {
+	attachments := make(map[int][]*models.Attachment, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	args := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		args[i] = id
+	}
+
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE message_id IN (?` + strings.Repeat(", ?", len(messageIDs)-1) + `)
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get attachments", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan attachment", err)
+		}
+		attachments[*attachment.MessageID] = append(attachments[*attachment.MessageID], attachment)
+	}
+
+	return attachments, nil
+}
+
+// UsageByRoom sums the size of every file uploaded to a room, whether or not a message
+// references it yet
+func (r *attachmentRepository) UsageByRoom(ctx context.Context, roomID int) (int64, error) {
+	query := `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE room_id = ?`
+
+	var usage int64
+	if err := r.db.QueryRowContext(ctx, query, roomID).Scan(&usage); err != nil {
+		return 0, errors.NewDatabaseError("failed to get room storage usage", err)
+	}
+	return usage, nil
+}
+
+// SaveImage records the outcome of processing an image: its dimensions, thumbnail and
+// placeholder once ready, or only the status when processing failed
+func (r *attachmentRepository) SaveImage(ctx context.Context, attachment *models.Attachment) error {
+	query := `
+		UPDATE attachments
+		SET image_status = ?, width = ?, height = ?, thumbnail_key = ?, thumbnail_width = ?, thumbnail_height = ?, placeholder = ?
+		WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query,
+		attachment.ImageStatus, attachment.Width, attachment.Height, attachment.ThumbnailKey,
+		attachment.ThumbnailWidth, attachment.ThumbnailHeight, attachment.Placeholder, attachment.ID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to save image details", err)
+	}
+	return nil
+}
+
+// GetPendingImages returns the IDs of images still waiting to be processed, oldest first
+func (r *attachmentRepository) GetPendingImages(ctx context.Context, limit int) ([]int, error) {
+	query := `SELECT id FROM attachments WHERE image_status = ? ORDER BY id LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, models.ImagePending, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get pending images", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.NewDatabaseError("failed to scan attachment ID", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+const attachmentColumns = `id, room_id, uploader_id, message_id, filename, content_type, size, checksum, storage_key, created_at,
+	image_status, width, height, thumbnail_key, thumbnail_width, thumbnail_height, placeholder`
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	var uploaderID, messageID sql.NullInt64
+
+	err := row.Scan(&attachment.ID, &attachment.RoomID, &uploaderID, &messageID, &attachment.Filename,
+		&attachment.ContentType, &attachment.Size, &attachment.Checksum, &attachment.StorageKey, &attachment.CreatedAt,
+		&attachment.ImageStatus, &attachment.Width, &attachment.Height, &attachment.ThumbnailKey,
+		&attachment.ThumbnailWidth, &attachment.ThumbnailHeight, &attachment.Placeholder)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.UploaderID = nullIntPtr(uploaderID)
+	attachment.MessageID = nullIntPtr(messageID)
+	return attachment, nil
+}
+
+// claimAttachments links a new message to its sender's uploads inside the message's
+// transaction. Only unclaimed uploads by the same user to the same room qualify, so a message
+// can neither borrow another user's file nor steal one already sent.
+func claimAttachments(ctx context.Context, tx *sql.Tx, messageID int, message *models.Message) error {
+	ids := message.AttachmentIDs
+	args := make([]interface{}, 0, len(ids)+3)
+	args = append(args, messageID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, message.RoomID, message.UserID)
+
+	query := `
+		UPDATE attachments SET message_id = ?
+		WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)
+		AND room_id = ? AND uploader_id = ? AND message_id IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.NewDatabaseError("failed to attach files", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if int(rowsAffected) != len(ids) {
+		return errors.NewConflictError("attachments must be your own unsent uploads to this room", nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE messages SET attachment_count = ? WHERE id = ?`, len(ids), messageID); err != nil {
+		return errors.NewDatabaseError("failed to count attachments", err)
+	}
+	return nil
+}
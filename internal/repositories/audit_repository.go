@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (room_id, actor_id, actor_username, action, target_user_id, target_username,
+			target_message_id, reason, before_state, after_state, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	entry.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx, query, entry.RoomID, entry.ActorID, entry.ActorUsername, entry.Action,
+		entry.TargetUserID, entry.TargetUsername, entry.TargetMessageID, entry.Reason,
+		nullJSON(entry.Before), nullJSON(entry.After), entry.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to write audit entry", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get audit entry ID", err)
+	}
+	entry.ID = id
+
+	return nil
+}
+
+// List returns matching entries, newest first
+func (r *auditRepository) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.RoomID != 0 {
+		conditions = append(conditions, "room_id = ?")
+		args = append(args, filter.RoomID)
+	}
+	if filter.ActorID != 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.TargetUserID != 0 {
+		conditions = append(conditions, "target_user_id = ?")
+		args = append(args, filter.TargetUserID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.Until)
+	}
+
+	query := `
+		SELECT id, room_id, actor_id, actor_username, action, target_user_id, target_username,
+			target_message_id, reason, before_state, after_state, created_at
+		FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to list audit entries", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		var roomID, actorID, targetUserID, targetMessageID sql.NullInt64
+		var before, after []byte
+
+		err := rows.Scan(&entry.ID, &roomID, &actorID, &entry.ActorUsername, &entry.Action, &targetUserID,
+			&entry.TargetUsername, &targetMessageID, &entry.Reason, &before, &after, &entry.CreatedAt)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan audit entry", err)
+		}
+
+		entry.RoomID = nullIntPtr(roomID)
+		entry.ActorID = nullIntPtr(actorID)
+		entry.TargetUserID = nullIntPtr(targetUserID)
+		entry.TargetMessageID = nullIntPtr(targetMessageID)
+		if len(before) > 0 {
+			entry.Before = before
+		}
+		if len(after) > 0 {
+			entry.After = after
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// nullJSON stores an empty snapshot as NULL rather than an invalid JSON document
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	id := int(value.Int64)
+	return &id
+}
+
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := value.Time
+	return &t
+}
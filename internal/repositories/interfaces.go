@@ -10,6 +10,7 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id int) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetAll(ctx context.Context, limit, offset int) ([]*models.User, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id int) error
 	Exists(ctx context.Context, username, email string) (bool, error)
@@ -17,20 +18,29 @@ type UserRepository interface {
 
 type SessionRepository interface {
 	Create(ctx context.Context, session *models.UserSession) error
+	GetByID(ctx context.Context, id string) (*models.UserSession, error)
 	GetByToken(ctx context.Context, token string) (*models.UserSession, error)
 	GetByUserID(ctx context.Context, userID int) ([]*models.UserSession, error)
 	Update(ctx context.Context, session *models.UserSession) error
 	Delete(ctx context.Context, token string) error
+	DeleteByID(ctx context.Context, id string) error
 	DeleteByUserID(ctx context.Context, userID int) error
 	CleanupExpired(ctx context.Context) error
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, currentID int, next *models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
 }
 
 type RoomRepository interface {
 	Create(ctx context.Context, room *models.Room) error
+	CreateConversation(ctx context.Context, room *models.Room, userIDs []int) error
 	GetByID(ctx context.Context, id int) (*models.Room, error)
 	GetByName(ctx context.Context, name string) (*models.Room, error)
 	GetAll(ctx context.Context, limit, offset int) ([]*models.Room, error)
 	GetByUserID(ctx context.Context, userID int) ([]*models.Room, error)
+	GetByParticipantKey(ctx context.Context, key string) (*models.Room, error)
+	GetConversationsByUserID(ctx context.Context, userID int) ([]*models.Room, error)
 	Update(ctx context.Context, room *models.Room) error
 	Delete(ctx context.Context, id int) error
 	Exists(ctx context.Context, name string) (bool, error)
@@ -42,16 +52,107 @@ type MessageRepository interface {
 	GetByRoomID(ctx context.Context, roomID int, limit, offset int) ([]*models.Message, error)
 	GetByRoomName(ctx context.Context, roomName string, limit, offset int) ([]*models.Message, error)
 	GetRecent(ctx context.Context, roomID int, limit int) ([]*models.Message, error)
-	Update(ctx context.Context, message *models.Message) error
-	Delete(ctx context.Context, id int) error
+	GetAfterSeq(ctx context.Context, roomID int, afterSeq int64, limit int) ([]*models.Message, error)
+	GetTimelineBefore(ctx context.Context, roomID int, beforeSeq int64, limit int) ([]*models.Message, error)
+	GetTimelineAfter(ctx context.Context, roomID int, afterSeq int64, limit int) ([]*models.Message, error)
+	GetReplies(ctx context.Context, parentID int, limit, offset int) ([]*models.Message, error)
+	Edit(ctx context.Context, message *models.Message, editedBy int) error
+	Delete(ctx context.Context, message *models.Message, deletedBy int) error
+	GetRevisions(ctx context.Context, messageID int) ([]*models.MessageRevision, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*models.Message, error)
 	CountByRoomID(ctx context.Context, roomID int) (int64, error)
 }
 
+// SearchRepository is a full-text search backend over messages. Backends that search the
+// messages table directly treat Index and Remove as no-ops.
+type SearchRepository interface {
+	Index(ctx context.Context, doc *models.SearchDocument) error
+	Remove(ctx context.Context, messageID int) error
+	// Search returns the IDs of matching messages, newest first
+	Search(ctx context.Context, criteria models.SearchCriteria) ([]int, error)
+}
+
+type ReactionRepository interface {
+	Add(ctx context.Context, reaction *models.Reaction) error
+	Remove(ctx context.Context, messageID, userID int, emoji string) error
+	Count(ctx context.Context, messageID int, emoji string) (int, error)
+	GetSummaries(ctx context.Context, messageIDs []int, userID int) (map[int][]models.ReactionSummary, error)
+}
+
+type MentionRepository interface {
+	GetByMessageIDs(ctx context.Context, messageIDs []int) (map[int][]*models.Mention, error)
+	GetForUser(ctx context.Context, userID, beforeID, limit int) ([]*models.Mention, error)
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	CreateForMembers(ctx context.Context, notification *models.Notification, exceptUserIDs []int) (int64, error)
+	GetByID(ctx context.Context, id int) (*models.Notification, error)
+	GetByMessage(ctx context.Context, messageID int, kind models.NotificationKind) ([]*models.Notification, error)
+	List(ctx context.Context, userID int, query models.NotificationQuery) ([]*models.Notification, error)
+	CountUnread(ctx context.Context, userID int) (int, error)
+	MarkRead(ctx context.Context, userID, id int) error
+	MarkAllRead(ctx context.Context, userID int) (int64, error)
+	Mute(ctx context.Context, userID, roomID int) error
+	Unmute(ctx context.Context, userID, roomID int) error
+	IsMuted(ctx context.Context, userID, roomID int) (bool, error)
+	GetMutes(ctx context.Context, userID int) ([]*models.NotificationMute, error)
+}
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment, roomQuota int64) error
+	GetByID(ctx context.Context, id int) (*models.Attachment, error)
+	GetByMessageIDs(ctx context.Context, messageIDs []int) (map[int][]*models.Attachment, error)
+	UsageByRoom(ctx context.Context, roomID int) (int64, error)
+	SaveImage(ctx context.Context, attachment *models.Attachment) error
+	GetPendingImages(ctx context.Context, limit int) ([]int, error)
+}
+
+type InviteRepository interface {
+	Create(ctx context.Context, invite *models.RoomInvite) error
+	GetByID(ctx context.Context, id int) (*models.RoomInvite, error)
+	GetByCode(ctx context.Context, code string) (*models.RoomInvite, error)
+	GetByRoomID(ctx context.Context, roomID int) ([]*models.RoomInvite, error)
+	Revoke(ctx context.Context, id int) error
+	Redeem(ctx context.Context, id int, member *models.RoomMember) error
+}
+
+type JoinRequestRepository interface {
+	Upsert(ctx context.Context, request *models.JoinRequest) error
+	GetByID(ctx context.Context, id int) (*models.JoinRequest, error)
+	GetPendingByRoomID(ctx context.Context, roomID int) ([]*models.JoinRequest, error)
+	Decide(ctx context.Context, id int, status models.JoinRequestStatus, decidedBy int) error
+	Approve(ctx context.Context, id, decidedBy int, member *models.RoomMember) error
+}
+
+// AuditRepository is append-only: entries are never updated or deleted
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error)
+}
+
+type SanctionRepository interface {
+	Create(ctx context.Context, sanction *models.RoomSanction) error
+	GetByID(ctx context.Context, id int) (*models.RoomSanction, error)
+	GetActive(ctx context.Context, roomID, userID int, kind models.SanctionKind) (*models.RoomSanction, error)
+	ListActive(ctx context.Context, roomID int, kind models.SanctionKind) ([]*models.RoomSanction, error)
+	Lift(ctx context.Context, id, liftedBy int) error
+}
+
 type RoomMemberRepository interface {
 	AddMember(ctx context.Context, member *models.RoomMember) error
 	RemoveMember(ctx context.Context, roomID, userID int) error
 	GetMembers(ctx context.Context, roomID int) ([]*models.RoomMember, error)
+	GetMember(ctx context.Context, roomID, userID int) (*models.RoomMember, error)
+	GetMembersByUsernames(ctx context.Context, roomID int, usernames []string) ([]*models.RoomMember, error)
+	UpdateRole(ctx context.Context, roomID, userID int, role models.RoomRole) error
+	TransferOwnership(ctx context.Context, roomID, fromUserID, toUserID int) error
+	GetMemberUsers(ctx context.Context, roomID int) ([]*models.User, error)
 	GetRoomsByUserID(ctx context.Context, userID int) ([]*models.Room, error)
+	GetOwnerlessRoomIDs(ctx context.Context) ([]int, error)
 	IsMember(ctx context.Context, roomID, userID int) (bool, error)
 	GetMemberCount(ctx context.Context, roomID int) (int64, error)
+	GetReadState(ctx context.Context, roomID, userID int) (*models.RoomReadState, error)
+	GetReadStates(ctx context.Context, userID int) ([]*models.RoomReadState, error)
+	MarkRead(ctx context.Context, roomID, userID int, seq int64) error
 }
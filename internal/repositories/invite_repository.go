@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type inviteRepository struct {
+	db *sql.DB
+}
+
+func NewInviteRepository(db *sql.DB) InviteRepository {
+	return &inviteRepository{db: db}
+}
+
+const inviteColumns = `id, room_id, code, created_by, role, max_uses, uses, expires_at, revoked_at, created_at`
+
+func (r *inviteRepository) Create(ctx context.Context, invite *models.RoomInvite) error {
+	query := `
+		INSERT INTO room_invites (room_id, code, created_by, role, max_uses, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	invite.CreatedAt = time.Now()
+
+	result, err := r.db.ExecContext(ctx, query,
+		invite.RoomID, invite.Code, invite.CreatedBy, invite.Role, invite.MaxUses, invite.ExpiresAt, invite.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to create invite", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get invite ID", err)
+	}
+
+	invite.ID = int(id)
+	return nil
+}
+
+func (r *inviteRepository) GetByID(ctx context.Context, id int) (*models.RoomInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM room_invites WHERE id = ?`
+	return r.getOne(ctx, query, id)
+}
+
+func (r *inviteRepository) GetByCode(ctx context.Context, code string) (*models.RoomInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM room_invites WHERE code = ?`
+	return r.getOne(ctx, query, code)
+}
+
+func (r *inviteRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.RoomInvite, error) {
+	invite, err := scanInvite(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("invite not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get invite", err)
+	}
+
+	return invite, nil
+}
+
+func (r *inviteRepository) GetByRoomID(ctx context.Context, roomID int) ([]*models.RoomInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM room_invites WHERE room_id = ? ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get room invites", err)
+	}
+	defer rows.Close()
+
+	var invites []*models.RoomInvite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan invite", err)
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, nil
+}
+
+func (r *inviteRepository) Revoke(ctx context.Context, id int) error {
+	query := `UPDATE room_invites SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return errors.NewDatabaseError("failed to revoke invite", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("invite not found or already revoked", nil)
+	}
+
+	return nil
+}
+
+// Redeem takes one use of an invite and adds the member in the same transaction, so a use is
+// only spent on a join that happened. The limits are checked in the same statement as the
+// use so two people redeeming the last use at once cannot both succeed.
+func (r *inviteRepository) Redeem(ctx context.Context, id int, member *models.RoomMember) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE room_invites
+		SET uses = uses + 1
+		WHERE id = ? AND revoked_at IS NULL
+			AND (max_uses IS NULL OR uses < max_uses)
+			AND (expires_at IS NULL OR expires_at > ?)`
+
+	result, err := tx.ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return errors.NewDatabaseError("failed to consume invite", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewConflictError("invite is no longer valid", nil)
+	}
+
+	if err := insertMember(ctx, tx, member); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit invite redemption", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvite(row rowScanner) (*models.RoomInvite, error) {
+	invite := &models.RoomInvite{}
+	var createdBy, maxUses sql.NullInt64
+	var expiresAt, revokedAt sql.NullTime
+
+	err := row.Scan(&invite.ID, &invite.RoomID, &invite.Code, &createdBy, &invite.Role,
+		&maxUses, &invite.Uses, &expiresAt, &revokedAt, &invite.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		invite.CreatedBy = &id
+	}
+	if maxUses.Valid {
+		n := int(maxUses.Int64)
+		invite.MaxUses = &n
+	}
+	if expiresAt.Valid {
+		invite.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		invite.RevokedAt = &revokedAt.Time
+	}
+
+	return invite, nil
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type joinRequestRepository struct {
+	db *sql.DB
+}
+
+func NewJoinRequestRepository(db *sql.DB) JoinRequestRepository {
+	return &joinRequestRepository{db: db}
+}
+
+const joinRequestSelect = `
+	SELECT jr.id, jr.room_id, jr.user_id, u.username, jr.message, jr.status, jr.decided_by, jr.decided_at, jr.created_at
+	FROM room_join_requests jr
+	INNER JOIN users u ON jr.user_id = u.id`
+
+// Upsert files a pending request. A request that was already decided is reopened with the new
+// message; one that is still pending is left alone and reported as a conflict.
+func (r *joinRequestRepository) Upsert(ctx context.Context, request *models.JoinRequest) error {
+	query := `
+		INSERT INTO room_join_requests (room_id, user_id, message, status, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			id = LAST_INSERT_ID(id),
+			message = IF(status = 'pending', message, VALUES(message)),
+			decided_by = IF(status = 'pending', decided_by, NULL),
+			decided_at = IF(status = 'pending', decided_at, NULL),
+			created_at = IF(status = 'pending', created_at, VALUES(created_at)),
+			status = VALUES(status)`
+
+	request.Status = models.JoinRequestPending
+	request.CreatedAt = time.Now()
+
+	result, err := r.db.ExecContext(ctx, query, request.RoomID, request.UserID, request.Message, request.Status, request.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to create join request", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get join request ID", err)
+	}
+	request.ID = int(id)
+
+	// Zero rows affected means the pending request matched and nothing changed
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewConflictError("a join request is already pending", nil)
+	}
+
+	return nil
+}
+
+func (r *joinRequestRepository) GetByID(ctx context.Context, id int) (*models.JoinRequest, error) {
+	query := joinRequestSelect + ` WHERE jr.id = ?`
+
+	request, err := scanJoinRequest(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("join request not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get join request", err)
+	}
+
+	return request, nil
+}
+
+func (r *joinRequestRepository) GetPendingByRoomID(ctx context.Context, roomID int) ([]*models.JoinRequest, error) {
+	query := joinRequestSelect + ` WHERE jr.room_id = ? AND jr.status = ? ORDER BY jr.created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID, models.JoinRequestPending)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get join requests", err)
+	}
+	defer rows.Close()
+
+	var requests []*models.JoinRequest
+	for rows.Next() {
+		request, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan join request", err)
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// Decide settles a pending request. Deciding one that is no longer pending is a conflict, so
+// two admins acting at once cannot both approve or contradict each other.
+func (r *joinRequestRepository) Decide(ctx context.Context, id int, status models.JoinRequestStatus, decidedBy int) error {
+	return decideJoinRequest(ctx, r.db, id, status, decidedBy)
+}
+
+// Approve settles a pending request as approved and adds the requester in the same
+// transaction, so an approved request always comes with its membership.
+func (r *joinRequestRepository) Approve(ctx context.Context, id, decidedBy int, member *models.RoomMember) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := decideJoinRequest(ctx, tx, id, models.JoinRequestApproved, decidedBy); err != nil {
+		return err
+	}
+	if err := insertMember(ctx, tx, member); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit join request approval", err)
+	}
+
+	return nil
+}
+
+func decideJoinRequest(ctx context.Context, db execer, id int, status models.JoinRequestStatus, decidedBy int) error {
+	query := `
+		UPDATE room_join_requests
+		SET status = ?, decided_by = ?, decided_at = ?
+		WHERE id = ? AND status = ?`
+
+	result, err := db.ExecContext(ctx, query, status, decidedBy, time.Now(), id, models.JoinRequestPending)
+	if err != nil {
+		return errors.NewDatabaseError("failed to decide join request", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewConflictError("join request has already been decided", nil)
+	}
+
+	return nil
+}
+
+func scanJoinRequest(row rowScanner) (*models.JoinRequest, error) {
+	request := &models.JoinRequest{}
+	var decidedBy sql.NullInt64
+	var decidedAt sql.NullTime
+
+	err := row.Scan(&request.ID, &request.RoomID, &request.UserID, &request.Username, &request.Message,
+		&request.Status, &decidedBy, &decidedAt, &request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if decidedBy.Valid {
+		id := int(decidedBy.Int64)
+		request.DecidedBy = &id
+	}
+	if decidedAt.Valid {
+		request.DecidedAt = &decidedAt.Time
+	}
+
+	return request, nil
+}
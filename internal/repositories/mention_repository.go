@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type mentionRepository struct {
+	db *sql.DB
+}
+
+func NewMentionRepository(db *sql.DB) MentionRepository {
+	return &mentionRepository{db: db}
+}
+
+// mentionColumns is selected from mentions aliased as mn, left joined to the mentioned user
+// as u, and read back with scanMention
+const mentionColumns = `mn.id, mn.message_id, mn.room_id, mn.kind, mn.user_id, COALESCE(u.username, ''), mn.created_at`
+
+// GetByMessageIDs loads the mentions of a page of messages in a single query
+func (r *mentionRepository) GetByMessageIDs(ctx context.Context, messageIDs []int) (map[int][]*models.Mention, error) {
+	mentions := make(map[int][]*models.Mention, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return mentions, nil
+	}
+
+	args := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		args[i] = id
+	}
+
+	query := `
+		SELECT ` + mentionColumns + `
+		FROM mentions mn
+		LEFT JOIN users u ON u.id = mn.user_id
+		WHERE mn.message_id IN (?` + strings.Repeat(", ?", len(messageIDs)-1) + `)
+		ORDER BY mn.id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get mentions", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		mention, err := scanMention(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan mention", err)
+		}
+		mentions[mention.MessageID] = append(mentions[mention.MessageID], mention)
+	}
+
+	return mentions, nil
+}
+
+// GetForUser lists the mentions addressed to a user, newest first and continuing below
+// beforeID when it is set: their own, and @room mentions made since they joined. Only rooms
+// they still belong to count, and deleted messages and their own are left out. Messages are
+// not loaded.
+func (r *mentionRepository) GetForUser(ctx context.Context, userID, beforeID, limit int) ([]*models.Mention, error) {
+	query := `
+		SELECT ` + mentionColumns + `
+		FROM mentions mn
+		INNER JOIN room_members rm ON rm.room_id = mn.room_id AND rm.user_id = ? AND rm.is_active = true
+		INNER JOIN messages m ON m.id = mn.message_id
+		LEFT JOIN users u ON u.id = mn.user_id
+		WHERE (mn.user_id = ? OR (mn.kind = ? AND mn.created_at >= rm.joined_at))
+		AND m.user_id <> ? AND m.deleted_at IS NULL`
+	args := []interface{}{userID, userID, models.MentionRoom, userID}
+
+	if beforeID != 0 {
+		query += ` AND mn.id < ?`
+		args = append(args, beforeID)
+	}
+	query += ` ORDER BY mn.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get mentions", err)
+	}
+	defer rows.Close()
+
+	var mentions []*models.Mention
+	for rows.Next() {
+		mention, err := scanMention(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan mention", err)
+		}
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, nil
+}
+
+// createMentions records a new message's mentions in the transaction that stores it
+func createMentions(ctx context.Context, tx *sql.Tx, messageID int, message *models.Message) error {
+	for _, mention := range message.Mentions {
+		mention.MessageID = messageID
+		mention.RoomID = message.RoomID
+		mention.CreatedAt = message.CreatedAt
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO mentions (message_id, room_id, kind, user_id, created_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			mention.MessageID, mention.RoomID, mention.Kind, mention.UserID, mention.CreatedAt)
+		if err != nil {
+			return errors.NewDatabaseError("failed to create mention", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return errors.NewDatabaseError("failed to get mention ID", err)
+		}
+		mention.ID = int(id)
+	}
+	return nil
+}
+
+func scanMention(row rowScanner) (*models.Mention, error) {
+	mention := &models.Mention{}
+	var userID sql.NullInt64
+
+	err := row.Scan(&mention.ID, &mention.MessageID, &mention.RoomID, &mention.Kind, &userID, &mention.Username, &mention.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	mention.UserID = nullIntPtr(userID)
+	return mention, nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"chat_app/internal/models"
@@ -17,17 +18,43 @@ func NewMessageRepository(db *sql.DB) MessageRepository {
 	return &messageRepository{db: db}
 }
 
+// Create stores a message under the room's next sequence number. Bumping rooms.last_seq
+// row-locks the room, so concurrent writers to one room are numbered without gaps.
 func (r *messageRepository) Create(ctx context.Context, message *models.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE rooms SET last_seq = LAST_INSERT_ID(last_seq + 1) WHERE id = ?`, message.RoomID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to allocate message sequence", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("room not found", nil)
+	}
+	seq, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get message sequence", err)
+	}
+
 	query := `
-		INSERT INTO messages (room_id, user_id, username, content, type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
+		INSERT INTO messages (room_id, seq, user_id, username, content, type, parent_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	now := time.Now()
+	message.Seq = seq
 	message.CreatedAt = now
 	message.UpdatedAt = now
 
-	result, err := r.db.ExecContext(ctx, query,
-		message.RoomID, message.UserID, message.Username, message.Content, message.Type, message.CreatedAt, message.UpdatedAt)
+	result, err = tx.ExecContext(ctx, query,
+		message.RoomID, message.Seq, message.UserID, message.Username, message.Content, message.Type, message.ParentID,
+		message.CreatedAt, message.UpdatedAt)
 
 	if err != nil {
 		return errors.NewDatabaseError("failed to create message", err)
@@ -38,19 +65,36 @@ func (r *messageRepository) Create(ctx context.Context, message *models.Message)
 		return errors.NewDatabaseError("failed to get message ID", err)
 	}
 
+	if len(message.AttachmentIDs) > 0 {
+		if err := claimAttachments(ctx, tx, int(id), message); err != nil {
+			return err
+		}
+	}
+
+	if err := createMentions(ctx, tx, int(id), message); err != nil {
+		return err
+	}
+
+	if message.ParentID != nil {
+		if err := refreshThread(ctx, tx, *message.ParentID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit message", err)
+	}
+
 	message.ID = int(id)
 	return nil
 }
 
 func (r *messageRepository) GetByID(ctx context.Context, id int) (*models.Message, error) {
 	query := `
-		SELECT id, room_id, user_id, username, content, type, created_at, updated_at
-		FROM messages WHERE id = ?`
+		SELECT ` + messageColumns + `
+		FROM messages m WHERE m.id = ?`
 
-	message := &models.Message{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&message.ID, &message.RoomID, &message.UserID, &message.Username,
-		&message.Content, &message.Type, &message.CreatedAt, &message.UpdatedAt)
+	message, err := scanMessage(r.db.QueryRowContext(ctx, query, id))
 
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("message not found", err)
@@ -64,10 +108,10 @@ func (r *messageRepository) GetByID(ctx context.Context, id int) (*models.Messag
 
 func (r *messageRepository) GetByRoomID(ctx context.Context, roomID int, limit, offset int) ([]*models.Message, error) {
 	query := `
-		SELECT id, room_id, user_id, username, content, type, created_at, updated_at
-		FROM messages
-		WHERE room_id = ?
-		ORDER BY created_at DESC
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = ? AND m.parent_id IS NULL
+		ORDER BY m.seq DESC
 		LIMIT ? OFFSET ?`
 
 	rows, err := r.db.QueryContext(ctx, query, roomID, limit, offset)
@@ -76,15 +120,9 @@ func (r *messageRepository) GetByRoomID(ctx context.Context, roomID int, limit,
 	}
 	defer rows.Close()
 
-	var messages []*models.Message
-	for rows.Next() {
-		message := &models.Message{}
-		err := rows.Scan(&message.ID, &message.RoomID, &message.UserID, &message.Username,
-			&message.Content, &message.Type, &message.CreatedAt, &message.UpdatedAt)
-		if err != nil {
-			return nil, errors.NewDatabaseError("failed to scan message", err)
-		}
-		messages = append(messages, message)
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Reverse to show oldest first
@@ -97,11 +135,11 @@ func (r *messageRepository) GetByRoomID(ctx context.Context, roomID int, limit,
 
 func (r *messageRepository) GetByRoomName(ctx context.Context, roomName string, limit, offset int) ([]*models.Message, error) {
 	query := `
-		SELECT m.id, m.room_id, m.user_id, m.username, m.content, m.type, m.created_at, m.updated_at
+		SELECT ` + messageColumns + `
 		FROM messages m
 		INNER JOIN rooms r ON m.room_id = r.id
-		WHERE r.name = ? AND r.is_active = true
-		ORDER BY m.created_at DESC
+		WHERE r.name = ? AND r.is_active = true AND m.parent_id IS NULL
+		ORDER BY m.seq DESC
 		LIMIT ? OFFSET ?`
 
 	rows, err := r.db.QueryContext(ctx, query, roomName, limit, offset)
@@ -110,15 +148,9 @@ func (r *messageRepository) GetByRoomName(ctx context.Context, roomName string,
 	}
 	defer rows.Close()
 
-	var messages []*models.Message
-	for rows.Next() {
-		message := &models.Message{}
-		err := rows.Scan(&message.ID, &message.RoomID, &message.UserID, &message.Username,
-			&message.Content, &message.Type, &message.CreatedAt, &message.UpdatedAt)
-		if err != nil {
-			return nil, errors.NewDatabaseError("failed to scan message", err)
-		}
-		messages = append(messages, message)
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Reverse to show oldest first
@@ -131,10 +163,10 @@ func (r *messageRepository) GetByRoomName(ctx context.Context, roomName string,
 
 func (r *messageRepository) GetRecent(ctx context.Context, roomID int, limit int) ([]*models.Message, error) {
 	query := `
-		SELECT id, room_id, user_id, username, content, type, created_at, updated_at
-		FROM messages
-		WHERE room_id = ?
-		ORDER BY created_at DESC
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = ? AND m.parent_id IS NULL
+		ORDER BY m.seq DESC
 		LIMIT ?`
 
 	rows, err := r.db.QueryContext(ctx, query, roomID, limit)
@@ -143,15 +175,39 @@ func (r *messageRepository) GetRecent(ctx context.Context, roomID int, limit int
 	}
 	defer rows.Close()
 
-	var messages []*models.Message
-	for rows.Next() {
-		message := &models.Message{}
-		err := rows.Scan(&message.ID, &message.RoomID, &message.UserID, &message.Username,
-			&message.Content, &message.Type, &message.CreatedAt, &message.UpdatedAt)
-		if err != nil {
-			return nil, errors.NewDatabaseError("failed to scan message", err)
-		}
-		messages = append(messages, message)
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverse to show oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// GetTimelineBefore returns up to limit top-level messages of a room with a sequence number
+// below beforeSeq, the newest of them, in sequence order. It walks the (room_id, seq) index,
+// so it costs the same however deep into history it starts.
+func (r *messageRepository) GetTimelineBefore(ctx context.Context, roomID int, beforeSeq int64, limit int) ([]*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = ? AND m.seq < ? AND m.parent_id IS NULL
+		ORDER BY m.seq DESC
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID, beforeSeq, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get messages before sequence", err)
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Reverse to show oldest first
@@ -162,44 +218,254 @@ func (r *messageRepository) GetRecent(ctx context.Context, roomID int, limit int
 	return messages, nil
 }
 
-func (r *messageRepository) Update(ctx context.Context, message *models.Message) error {
+// GetTimelineAfter returns up to limit top-level messages of a room with a sequence number
+// above afterSeq, in sequence order
+func (r *messageRepository) GetTimelineAfter(ctx context.Context, roomID int, afterSeq int64, limit int) ([]*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = ? AND m.seq > ? AND m.parent_id IS NULL
+		ORDER BY m.seq ASC
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID, afterSeq, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get messages after sequence", err)
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetAfterSeq returns up to limit messages of a room with a sequence number above afterSeq,
+// in sequence order. Thread replies are included, since they take sequence numbers too.
+func (r *messageRepository) GetAfterSeq(ctx context.Context, roomID int, afterSeq int64, limit int) ([]*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.room_id = ? AND m.seq > ?
+		ORDER BY m.seq ASC
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID, afterSeq, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get messages after sequence", err)
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// Edit replaces a message's content, keeping the previous text as a revision
+func (r *messageRepository) Edit(ctx context.Context, message *models.Message, editedBy int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	previous, _, err := lockForRevision(ctx, tx, message.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := addRevision(ctx, tx, message.ID, models.RevisionEdit, previous, editedBy, now); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE messages
-		SET content = ?, updated_at = ?
+		SET content = ?, edited_at = ?, updated_at = ?
 		WHERE id = ?`
 
-	message.UpdatedAt = time.Now()
+	if _, err := tx.ExecContext(ctx, query, message.Content, now, now, message.ID); err != nil {
+		return errors.NewDatabaseError("failed to update message", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit message edit", err)
+	}
 
-	result, err := r.db.ExecContext(ctx, query, message.Content, message.UpdatedAt, message.ID)
+	message.EditedAt = &now
+	message.UpdatedAt = now
+	return nil
+}
+
+// Delete turns a message into a tombstone: its content moves into a final revision and the
+// row stays, so sequence numbers, threads and replays keep their shape. Deleting a reply
+// updates its thread's summary.
+func (r *messageRepository) Delete(ctx context.Context, message *models.Message, deletedBy int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return errors.NewDatabaseError("failed to update message", err)
+		return errors.NewDatabaseError("failed to begin transaction", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	id := message.ID
+	previous, parentID, err := lockForRevision(ctx, tx, id)
 	if err != nil {
-		return errors.NewDatabaseError("failed to get rows affected", err)
+		return err
 	}
-	if rowsAffected == 0 {
-		return errors.NewNotFoundError("message not found", nil)
+
+	now := time.Now()
+	if err := addRevision(ctx, tx, id, models.RevisionDelete, previous, deletedBy, now); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE messages
+		SET content = '', deleted_at = ?, deleted_by = ?, updated_at = ?
+		WHERE id = ?`
+
+	if _, err := tx.ExecContext(ctx, query, now, deletedBy, now, id); err != nil {
+		return errors.NewDatabaseError("failed to delete message", err)
+	}
+
+	if parentID.Valid {
+		if err := refreshThread(ctx, tx, int(parentID.Int64)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit message deletion", err)
 	}
 
+	message.Content = ""
+	message.DeletedAt = &now
+	message.DeletedBy = &deletedBy
+	message.UpdatedAt = now
 	return nil
 }
 
-func (r *messageRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM messages WHERE id = ?`
+// GetRevisions returns a message's earlier contents, oldest first
+func (r *messageRepository) GetRevisions(ctx context.Context, messageID int) ([]*models.MessageRevision, error) {
+	query := `
+		SELECT mr.id, mr.message_id, mr.action, mr.content, mr.edited_by, COALESCE(u.username, ''), mr.created_at
+		FROM message_revisions mr
+		LEFT JOIN users u ON mr.edited_by = u.id
+		WHERE mr.message_id = ?
+		ORDER BY mr.id ASC`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	rows, err := r.db.QueryContext(ctx, query, messageID)
 	if err != nil {
-		return errors.NewDatabaseError("failed to delete message", err)
+		return nil, errors.NewDatabaseError("failed to get message revisions", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
+	var revisions []*models.MessageRevision
+	for rows.Next() {
+		revision := &models.MessageRevision{}
+		var editedBy sql.NullInt64
+		err := rows.Scan(&revision.ID, &revision.MessageID, &revision.Action, &revision.Content,
+			&editedBy, &revision.EditedByUsername, &revision.CreatedAt)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan message revision", err)
+		}
+		revision.EditedBy = nullIntPtr(editedBy)
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// lockForRevision locks a live message and returns its current content and thread. A
+// tombstone cannot be changed any further.
+func lockForRevision(ctx context.Context, tx *sql.Tx, id int) (string, sql.NullInt64, error) {
+	var content string
+	var parentID sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := tx.QueryRowContext(ctx, `SELECT content, parent_id, deleted_at FROM messages WHERE id = ? FOR UPDATE`, id).
+		Scan(&content, &parentID, &deletedAt)
+	if err == sql.ErrNoRows {
+		return "", parentID, errors.NewNotFoundError("message not found", err)
+	}
 	if err != nil {
-		return errors.NewDatabaseError("failed to get rows affected", err)
+		return "", parentID, errors.NewDatabaseError("failed to get message", err)
 	}
-	if rowsAffected == 0 {
-		return errors.NewNotFoundError("message not found", nil)
+	if deletedAt.Valid {
+		return "", parentID, errors.NewConflictError("message has been deleted", nil)
+	}
+
+	return content, parentID, nil
+}
+
+func addRevision(ctx context.Context, tx *sql.Tx, messageID int, action models.RevisionAction, content string, editedBy int, at time.Time) error {
+	query := `INSERT INTO message_revisions (message_id, action, content, edited_by, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	if _, err := tx.ExecContext(ctx, query, messageID, action, content, editedBy, at); err != nil {
+		return errors.NewDatabaseError("failed to store message revision", err)
+	}
+	return nil
+}
+
+// GetByIDs loads a batch of messages in one query, in no particular order. IDs that do not
+// exist are skipped.
+func (r *messageRepository) GetByIDs(ctx context.Context, ids []int) ([]*models.Message, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get messages by ID", err)
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetReplies returns a page of a thread's replies, oldest first
+func (r *messageRepository) GetReplies(ctx context.Context, parentID int, limit, offset int) ([]*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.parent_id = ?
+		ORDER BY m.seq ASC
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, parentID, limit, offset)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get thread replies", err)
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// refreshThread recomputes a thread's reply count and last reply time on its parent from
+// the live replies themselves, so they cannot drift however replies come and go
+func refreshThread(ctx context.Context, tx *sql.Tx, parentID int) error {
+	var count int
+	var lastReplyAt sql.NullTime
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*), MAX(created_at) FROM messages WHERE parent_id = ? AND deleted_at IS NULL`, parentID).
+		Scan(&count, &lastReplyAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to summarize thread", err)
+	}
+
+	// updated_at is carried over so a new reply does not look like a change to the parent
+	_, err = tx.ExecContext(ctx, `UPDATE messages SET reply_count = ?, last_reply_at = ?, updated_at = updated_at WHERE id = ?`,
+		count, lastReplyAt, parentID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to update thread", err)
 	}
 
 	return nil
@@ -216,3 +482,40 @@ func (r *messageRepository) CountByRoomID(ctx context.Context, roomID int) (int6
 
 	return count, nil
 }
+
+// messageColumns is selected from messages aliased as m and read back with scanMessage
+const messageColumns = `m.id, m.room_id, m.seq, m.user_id, m.username, m.content, m.type, m.parent_id, m.reply_count, m.last_reply_at,
+	m.edited_at, m.deleted_at, m.deleted_by, m.created_at, m.updated_at, m.attachment_count`
+
+func scanMessage(row rowScanner) (*models.Message, error) {
+	message := &models.Message{}
+	var parentID, deletedBy sql.NullInt64
+	var lastReplyAt, editedAt, deletedAt sql.NullTime
+
+	err := row.Scan(&message.ID, &message.RoomID, &message.Seq, &message.UserID, &message.Username,
+		&message.Content, &message.Type, &parentID, &message.ReplyCount, &lastReplyAt,
+		&editedAt, &deletedAt, &deletedBy, &message.CreatedAt, &message.UpdatedAt, &message.AttachmentCount)
+	if err != nil {
+		return nil, err
+	}
+
+	message.ParentID = nullIntPtr(parentID)
+	message.DeletedBy = nullIntPtr(deletedBy)
+	message.LastReplyAt = nullTimePtr(lastReplyAt)
+	message.EditedAt = nullTimePtr(editedAt)
+	message.DeletedAt = nullTimePtr(deletedAt)
+	return message, nil
+}
+
+func scanMessages(rows *sql.Rows) ([]*models.Message, error) {
+	var messages []*models.Message
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan message", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type notificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// notificationColumns is selected from notifications aliased as n and read back with
+// scanNotification. The joins fill in names and the excerpt of a message that still exists;
+// its placeholder takes the excerpt length plus one, so scanning can tell it was cut short.
+const notificationColumns = `n.id, n.user_id, n.kind, n.room_id, COALESCE(r.name, ''), n.message_id, n.actor_id,
+	COALESCE(u.username, ''), n.reason, CASE WHEN m.deleted_at IS NULL THEN COALESCE(SUBSTRING(m.content, 1, ?), '') ELSE '' END,
+	n.read_at, n.created_at`
+
+const notificationJoins = `
+		FROM notifications n
+		LEFT JOIN rooms r ON r.id = n.room_id
+		LEFT JOIN users u ON u.id = n.actor_id
+		LEFT JOIN messages m ON m.id = n.message_id`
+
+func (r *notificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, kind, room_id, message_id, actor_id, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	notification.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		notification.UserID, notification.Kind, notification.RoomID, notification.MessageID,
+		notification.ActorID, notification.Reason, notification.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to create notification", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get notification ID", err)
+	}
+	notification.ID = int(id)
+
+	return nil
+}
+
+// CreateForMembers records a copy of the notification for every active member of its room
+// who has not muted it, except the users listed. It returns the number of copies made.
+func (r *notificationRepository) CreateForMembers(ctx context.Context, notification *models.Notification, exceptUserIDs []int) (int64, error) {
+	notification.CreatedAt = time.Now()
+	args := []interface{}{notification.Kind, notification.RoomID, notification.MessageID, notification.ActorID,
+		notification.Reason, notification.CreatedAt, notification.RoomID}
+
+	query := `
+		INSERT INTO notifications (user_id, kind, room_id, message_id, actor_id, reason, created_at)
+		SELECT rm.user_id, ?, ?, ?, ?, ?, ?
+		FROM room_members rm
+		LEFT JOIN notification_mutes nm ON nm.user_id = rm.user_id AND nm.room_id = rm.room_id
+		WHERE rm.room_id = ? AND rm.is_active = true AND nm.user_id IS NULL`
+	if len(exceptUserIDs) > 0 {
+		query += ` AND rm.user_id NOT IN (?` + strings.Repeat(", ?", len(exceptUserIDs)-1) + `)`
+		for _, id := range exceptUserIDs {
+			args = append(args, id)
+		}
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.NewDatabaseError("failed to create notifications", err)
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	return created, nil
+}
+
+func (r *notificationRepository) GetByID(ctx context.Context, id int) (*models.Notification, error) {
+	query := `SELECT ` + notificationColumns + notificationJoins + ` WHERE n.id = ?`
+
+	notification, err := scanNotification(r.db.QueryRowContext(ctx, query, models.ExcerptLength+1, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("notification not found", err)
+		}
+		return nil, errors.NewDatabaseError("failed to get notification", err)
+	}
+	return notification, nil
+}
+
+// GetByMessage loads the copies of a notification CreateForMembers made for a message
+func (r *notificationRepository) GetByMessage(ctx context.Context, messageID int, kind models.NotificationKind) ([]*models.Notification, error) {
+	query := `SELECT ` + notificationColumns + notificationJoins + ` WHERE n.message_id = ? AND n.kind = ? ORDER BY n.id`
+
+	rows, err := r.db.QueryContext(ctx, query, models.ExcerptLength+1, messageID, kind)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get notifications", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// List returns a page of the user's notifications, newest first
+func (r *notificationRepository) List(ctx context.Context, userID int, query models.NotificationQuery) ([]*models.Notification, error) {
+	conditions := []string{"n.user_id = ?"}
+	args := []interface{}{models.ExcerptLength + 1, userID}
+
+	if query.UnreadOnly {
+		conditions = append(conditions, "n.read_at IS NULL")
+	}
+	if query.BeforeID != 0 {
+		conditions = append(conditions, "n.id < ?")
+		args = append(args, query.BeforeID)
+	}
+
+	sqlQuery := `SELECT ` + notificationColumns + notificationJoins + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY n.id DESC LIMIT ?`
+	args = append(args, query.Limit)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get notifications", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, errors.NewDatabaseError("failed to count unread notifications", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one of the user's notifications read. Reading it again keeps the first time.
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id int) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to mark notification read", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	// MySQL counts changed rows, so an already-read notification needs a second look
+	if rowsAffected == 0 {
+		var exists bool
+		err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)`, id, userID).Scan(&exists)
+		if err != nil {
+			return errors.NewDatabaseError("failed to get notification", err)
+		}
+		if !exists {
+			return errors.NewNotFoundError("notification not found", nil)
+		}
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read and returns how many there were
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	query := `UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		return 0, errors.NewDatabaseError("failed to mark notifications read", err)
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	return marked, nil
+}
+
+func (r *notificationRepository) Mute(ctx context.Context, userID, roomID int) error {
+	query := `INSERT IGNORE INTO notification_mutes (user_id, room_id, created_at) VALUES (?, ?, ?)`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, roomID, time.Now()); err != nil {
+		return errors.NewDatabaseError("failed to mute room", err)
+	}
+	return nil
+}
+
+func (r *notificationRepository) Unmute(ctx context.Context, userID, roomID int) error {
+	query := `DELETE FROM notification_mutes WHERE user_id = ? AND room_id = ?`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, roomID); err != nil {
+		return errors.NewDatabaseError("failed to unmute room", err)
+	}
+	return nil
+}
+
+func (r *notificationRepository) IsMuted(ctx context.Context, userID, roomID int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM notification_mutes WHERE user_id = ? AND room_id = ?)`
+
+	var muted bool
+	if err := r.db.QueryRowContext(ctx, query, userID, roomID).Scan(&muted); err != nil {
+		return false, errors.NewDatabaseError("failed to get room mute", err)
+	}
+	return muted, nil
+}
+
+func (r *notificationRepository) GetMutes(ctx context.Context, userID int) ([]*models.NotificationMute, error) {
+	query := `SELECT room_id, created_at FROM notification_mutes WHERE user_id = ? ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get room mutes", err)
+	}
+	defer rows.Close()
+
+	mutes := []*models.NotificationMute{}
+	for rows.Next() {
+		mute := &models.NotificationMute{}
+		if err := rows.Scan(&mute.RoomID, &mute.CreatedAt); err != nil {
+			return nil, errors.NewDatabaseError("failed to scan room mute", err)
+		}
+		mutes = append(mutes, mute)
+	}
+	return mutes, nil
+}
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	notification := &models.Notification{}
+	var roomID, messageID, actorID sql.NullInt64
+	var readAt sql.NullTime
+
+	err := row.Scan(&notification.ID, &notification.UserID, &notification.Kind, &roomID, &notification.RoomName,
+		&messageID, &actorID, &notification.ActorName, &notification.Reason, &notification.Excerpt,
+		&readAt, &notification.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	notification.RoomID = nullIntPtr(roomID)
+	notification.MessageID = nullIntPtr(messageID)
+	notification.ActorID = nullIntPtr(actorID)
+	notification.ReadAt = nullTimePtr(readAt)
+	notification.Excerpt = models.Excerpt(notification.Excerpt)
+	return notification, nil
+}
+
+func scanNotifications(rows *sql.Rows) ([]*models.Notification, error) {
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan notification", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type reactionRepository struct {
+	db *sql.DB
+}
+
+func NewReactionRepository(db *sql.DB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+// Add stores a reaction. A user reacting twice with the same emoji is a conflict, so callers
+// only announce reactions that actually changed something.
+func (r *reactionRepository) Add(ctx context.Context, reaction *models.Reaction) error {
+	query := `INSERT IGNORE INTO message_reactions (message_id, user_id, emoji, created_at) VALUES (?, ?, ?, ?)`
+
+	reaction.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji, reaction.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to add reaction", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewConflictError("reaction already added", nil)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get reaction ID", err)
+	}
+	reaction.ID = int(id)
+
+	return nil
+}
+
+func (r *reactionRepository) Remove(ctx context.Context, messageID, userID int, emoji string) error {
+	query := `DELETE FROM message_reactions WHERE message_id = ? AND user_id = ? AND emoji = ?`
+
+	result, err := r.db.ExecContext(ctx, query, messageID, userID, emoji)
+	if err != nil {
+		return errors.NewDatabaseError("failed to remove reaction", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("reaction not found", nil)
+	}
+
+	return nil
+}
+
+func (r *reactionRepository) Count(ctx context.Context, messageID int, emoji string) (int, error) {
+	query := `SELECT COUNT(*) FROM message_reactions WHERE message_id = ? AND emoji = ?`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, messageID, emoji).Scan(&count); err != nil {
+		return 0, errors.NewDatabaseError("failed to count reactions", err)
+	}
+
+	return count, nil
+}
+
+// GetSummaries aggregates the reactions on a batch of messages in one query, as seen by
+// userID. Emoji are listed in the order they were first used on each message.
+func (r *reactionRepository) GetSummaries(ctx context.Context, messageIDs []int, userID int) (map[int][]models.ReactionSummary, error) {
+	summaries := make(map[int][]models.ReactionSummary, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return summaries, nil
+	}
+
+	args := make([]interface{}, 0, len(messageIDs)+1)
+	args = append(args, userID)
+	for _, id := range messageIDs {
+		args = append(args, id)
+	}
+
+	query := `
+		SELECT message_id, emoji, COUNT(*), MAX(user_id = ?)
+		FROM message_reactions
+		WHERE message_id IN (?` + strings.Repeat(", ?", len(messageIDs)-1) + `)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(id)`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get reactions", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID int
+		var summary models.ReactionSummary
+		if err := rows.Scan(&messageID, &summary.Emoji, &summary.Count, &summary.ReactedByMe); err != nil {
+			return nil, errors.NewDatabaseError("failed to scan reaction", err)
+		}
+		summaries[messageID] = append(summaries[messageID], summary)
+	}
+
+	return summaries, nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"chat_app/internal/models"
@@ -18,15 +19,25 @@ func NewRoomMemberRepository(db *sql.DB) RoomMemberRepository {
 }
 
 func (r *roomMemberRepository) AddMember(ctx context.Context, member *models.RoomMember) error {
+	return insertMember(ctx, r.db, member)
+}
+
+func insertMember(ctx context.Context, db execer, member *models.RoomMember) error {
+	// Members who left keep their row, so rejoining reactivates it instead of violating unique_room_user.
+	// A rejoining member starts over with the role given now, not the one they left with.
 	query := `
-		INSERT INTO room_members (room_id, user_id, joined_at, is_active)
-		VALUES (?, ?, ?, ?)`
+		INSERT INTO room_members (room_id, user_id, role, joined_at, is_active)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role), joined_at = VALUES(joined_at), is_active = VALUES(is_active)`
 
 	now := time.Now()
 	member.JoinedAt = now
 	member.IsActive = true
+	if member.Role == "" {
+		member.Role = models.RoleMember
+	}
 
-	_, err := r.db.ExecContext(ctx, query, member.RoomID, member.UserID, member.JoinedAt, member.IsActive)
+	_, err := db.ExecContext(ctx, query, member.RoomID, member.UserID, member.Role, member.JoinedAt, member.IsActive)
 	if err != nil {
 		return errors.NewDatabaseError("failed to add room member", err)
 	}
@@ -55,10 +66,11 @@ func (r *roomMemberRepository) RemoveMember(ctx context.Context, roomID, userID
 
 func (r *roomMemberRepository) GetMembers(ctx context.Context, roomID int) ([]*models.RoomMember, error) {
 	query := `
-		SELECT id, room_id, user_id, joined_at, is_active
-		FROM room_members
-		WHERE room_id = ? AND is_active = true
-		ORDER BY joined_at ASC`
+		SELECT rm.id, rm.room_id, rm.user_id, u.username, rm.role, rm.joined_at, rm.is_active
+		FROM room_members rm
+		INNER JOIN users u ON rm.user_id = u.id
+		WHERE rm.room_id = ? AND rm.is_active = true
+		ORDER BY rm.joined_at ASC`
 
 	rows, err := r.db.QueryContext(ctx, query, roomID)
 	if err != nil {
@@ -69,7 +81,67 @@ func (r *roomMemberRepository) GetMembers(ctx context.Context, roomID int) ([]*m
 	var members []*models.RoomMember
 	for rows.Next() {
 		member := &models.RoomMember{}
-		err := rows.Scan(&member.ID, &member.RoomID, &member.UserID, &member.JoinedAt, &member.IsActive)
+		err := rows.Scan(&member.ID, &member.RoomID, &member.UserID, &member.Username, &member.Role, &member.JoinedAt, &member.IsActive)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan room member", err)
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+func (r *roomMemberRepository) GetMember(ctx context.Context, roomID, userID int) (*models.RoomMember, error) {
+	query := `
+		SELECT rm.id, rm.room_id, rm.user_id, u.username, rm.role, rm.joined_at, rm.is_active
+		FROM room_members rm
+		INNER JOIN users u ON rm.user_id = u.id
+		WHERE rm.room_id = ? AND rm.user_id = ? AND rm.is_active = true`
+
+	member := &models.RoomMember{}
+	err := r.db.QueryRowContext(ctx, query, roomID, userID).Scan(
+		&member.ID, &member.RoomID, &member.UserID, &member.Username, &member.Role, &member.JoinedAt, &member.IsActive)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("room member not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get room member", err)
+	}
+
+	return member, nil
+}
+
+// GetMembersByUsernames looks up which of the named users are active members of a room.
+// Names that match no member are left out of the result.
+func (r *roomMemberRepository) GetMembersByUsernames(ctx context.Context, roomID int, usernames []string) ([]*models.RoomMember, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(usernames)+1)
+	args = append(args, roomID)
+	for _, username := range usernames {
+		args = append(args, username)
+	}
+
+	query := `
+		SELECT rm.id, rm.room_id, rm.user_id, u.username, rm.role, rm.joined_at, rm.is_active
+		FROM room_members rm
+		INNER JOIN users u ON rm.user_id = u.id
+		WHERE rm.room_id = ? AND rm.is_active = true
+		AND u.username IN (?` + strings.Repeat(", ?", len(usernames)-1) + `)`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get room members", err)
+	}
+	defer rows.Close()
+
+	var members []*models.RoomMember
+	for rows.Next() {
+		member := &models.RoomMember{}
+		err := rows.Scan(&member.ID, &member.RoomID, &member.UserID, &member.Username, &member.Role, &member.JoinedAt, &member.IsActive)
 		if err != nil {
 			return nil, errors.NewDatabaseError("failed to scan room member", err)
 		}
@@ -79,9 +151,133 @@ func (r *roomMemberRepository) GetMembers(ctx context.Context, roomID int) ([]*m
 	return members, nil
 }
 
+func (r *roomMemberRepository) UpdateRole(ctx context.Context, roomID, userID int, role models.RoomRole) error {
+	query := `UPDATE room_members SET role = ? WHERE room_id = ? AND user_id = ? AND is_active = true`
+
+	result, err := r.db.ExecContext(ctx, query, role, roomID, userID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to update member role", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("room member not found", nil)
+	}
+
+	return nil
+}
+
+// TransferOwnership makes toUserID the owner and demotes fromUserID to admin in one
+// transaction, so a room never has two owners or none
+func (r *roomMemberRepository) TransferOwnership(ctx context.Context, roomID, fromUserID, toUserID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE room_members SET role = ? WHERE room_id = ? AND user_id = ? AND role = ? AND is_active = true`
+
+	result, err := tx.ExecContext(ctx, query, models.RoleAdmin, roomID, fromUserID, models.RoleOwner)
+	if err != nil {
+		return errors.NewDatabaseError("failed to demote previous owner", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewConflictError("user is no longer the room owner", nil)
+	}
+
+	query = `UPDATE room_members SET role = ? WHERE room_id = ? AND user_id = ? AND is_active = true`
+
+	result, err = tx.ExecContext(ctx, query, models.RoleOwner, roomID, toUserID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to promote new owner", err)
+	}
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("room member not found", nil)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit ownership transfer", err)
+	}
+
+	return nil
+}
+
+func (r *roomMemberRepository) GetMemberUsers(ctx context.Context, roomID int) ([]*models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.created_at, u.updated_at, u.is_active
+		FROM users u
+		INNER JOIN room_members rm ON u.id = rm.user_id
+		WHERE rm.room_id = ? AND rm.is_active = true AND u.is_active = true
+		ORDER BY rm.joined_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get room member users", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.IsActive)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan user", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// GetRoomsByUserID lists every room and conversation a user belongs to
+// GetOwnerlessRoomIDs lists active rooms that have members but no owner, which happens when
+// an owner's account row is deleted outright and their membership goes with it
+func (r *roomMemberRepository) GetOwnerlessRoomIDs(ctx context.Context) ([]int, error) {
+	query := `
+		SELECT r.id
+		FROM rooms r
+		WHERE r.kind = ? AND r.is_active = true
+			AND EXISTS (
+				SELECT 1 FROM room_members rm
+				WHERE rm.room_id = r.id AND rm.is_active = true AND rm.role <> ?)
+			AND NOT EXISTS (
+				SELECT 1 FROM room_members rm
+				WHERE rm.room_id = r.id AND rm.is_active = true AND rm.role = ?)
+		ORDER BY r.id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, models.RoomKindRoom, models.RoleGuest, models.RoleOwner)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get ownerless rooms", err)
+	}
+	defer rows.Close()
+
+	var roomIDs []int
+	for rows.Next() {
+		var roomID int
+		if err := rows.Scan(&roomID); err != nil {
+			return nil, errors.NewDatabaseError("failed to scan room ID", err)
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+
+	return roomIDs, nil
+}
+
 func (r *roomMemberRepository) GetRoomsByUserID(ctx context.Context, userID int) ([]*models.Room, error) {
 	query := `
-		SELECT r.id, r.name, r.description, r.is_private, r.created_by, r.created_at, r.updated_at, r.is_active
+		SELECT ` + roomColumns + `
 		FROM rooms r
 		INNER JOIN room_members rm ON r.id = rm.room_id
 		WHERE rm.user_id = ? AND r.is_active = true AND rm.is_active = true
@@ -93,18 +289,7 @@ func (r *roomMemberRepository) GetRoomsByUserID(ctx context.Context, userID int)
 	}
 	defer rows.Close()
 
-	var rooms []*models.Room
-	for rows.Next() {
-		room := &models.Room{}
-		err := rows.Scan(&room.ID, &room.Name, &room.Description, &room.IsPrivate,
-			&room.CreatedBy, &room.CreatedAt, &room.UpdatedAt, &room.IsActive)
-		if err != nil {
-			return nil, errors.NewDatabaseError("failed to scan room", err)
-		}
-		rooms = append(rooms, room)
-	}
-
-	return rooms, nil
+	return scanRooms(rows)
 }
 
 func (r *roomMemberRepository) IsMember(ctx context.Context, roomID, userID int) (bool, error) {
@@ -132,3 +317,65 @@ func (r *roomMemberRepository) GetMemberCount(ctx context.Context, roomID int) (
 
 	return count, nil
 }
+
+const readStateQuery = `
+	SELECT r.id, COALESCE(r.name, ''), r.last_seq, LEAST(rm.last_read_seq, r.last_seq)
+	FROM room_members rm
+	INNER JOIN rooms r ON rm.room_id = r.id
+	WHERE rm.is_active = true AND r.is_active = true`
+
+func (r *roomMemberRepository) GetReadState(ctx context.Context, roomID, userID int) (*models.RoomReadState, error) {
+	query := readStateQuery + ` AND rm.room_id = ? AND rm.user_id = ?`
+
+	state := &models.RoomReadState{}
+	err := r.db.QueryRowContext(ctx, query, roomID, userID).Scan(
+		&state.RoomID, &state.RoomName, &state.LastSeq, &state.LastReadSeq)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("room member not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get read state", err)
+	}
+
+	state.Unread = state.LastSeq - state.LastReadSeq
+	return state, nil
+}
+
+func (r *roomMemberRepository) GetReadStates(ctx context.Context, userID int) ([]*models.RoomReadState, error) {
+	query := readStateQuery + ` AND rm.user_id = ? ORDER BY r.name`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get read states", err)
+	}
+	defer rows.Close()
+
+	var states []*models.RoomReadState
+	for rows.Next() {
+		state := &models.RoomReadState{}
+		if err := rows.Scan(&state.RoomID, &state.RoomName, &state.LastSeq, &state.LastReadSeq); err != nil {
+			return nil, errors.NewDatabaseError("failed to scan read state", err)
+		}
+		state.Unread = state.LastSeq - state.LastReadSeq
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// MarkRead moves a member's read marker forward; it never moves back, so reads reported out
+// of order by several devices settle on the newest one
+func (r *roomMemberRepository) MarkRead(ctx context.Context, roomID, userID int, seq int64) error {
+	query := `
+		UPDATE room_members
+		SET last_read_seq = GREATEST(last_read_seq, ?)
+		WHERE room_id = ? AND user_id = ? AND is_active = true`
+
+	_, err := r.db.ExecContext(ctx, query, seq, roomID, userID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to mark room read", err)
+	}
+
+	return nil
+}
@@ -17,18 +17,55 @@ func NewRoomRepository(db *sql.DB) RoomRepository {
 	return &roomRepository{db: db}
 }
 
+// roomColumns is selected from rooms aliased as r and read back with scanRoom
+const roomColumns = `r.id, r.name, r.description, r.is_private, r.kind, r.created_by, r.created_at, r.updated_at, r.is_active`
+
 func (r *roomRepository) Create(ctx context.Context, room *models.Room) error {
+	return insertRoom(ctx, r.db, room)
+}
+
+// CreateConversation stores a conversation together with its participants as plain members,
+// so a failure leaves neither behind
+func (r *roomRepository) CreateConversation(ctx context.Context, room *models.Room, userIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertRoom(ctx, tx, room); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := insertMember(ctx, tx, &models.RoomMember{RoomID: room.ID, UserID: userID}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit conversation", err)
+	}
+	return nil
+}
+
+func insertRoom(ctx context.Context, db execer, room *models.Room) error {
 	query := `
-		INSERT INTO rooms (name, description, is_private, created_by, created_at, updated_at, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
+		INSERT INTO rooms (name, description, is_private, kind, participant_key, created_by, created_at, updated_at, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	now := time.Now()
 	room.CreatedAt = now
 	room.UpdatedAt = now
 	room.IsActive = true
+	if room.Kind == "" {
+		room.Kind = models.RoomKindRoom
+	}
 
-	result, err := r.db.ExecContext(ctx, query,
-		room.Name, room.Description, room.IsPrivate, room.CreatedBy, room.CreatedAt, room.UpdatedAt, room.IsActive)
+	// Conversations have no name and rooms no participant key; both are stored as NULL so
+	// the unique indexes ignore them
+	result, err := db.ExecContext(ctx, query,
+		nullString(room.Name), room.Description, room.IsPrivate, room.Kind, nullString(room.ParticipantKey),
+		room.CreatedBy, room.CreatedAt, room.UpdatedAt, room.IsActive)
 
 	if err != nil {
 		return errors.NewDatabaseError("failed to create room", err)
@@ -44,15 +81,9 @@ func (r *roomRepository) Create(ctx context.Context, room *models.Room) error {
 }
 
 func (r *roomRepository) GetByID(ctx context.Context, id int) (*models.Room, error) {
-	query := `
-		SELECT id, name, description, is_private, created_by, created_at, updated_at, is_active
-		FROM rooms WHERE id = ? AND is_active = true`
-
-	room := &models.Room{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&room.ID, &room.Name, &room.Description, &room.IsPrivate,
-		&room.CreatedBy, &room.CreatedAt, &room.UpdatedAt, &room.IsActive)
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.id = ? AND r.is_active = true`
 
+	room, err := scanRoom(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("room not found", err)
 	}
@@ -64,15 +95,9 @@ func (r *roomRepository) GetByID(ctx context.Context, id int) (*models.Room, err
 }
 
 func (r *roomRepository) GetByName(ctx context.Context, name string) (*models.Room, error) {
-	query := `
-		SELECT id, name, description, is_private, created_by, created_at, updated_at, is_active
-		FROM rooms WHERE name = ? AND is_active = true`
-
-	room := &models.Room{}
-	err := r.db.QueryRowContext(ctx, query, name).Scan(
-		&room.ID, &room.Name, &room.Description, &room.IsPrivate,
-		&room.CreatedBy, &room.CreatedAt, &room.UpdatedAt, &room.IsActive)
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.name = ? AND r.is_active = true`
 
+	room, err := scanRoom(r.db.QueryRowContext(ctx, query, name))
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("room not found", err)
 	}
@@ -83,12 +108,28 @@ func (r *roomRepository) GetByName(ctx context.Context, name string) (*models.Ro
 	return room, nil
 }
 
+// GetByParticipantKey finds the conversation between exactly the users the key names
+func (r *roomRepository) GetByParticipantKey(ctx context.Context, key string) (*models.Room, error) {
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.participant_key = ? AND r.is_active = true`
+
+	room, err := scanRoom(r.db.QueryRowContext(ctx, query, key))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("conversation not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get conversation", err)
+	}
+
+	return room, nil
+}
+
+// GetAll lists named rooms; conversations are never listed publicly
 func (r *roomRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Room, error) {
 	query := `
-		SELECT id, name, description, is_private, created_by, created_at, updated_at, is_active
-		FROM rooms
-		WHERE is_active = true
-		ORDER BY created_at DESC
+		SELECT ` + roomColumns + `
+		FROM rooms r
+		WHERE r.is_active = true AND r.kind = 'room'
+		ORDER BY r.created_at DESC
 		LIMIT ? OFFSET ?`
 
 	rows, err := r.db.QueryContext(ctx, query, limit, offset)
@@ -97,26 +138,25 @@ func (r *roomRepository) GetAll(ctx context.Context, limit, offset int) ([]*mode
 	}
 	defer rows.Close()
 
-	var rooms []*models.Room
-	for rows.Next() {
-		room := &models.Room{}
-		err := rows.Scan(&room.ID, &room.Name, &room.Description, &room.IsPrivate,
-			&room.CreatedBy, &room.CreatedAt, &room.UpdatedAt, &room.IsActive)
-		if err != nil {
-			return nil, errors.NewDatabaseError("failed to scan room", err)
-		}
-		rooms = append(rooms, room)
-	}
-
-	return rooms, nil
+	return scanRooms(rows)
 }
 
+// GetByUserID lists the named rooms a user belongs to
 func (r *roomRepository) GetByUserID(ctx context.Context, userID int) ([]*models.Room, error) {
+	return r.getByUserID(ctx, userID, `r.kind = 'room'`)
+}
+
+// GetConversationsByUserID lists the direct and group conversations a user takes part in
+func (r *roomRepository) GetConversationsByUserID(ctx context.Context, userID int) ([]*models.Room, error) {
+	return r.getByUserID(ctx, userID, `r.kind <> 'room'`)
+}
+
+func (r *roomRepository) getByUserID(ctx context.Context, userID int, kindCondition string) ([]*models.Room, error) {
 	query := `
-		SELECT r.id, r.name, r.description, r.is_private, r.created_by, r.created_at, r.updated_at, r.is_active
+		SELECT ` + roomColumns + `
 		FROM rooms r
 		INNER JOIN room_members rm ON r.id = rm.room_id
-		WHERE rm.user_id = ? AND r.is_active = true AND rm.is_active = true
+		WHERE rm.user_id = ? AND r.is_active = true AND rm.is_active = true AND ` + kindCondition + `
 		ORDER BY r.created_at DESC`
 
 	rows, err := r.db.QueryContext(ctx, query, userID)
@@ -125,18 +165,7 @@ func (r *roomRepository) GetByUserID(ctx context.Context, userID int) ([]*models
 	}
 	defer rows.Close()
 
-	var rooms []*models.Room
-	for rows.Next() {
-		room := &models.Room{}
-		err := rows.Scan(&room.ID, &room.Name, &room.Description, &room.IsPrivate,
-			&room.CreatedBy, &room.CreatedAt, &room.UpdatedAt, &room.IsActive)
-		if err != nil {
-			return nil, errors.NewDatabaseError("failed to scan room", err)
-		}
-		rooms = append(rooms, room)
-	}
-
-	return rooms, nil
+	return scanRooms(rows)
 }
 
 func (r *roomRepository) Update(ctx context.Context, room *models.Room) error {
@@ -148,7 +177,7 @@ func (r *roomRepository) Update(ctx context.Context, room *models.Room) error {
 	room.UpdatedAt = time.Now()
 
 	result, err := r.db.ExecContext(ctx, query,
-		room.Name, room.Description, room.IsPrivate, room.UpdatedAt, room.IsActive, room.ID)
+		nullString(room.Name), room.Description, room.IsPrivate, room.UpdatedAt, room.IsActive, room.ID)
 
 	if err != nil {
 		return errors.NewDatabaseError("failed to update room", err)
@@ -195,3 +224,38 @@ func (r *roomRepository) Exists(ctx context.Context, name string) (bool, error)
 
 	return count > 0, nil
 }
+
+func scanRoom(row rowScanner) (*models.Room, error) {
+	room := &models.Room{}
+	var name, description sql.NullString
+
+	err := row.Scan(&room.ID, &name, &description, &room.IsPrivate, &room.Kind,
+		&room.CreatedBy, &room.CreatedAt, &room.UpdatedAt, &room.IsActive)
+	if err != nil {
+		return nil, err
+	}
+
+	room.Name = name.String
+	room.Description = description.String
+	return room, nil
+}
+
+func scanRooms(rows *sql.Rows) ([]*models.Room, error) {
+	var rooms []*models.Room
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan room", err)
+		}
+		rooms = append(rooms, room)
+	}
+
+	return rooms, nil
+}
+
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+type sanctionRepository struct {
+	db *sql.DB
+}
+
+func NewSanctionRepository(db *sql.DB) SanctionRepository {
+	return &sanctionRepository{db: db}
+}
+
+const sanctionSelect = `
+	SELECT s.id, s.room_id, s.user_id, u.username, s.kind, s.reason, s.created_by, s.expires_at, s.lifted_at, s.lifted_by, s.created_at
+	FROM room_sanctions s
+	INNER JOIN users u ON s.user_id = u.id`
+
+// sanctionActive matches sanctions that are neither lifted nor expired. Expiry needs no
+// background job: an expired sanction simply stops matching.
+const sanctionActive = ` AND s.lifted_at IS NULL AND (s.expires_at IS NULL OR s.expires_at > ?)`
+
+// Create records a sanction, lifting any active one of the same kind it replaces so a user
+// has at most one active ban and one active mute per room
+func (r *sanctionRepository) Create(ctx context.Context, sanction *models.RoomSanction) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	sanction.CreatedAt = now
+
+	query := `
+		UPDATE room_sanctions SET lifted_at = ?, lifted_by = ?
+		WHERE room_id = ? AND user_id = ? AND kind = ? AND lifted_at IS NULL`
+
+	_, err = tx.ExecContext(ctx, query, now, sanction.CreatedBy, sanction.RoomID, sanction.UserID, sanction.Kind)
+	if err != nil {
+		return errors.NewDatabaseError("failed to replace previous sanction", err)
+	}
+
+	query = `
+		INSERT INTO room_sanctions (room_id, user_id, kind, reason, created_by, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := tx.ExecContext(ctx, query, sanction.RoomID, sanction.UserID, sanction.Kind, sanction.Reason,
+		sanction.CreatedBy, sanction.ExpiresAt, sanction.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to create sanction", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get sanction ID", err)
+	}
+	sanction.ID = int(id)
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit sanction", err)
+	}
+
+	return nil
+}
+
+func (r *sanctionRepository) GetByID(ctx context.Context, id int) (*models.RoomSanction, error) {
+	query := sanctionSelect + ` WHERE s.id = ?`
+
+	sanction, err := scanSanction(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("sanction not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get sanction", err)
+	}
+
+	return sanction, nil
+}
+
+func (r *sanctionRepository) GetActive(ctx context.Context, roomID, userID int, kind models.SanctionKind) (*models.RoomSanction, error) {
+	query := sanctionSelect + ` WHERE s.room_id = ? AND s.user_id = ? AND s.kind = ?` + sanctionActive + `
+		ORDER BY s.created_at DESC LIMIT 1`
+
+	sanction, err := scanSanction(r.db.QueryRowContext(ctx, query, roomID, userID, kind, time.Now()))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("no active sanction", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get active sanction", err)
+	}
+
+	return sanction, nil
+}
+
+// ListActive returns a room's active sanctions, of one kind or of every kind when kind is empty
+func (r *sanctionRepository) ListActive(ctx context.Context, roomID int, kind models.SanctionKind) ([]*models.RoomSanction, error) {
+	query := sanctionSelect + ` WHERE s.room_id = ? AND (? = '' OR s.kind = ?)` + sanctionActive + `
+		ORDER BY s.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID, kind, kind, time.Now())
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to list sanctions", err)
+	}
+	defer rows.Close()
+
+	var sanctions []*models.RoomSanction
+	for rows.Next() {
+		sanction, err := scanSanction(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan sanction", err)
+		}
+		sanctions = append(sanctions, sanction)
+	}
+
+	return sanctions, nil
+}
+
+func (r *sanctionRepository) Lift(ctx context.Context, id, liftedBy int) error {
+	query := `UPDATE room_sanctions SET lifted_at = ?, lifted_by = ? WHERE id = ? AND lifted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), liftedBy, id)
+	if err != nil {
+		return errors.NewDatabaseError("failed to lift sanction", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("sanction not found or already lifted", nil)
+	}
+
+	return nil
+}
+
+func scanSanction(row rowScanner) (*models.RoomSanction, error) {
+	sanction := &models.RoomSanction{}
+	var createdBy, liftedBy sql.NullInt64
+	var expiresAt, liftedAt sql.NullTime
+
+	err := row.Scan(&sanction.ID, &sanction.RoomID, &sanction.UserID, &sanction.Username, &sanction.Kind,
+		&sanction.Reason, &createdBy, &expiresAt, &liftedAt, &liftedBy, &sanction.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		sanction.CreatedBy = &id
+	}
+	if liftedBy.Valid {
+		id := int(liftedBy.Int64)
+		sanction.LiftedBy = &id
+	}
+	if expiresAt.Valid {
+		sanction.ExpiresAt = &expiresAt.Time
+	}
+	if liftedAt.Valid {
+		sanction.LiftedAt = &liftedAt.Time
+	}
+
+	return sanction, nil
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"chat_app/internal/models"
+)
+
+// memorySearchRepository is an in-process inverted index, for databases without full-text
+// search and for tests. It only knows messages indexed since the process started.
+type memorySearchRepository struct {
+	mu sync.RWMutex
+	// postings maps each term to the messages containing it
+	postings map[string]map[int]struct{}
+	docs     map[int]*indexedDocument
+}
+
+type indexedDocument struct {
+	doc   models.SearchDocument
+	terms []string
+}
+
+func NewMemorySearchRepository() SearchRepository {
+	return &memorySearchRepository{
+		postings: make(map[string]map[int]struct{}),
+		docs:     make(map[int]*indexedDocument),
+	}
+}
+
+// Index adds a message, replacing whatever was indexed for it before
+func (r *memorySearchRepository) Index(ctx context.Context, doc *models.SearchDocument) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(doc.MessageID)
+
+	indexed := &indexedDocument{doc: *doc, terms: models.SearchTerms(doc.Content)}
+	for _, term := range indexed.terms {
+		if r.postings[term] == nil {
+			r.postings[term] = make(map[int]struct{})
+		}
+		r.postings[term][doc.MessageID] = struct{}{}
+	}
+	r.docs[doc.MessageID] = indexed
+	return nil
+}
+
+func (r *memorySearchRepository) Remove(ctx context.Context, messageID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(messageID)
+	return nil
+}
+
+func (r *memorySearchRepository) remove(messageID int) {
+	indexed, ok := r.docs[messageID]
+	if !ok {
+		return
+	}
+	for _, term := range indexed.terms {
+		delete(r.postings[term], messageID)
+		if len(r.postings[term]) == 0 {
+			delete(r.postings, term)
+		}
+	}
+	delete(r.docs, messageID)
+}
+
+func (r *memorySearchRepository) Search(ctx context.Context, criteria models.SearchCriteria) ([]int, error) {
+	if len(criteria.Terms) == 0 || len(criteria.RoomIDs) == 0 {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var candidates map[int]struct{}
+	for _, term := range criteria.Terms {
+		matching := r.prefixMatches(term)
+		if candidates == nil {
+			candidates = matching
+			continue
+		}
+		for id := range candidates {
+			if _, ok := matching[id]; !ok {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	rooms := make(map[int]bool, len(criteria.RoomIDs))
+	for _, roomID := range criteria.RoomIDs {
+		rooms[roomID] = true
+	}
+
+	var ids []int
+	for id := range candidates {
+		if r.matches(&r.docs[id].doc, criteria, rooms) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	if len(ids) > criteria.Limit {
+		ids = ids[:criteria.Limit]
+	}
+	return ids, nil
+}
+
+// prefixMatches collects the messages with a word starting with term, like MySQL's term*
+func (r *memorySearchRepository) prefixMatches(term string) map[int]struct{} {
+	matching := make(map[int]struct{})
+	for indexed, ids := range r.postings {
+		if !strings.HasPrefix(indexed, term) {
+			continue
+		}
+		for id := range ids {
+			matching[id] = struct{}{}
+		}
+	}
+	return matching
+}
+
+func (r *memorySearchRepository) matches(doc *models.SearchDocument, criteria models.SearchCriteria, rooms map[int]bool) bool {
+	switch {
+	case !rooms[doc.RoomID]:
+		return false
+	case criteria.UserID != 0 && doc.UserID != criteria.UserID:
+		return false
+	case criteria.Since != nil && doc.CreatedAt.Before(*criteria.Since):
+		return false
+	case criteria.Until != nil && !doc.CreatedAt.Before(*criteria.Until):
+		return false
+	case criteria.HasAttachment != nil && doc.HasAttachment != *criteria.HasAttachment:
+		return false
+	case criteria.BeforeID != 0 && doc.MessageID >= criteria.BeforeID:
+		return false
+	}
+	return true
+}
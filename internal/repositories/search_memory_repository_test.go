@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat_app/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func indexMessages(t *testing.T, repo SearchRepository, docs ...models.SearchDocument) {
+	t.Helper()
+	for i := range docs {
+		require.NoError(t, repo.Index(context.Background(), &docs[i]))
+	}
+}
+
+func TestMemorySearchMatchesEveryTermAsPrefix(t *testing.T) {
+	repo := NewMemorySearchRepository()
+	indexMessages(t, repo,
+		models.SearchDocument{MessageID: 1, RoomID: 1, Content: "The exam date is June 3"},
+		models.SearchDocument{MessageID: 2, RoomID: 1, Content: "Examination dates will follow"},
+		models.SearchDocument{MessageID: 3, RoomID: 1, Content: "No exam talk here"},
+	)
+
+	ids, err := repo.Search(context.Background(), models.SearchCriteria{
+		Terms: []string{"exam", "date"}, RoomIDs: []int{1}, Limit: 10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, ids)
+}
+
+func TestMemorySearchAppliesFiltersAndCursor(t *testing.T) {
+	repo := NewMemorySearchRepository()
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	indexMessages(t, repo,
+		models.SearchDocument{MessageID: 1, RoomID: 1, UserID: 7, Content: "homework", CreatedAt: day},
+		models.SearchDocument{MessageID: 2, RoomID: 2, UserID: 7, Content: "homework", CreatedAt: day},
+		models.SearchDocument{MessageID: 3, RoomID: 1, UserID: 8, Content: "homework", CreatedAt: day},
+		models.SearchDocument{MessageID: 4, RoomID: 1, UserID: 7, Content: "homework", CreatedAt: day.AddDate(0, 0, 2), HasAttachment: true},
+		models.SearchDocument{MessageID: 5, RoomID: 1, UserID: 7, Content: "homework", CreatedAt: day},
+	)
+	withAttachment := true
+	until := day.AddDate(0, 0, 1)
+
+	cases := map[string]struct {
+		criteria models.SearchCriteria
+		want     []int
+	}{
+		"rooms":      {models.SearchCriteria{RoomIDs: []int{2}}, []int{2}},
+		"author":     {models.SearchCriteria{RoomIDs: []int{1}, UserID: 8}, []int{3}},
+		"until":      {models.SearchCriteria{RoomIDs: []int{1}, UserID: 7, Until: &until}, []int{5, 1}},
+		"attachment": {models.SearchCriteria{RoomIDs: []int{1, 2}, HasAttachment: &withAttachment}, []int{4}},
+		"cursor":     {models.SearchCriteria{RoomIDs: []int{1, 2}, BeforeID: 4}, []int{3, 2}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.criteria.Terms = []string{"homework"}
+			if tc.criteria.Limit == 0 {
+				tc.criteria.Limit = 2
+			}
+			ids, err := repo.Search(context.Background(), tc.criteria)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestMemorySearchForgetsReplacedAndRemovedContent(t *testing.T) {
+	repo := NewMemorySearchRepository()
+	indexMessages(t, repo,
+		models.SearchDocument{MessageID: 1, RoomID: 1, Content: "meet at noon"},
+		models.SearchDocument{MessageID: 2, RoomID: 1, Content: "meet tomorrow"},
+	)
+	indexMessages(t, repo, models.SearchDocument{MessageID: 1, RoomID: 1, Content: "meet at dawn"})
+	require.NoError(t, repo.Remove(context.Background(), 2))
+
+	ids, err := repo.Search(context.Background(), models.SearchCriteria{Terms: []string{"noon"}, RoomIDs: []int{1}, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	ids, err = repo.Search(context.Background(), models.SearchCriteria{Terms: []string{"meet"}, RoomIDs: []int{1}, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, ids)
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+)
+
+// mysqlSearchRepository searches the messages table through its FULLTEXT index, which MySQL
+// keeps current on every write
+type mysqlSearchRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLSearchRepository(db *sql.DB) SearchRepository {
+	return &mysqlSearchRepository{db: db}
+}
+
+func (r *mysqlSearchRepository) Index(ctx context.Context, doc *models.SearchDocument) error {
+	return nil
+}
+
+func (r *mysqlSearchRepository) Remove(ctx context.Context, messageID int) error {
+	return nil
+}
+
+// Search runs the terms as required prefix matches in boolean mode. Terms come from
+// models.SearchTerms and hold only letters and digits, so none can act as an operator.
+func (r *mysqlSearchRepository) Search(ctx context.Context, criteria models.SearchCriteria) ([]int, error) {
+	if len(criteria.Terms) == 0 || len(criteria.RoomIDs) == 0 {
+		return nil, nil
+	}
+
+	against := make([]string, len(criteria.Terms))
+	for i, term := range criteria.Terms {
+		against[i] = "+" + term + "*"
+	}
+
+	conditions := []string{
+		"MATCH(m.content) AGAINST (? IN BOOLEAN MODE)",
+		"m.deleted_at IS NULL",
+		"m.room_id IN (?" + strings.Repeat(", ?", len(criteria.RoomIDs)-1) + ")",
+	}
+	args := []interface{}{strings.Join(against, " ")}
+	for _, roomID := range criteria.RoomIDs {
+		args = append(args, roomID)
+	}
+
+	if criteria.UserID != 0 {
+		conditions = append(conditions, "m.user_id = ?")
+		args = append(args, criteria.UserID)
+	}
+	if criteria.Since != nil {
+		conditions = append(conditions, "m.created_at >= ?")
+		args = append(args, *criteria.Since)
+	}
+	if criteria.Until != nil {
+		conditions = append(conditions, "m.created_at < ?")
+		args = append(args, *criteria.Until)
+	}
+	if criteria.HasAttachment != nil {
+		if *criteria.HasAttachment {
+			conditions = append(conditions, "m.attachment_count > 0")
+		} else {
+			conditions = append(conditions, "m.attachment_count = 0")
+		}
+	}
+	if criteria.BeforeID != 0 {
+		conditions = append(conditions, "m.id < ?")
+		args = append(args, criteria.BeforeID)
+	}
+
+	query := `SELECT m.id FROM messages m WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY m.id DESC LIMIT ?`
+	args = append(args, criteria.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to search messages", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.NewDatabaseError("failed to scan search result", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
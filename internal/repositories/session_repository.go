@@ -36,6 +36,26 @@ func (r *sessionRepository) Create(ctx context.Context, session *models.UserSess
 	return nil
 }
 
+func (r *sessionRepository) GetByID(ctx context.Context, id string) (*models.UserSession, error) {
+	query := `
+		SELECT id, user_id, token, expires_at, created_at, is_active
+		FROM user_sessions
+		WHERE id = ?`
+
+	session := &models.UserSession{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.Token, &session.ExpiresAt, &session.CreatedAt, &session.IsActive)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("session not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get session by ID", err)
+	}
+
+	return session, nil
+}
+
 func (r *sessionRepository) GetByToken(ctx context.Context, token string) (*models.UserSession, error) {
 	query := `
 		SELECT id, user_id, token, expires_at, created_at, is_active
@@ -85,10 +105,10 @@ func (r *sessionRepository) GetByUserID(ctx context.Context, userID int) ([]*mod
 func (r *sessionRepository) Update(ctx context.Context, session *models.UserSession) error {
 	query := `
 		UPDATE user_sessions
-		SET expires_at = ?, is_active = ?
+		SET token = ?, expires_at = ?, is_active = ?
 		WHERE id = ?`
 
-	_, err := r.db.ExecContext(ctx, query, session.ExpiresAt, session.IsActive, session.ID)
+	_, err := r.db.ExecContext(ctx, query, session.Token, session.ExpiresAt, session.IsActive, session.ID)
 	if err != nil {
 		return errors.NewDatabaseError("failed to update session", err)
 	}
@@ -107,6 +127,17 @@ func (r *sessionRepository) Delete(ctx context.Context, token string) error {
 	return nil
 }
 
+func (r *sessionRepository) DeleteByID(ctx context.Context, id string) error {
+	query := `UPDATE user_sessions SET is_active = false WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return errors.NewDatabaseError("failed to delete session by ID", err)
+	}
+
+	return nil
+}
+
 func (r *sessionRepository) DeleteByUserID(ctx context.Context, userID int) error {
 	query := `UPDATE user_sessions SET is_active = false WHERE user_id = ?`
 
@@ -126,5 +157,134 @@ func (r *sessionRepository) CleanupExpired(ctx context.Context) error {
 		return errors.NewDatabaseError("failed to cleanup expired sessions", err)
 	}
 
+	_, err = r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return errors.NewDatabaseError("failed to cleanup expired refresh tokens", err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return r.insertRefreshToken(ctx, r.db, token)
+}
+
+func (r *sessionRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, session_id, user_id, family_id, token_hash, expires_at, created_at, rotated_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = ?`
+
+	token := &models.RefreshToken{}
+	var rotatedAt, revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.SessionID, &token.UserID, &token.FamilyID, &token.TokenHash,
+		&token.ExpiresAt, &token.CreatedAt, &rotatedAt, &revokedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("refresh token not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get refresh token", err)
+	}
+
+	if rotatedAt.Valid {
+		token.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the current token as used and stores its successor atomically.
+// It returns a conflict error when the current token was already rotated or revoked, which
+// callers treat as token reuse.
+func (r *sessionRepository) RotateRefreshToken(ctx context.Context, currentID int, next *models.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE refresh_tokens
+		SET rotated_at = ?
+		WHERE id = ? AND rotated_at IS NULL AND revoked_at IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, time.Now(), currentID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to rotate refresh token", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewConflictError("refresh token already used", nil)
+	}
+
+	if err := r.insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit refresh token rotation", err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, time.Now(), familyID); err != nil {
+		return errors.NewDatabaseError("failed to revoke refresh token family", err)
+	}
+
+	query = `
+		UPDATE user_sessions SET is_active = false
+		WHERE id IN (SELECT session_id FROM refresh_tokens WHERE family_id = ?)`
+	if _, err := tx.ExecContext(ctx, query, familyID); err != nil {
+		return errors.NewDatabaseError("failed to revoke sessions for refresh token family", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("failed to commit refresh token family revocation", err)
+	}
+
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *sessionRepository) insertRefreshToken(ctx context.Context, db execer, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (session_id, user_id, family_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	token.CreatedAt = time.Now()
+
+	result, err := db.ExecContext(ctx, query,
+		token.SessionID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("failed to create refresh token", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("failed to get refresh token ID", err)
+	}
+
+	token.ID = int(id)
 	return nil
 }
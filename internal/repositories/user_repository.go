@@ -45,13 +45,13 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 
 func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password, created_at, updated_at, is_active
+		SELECT id, username, email, password, created_at, updated_at, is_active, is_admin
 		FROM users WHERE id = ? AND is_active = true`
 
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.CreatedAt, &user.UpdatedAt, &user.IsActive)
+		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.IsAdmin)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("user not found", err)
@@ -65,13 +65,13 @@ func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, err
 
 func (r *userRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password, created_at, updated_at, is_active
+		SELECT id, username, email, password, created_at, updated_at, is_active, is_admin
 		FROM users WHERE username = ? AND is_active = true`
 
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.CreatedAt, &user.UpdatedAt, &user.IsActive)
+		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.IsAdmin)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("user not found", err)
@@ -85,13 +85,13 @@ func (r *userRepository) GetByUsername(ctx context.Context, username string) (*m
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password, created_at, updated_at, is_active
+		SELECT id, username, email, password, created_at, updated_at, is_active, is_admin
 		FROM users WHERE email = ? AND is_active = true`
 
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.CreatedAt, &user.UpdatedAt, &user.IsActive)
+		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.IsAdmin)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("user not found", err)
@@ -103,6 +103,33 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return user, nil
 }
 
+func (r *userRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	query := `
+		SELECT id, username, email, created_at, updated_at, is_active
+		FROM users
+		WHERE is_active = true
+		ORDER BY username ASC
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to get users", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.IsActive)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to scan user", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"chat_app/internal/auth"
+	"chat_app/internal/config"
+	"chat_app/internal/imaging"
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/internal/storage"
+	"chat_app/pkg/errors"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// sniffLength is how much of an upload is read to detect its type, enough for every format
+// the detector knows
+const sniffLength = 3072
+
+const (
+	attachmentContentPath   = "/api/v1/attachments/%d/content"
+	attachmentThumbnailPath = "/api/v1/attachments/%d/thumbnail"
+)
+
+type attachmentService struct {
+	attachmentRepo repositories.AttachmentRepository
+	messageRepo    repositories.MessageRepository
+	roomMemberRepo repositories.RoomMemberRepository
+	sanctionRepo   repositories.SanctionRepository
+	storage        storage.Storage
+	signer         *auth.DownloadSigner
+	images         ImageProcessor
+	maxSize        int64
+	roomQuota      int64
+}
+
+func NewAttachmentService(attachmentRepo repositories.AttachmentRepository, messageRepo repositories.MessageRepository, roomMemberRepo repositories.RoomMemberRepository, sanctionRepo repositories.SanctionRepository, store storage.Storage, signer *auth.DownloadSigner, images ImageProcessor, cfg config.StorageConfig) AttachmentService {
+	return &attachmentService{
+		attachmentRepo: attachmentRepo,
+		messageRepo:    messageRepo,
+		roomMemberRepo: roomMemberRepo,
+		sanctionRepo:   sanctionRepo,
+		storage:        store,
+		signer:         signer,
+		images:         images,
+		maxSize:        cfg.MaxUploadBytes,
+		roomQuota:      cfg.RoomQuotaBytes,
+	}
+}
+
+// Upload stores a file for a message the user is about to send to the room. The file's type
+// is sniffed from its contents and its checksum computed while it streams to storage. Images
+// have their metadata stripped before they are stored, and are queued for thumbnailing.
+func (s *attachmentService) Upload(ctx context.Context, roomID, userID int, upload *models.AttachmentUpload) (*models.Attachment, error) {
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, userID, models.PermPost); err != nil {
+		return nil, err
+	}
+	if err := requireNotMuted(ctx, s.sanctionRepo, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	if upload.Size <= 0 {
+		return nil, errors.NewValidationError("file is empty", nil)
+	}
+	if s.maxSize > 0 && upload.Size > s.maxSize {
+		return nil, errors.NewTooLargeError(fmt.Sprintf("file exceeds the %d MB limit", s.maxSize>>20), nil)
+	}
+	// An early look at the quota spares storing a file that cannot fit; Create enforces it
+	if s.roomQuota > 0 {
+		usage, err := s.attachmentRepo.UsageByRoom(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if usage+upload.Size > s.roomQuota {
+			return nil, errors.NewTooLargeError("room has reached its storage quota", nil)
+		}
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, errors.NewInternalError("failed to read upload", err)
+	}
+	head = head[:n]
+
+	key, err := storageKey(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		RoomID:      roomID,
+		UploaderID:  &userID,
+		Filename:    models.SanitizeFilename(upload.Filename),
+		ContentType: mimetype.Detect(head).String(),
+		Size:        upload.Size,
+		StorageKey:  key,
+	}
+
+	var content io.Reader = io.MultiReader(bytes.NewReader(head), upload.Content)
+	if imaging.Supported(attachment.ContentType) {
+		if content, err = stripImage(attachment, content); err != nil {
+			return nil, err
+		}
+	}
+
+	hash := sha256.New()
+	if err := s.storage.Put(ctx, key, io.TeeReader(content, hash), attachment.Size, attachment.ContentType); err != nil {
+		return nil, errors.NewInternalError("failed to store file", err)
+	}
+	attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := s.attachmentRepo.Create(ctx, attachment, s.roomQuota); err != nil {
+		_ = s.storage.Delete(ctx, key)
+		return nil, err
+	}
+
+	// An image that misses the queue stays pending and is picked up on the next start
+	if attachment.ImageStatus == models.ImagePending {
+		s.images.Enqueue(attachment.ID)
+	}
+
+	s.sign(attachment, userID)
+	return attachment, nil
+}
+
+// stripImage removes an image's metadata, such as the location a photo was taken at, before
+// anything is stored. Stripping changes the size, so images are read whole; the upload limit
+// bounds how much that holds in memory.
+func stripImage(attachment *models.Attachment, content io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(io.LimitReader(content, attachment.Size))
+	if err != nil {
+		return nil, errors.NewInternalError("failed to read upload", err)
+	}
+	if int64(len(data)) != attachment.Size {
+		return nil, errors.NewValidationError("upload is shorter than its declared size", nil)
+	}
+
+	stripped, err := imaging.StripMetadata(attachment.ContentType, data)
+	if err != nil {
+		return nil, errors.NewValidationError("image file is damaged", err)
+	}
+
+	attachment.Size = int64(len(stripped))
+	attachment.ImageStatus = models.ImagePending
+	return bytes.NewReader(stripped), nil
+}
+
+// GetAttachment returns an attachment with a fresh download URL for the user
+func (s *attachmentService) GetAttachment(ctx context.Context, attachmentID, userID int) (*models.Attachment, error) {
+	attachment, err := s.readable(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.sign(attachment, userID)
+	return attachment, nil
+}
+
+// OpenAttachment checks a signed download URL and opens the file. The user the URL was
+// issued to must still be in the room, so leaving or being removed revokes links at once.
+func (s *attachmentService) OpenAttachment(ctx context.Context, attachmentID, userID int, expires int64, signature string) (*models.Attachment, io.ReadCloser, error) {
+	if err := s.signer.Verify(attachmentID, userID, expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.readable(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// OpenThumbnail checks a signed download URL and opens the image's thumbnail. URLs grant an
+// attachment as a whole, so the signature is the same as for the original.
+func (s *attachmentService) OpenThumbnail(ctx context.Context, attachmentID, userID int, expires int64, signature string) (*models.Attachment, io.ReadCloser, error) {
+	if err := s.signer.Verify(attachmentID, userID, expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.readable(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.ThumbnailKey == "" {
+		return nil, nil, errors.NewNotFoundError("attachment has no thumbnail", nil)
+	}
+
+	content, err := s.open(ctx, attachment.ThumbnailKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (s *attachmentService) open(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, err := s.storage.Open(ctx, key)
+	if err == storage.ErrNotFound {
+		return nil, errors.NewNotFoundError("attachment content not found", err)
+	}
+	if err != nil {
+		return nil, errors.NewInternalError("failed to open file", err)
+	}
+	return content, nil
+}
+
+// readable loads an attachment the user may see: one in a room they belong to, which is
+// either their own upload or part of a message that has not been deleted
+func (s *attachmentService) readable(ctx context.Context, attachmentID, userID int) (*models.Attachment, error) {
+	attachment, err := s.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := roomMembership(ctx, s.roomMemberRepo, attachment.RoomID, userID); err != nil {
+		return nil, err
+	}
+
+	if attachment.MessageID == nil {
+		if attachment.UploaderID == nil || *attachment.UploaderID != userID {
+			return nil, errors.NewNotFoundError("attachment not found", nil)
+		}
+		return attachment, nil
+	}
+
+	message, err := s.messageRepo.GetByID(ctx, *attachment.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return nil, errors.NewNotFoundError("attachment not found", nil)
+	}
+	return attachment, nil
+}
+
+// AttachTo loads the attachments of a page of messages in a single query. Download URLs are
+// signed for userID; with no user the attachments carry no URLs, as for messages pushed to a
+// whole room, whose readers fetch their own. Deleted messages lose their attachments.
+func (s *attachmentService) AttachTo(ctx context.Context, messages []*models.Message, userID int) error {
+	ids := make([]int, 0, len(messages))
+	for _, message := range messages {
+		if message.DeletedAt == nil {
+			ids = append(ids, message.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	attachments, err := s.attachmentRepo.GetByMessageIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		message.Attachments = attachments[message.ID]
+		if userID == 0 {
+			continue
+		}
+		for _, attachment := range message.Attachments {
+			s.sign(attachment, userID)
+		}
+	}
+	return nil
+}
+
+func (s *attachmentService) sign(attachment *models.Attachment, userID int) {
+	expires, signature := s.signer.Sign(attachment.ID, userID)
+
+	query := url.Values{}
+	query.Set("uid", strconv.Itoa(userID))
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("sig", signature)
+
+	attachment.URL = fmt.Sprintf(attachmentContentPath, attachment.ID) + "?" + query.Encode()
+	if attachment.ThumbnailKey != "" {
+		attachment.ThumbnailURL = fmt.Sprintf(attachmentThumbnailPath, attachment.ID) + "?" + query.Encode()
+	}
+	attachment.URLExpiresAt = &expires
+}
+
+// storageKey names a new object under its room with a random suffix, so keys are never
+// guessable or derived from client input
+func storageKey(roomID int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.NewInternalError("failed to generate storage key", err)
+	}
+	return fmt.Sprintf("rooms/%d/%s", roomID, hex.EncodeToString(b)), nil
+}
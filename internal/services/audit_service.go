@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/errors"
+	"chat_app/pkg/logger"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+type auditService struct {
+	auditRepo      repositories.AuditRepository
+	userRepo       repositories.UserRepository
+	roomMemberRepo repositories.RoomMemberRepository
+	logger         *logger.Logger
+}
+
+func NewAuditService(auditRepo repositories.AuditRepository, userRepo repositories.UserRepository, roomMemberRepo repositories.RoomMemberRepository, logger *logger.Logger) AuditService {
+	return &auditService{
+		auditRepo:      auditRepo,
+		userRepo:       userRepo,
+		roomMemberRepo: roomMemberRepo,
+		logger:         logger,
+	}
+}
+
+// Record appends an entry, filling in the usernames it names so the entry still reads
+// correctly after those accounts are renamed or deleted. The action has already happened
+// by the time it is recorded, so a failed write is logged instead of failing the request.
+func (s *auditService) Record(ctx context.Context, entry *models.AuditEntry) {
+	if entry.ActorID != nil && entry.ActorUsername == "" {
+		entry.ActorUsername = s.username(ctx, *entry.ActorID)
+	}
+	if entry.TargetUserID != nil && entry.TargetUsername == "" {
+		entry.TargetUsername = s.username(ctx, *entry.TargetUserID)
+	}
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		s.logger.WithFields(logger.Fields{
+			"action":   entry.Action,
+			"room_id":  entry.RoomID,
+			"actor_id": entry.ActorID,
+		}).WithError(err).Error("Failed to write audit entry")
+	}
+}
+
+func (s *auditService) username(ctx context.Context, userID int) string {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return user.Username
+}
+
+// GetRoomLog returns a room's audit history to its owner or a site administrator
+func (s *auditService) GetRoomLog(ctx context.Context, roomID, userID int, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	member, err := s.roomMemberRepo.GetMember(ctx, roomID, userID)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if member == nil || member.Role != models.RoleOwner {
+		if err := s.requireAdmin(ctx, userID); err != nil {
+			return nil, errors.NewForbiddenError("only the room owner can view the audit log", nil)
+		}
+	}
+
+	filter.RoomID = roomID
+	return s.auditRepo.List(ctx, pageAuditFilter(filter))
+}
+
+// GetLog returns audit history across every room; it is limited to site administrators
+func (s *auditService) GetLog(ctx context.Context, userID int, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	if err := s.requireAdmin(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.auditRepo.List(ctx, pageAuditFilter(filter))
+}
+
+func (s *auditService) requireAdmin(ctx context.Context, userID int) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return errors.NewForbiddenError("administrator access required", nil)
+	}
+	return nil
+}
+
+func pageAuditFilter(filter models.AuditFilter) models.AuditFilter {
+	if filter.Limit <= 0 || filter.Limit > maxAuditLimit {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return filter
+}
+
+// snapshot encodes a value for an audit entry's before or after state
+func snapshot(value interface{}) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func intPtr(value int) *int {
+	return &value
+}
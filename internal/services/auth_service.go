@@ -4,12 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
+	stderrors "errors"
 	"time"
 
+	"chat_app/internal/auth"
 	"chat_app/internal/models"
 	"chat_app/internal/repositories"
 	"chat_app/pkg/errors"
+	"chat_app/pkg/logger"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,16 +19,20 @@ import (
 type authService struct {
 	userRepo    repositories.UserRepository
 	sessionRepo repositories.SessionRepository
-	jwtSecret   string
-	jwtExpiry   time.Duration
+	tokens      *auth.TokenManager
+	sessions    *sessionCache
+	logger      *logger.Logger
 }
 
-func NewAuthService(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository, jwtSecret string, jwtExpiry time.Duration) AuthService {
+const sessionCacheTTL = 30 * time.Second
+
+func NewAuthService(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository, tokens *auth.TokenManager, logger *logger.Logger) AuthService {
 	return &authService{
 		userRepo:    userRepo,
 		sessionRepo: sessionRepo,
-		jwtSecret:   jwtSecret,
-		jwtExpiry:   jwtExpiry,
+		tokens:      tokens,
+		sessions:    newSessionCache(sessionCacheTTL),
+		logger:      logger,
 	}
 }
 
@@ -57,30 +63,7 @@ func (s *authService) Register(ctx context.Context, req *models.RegisterRequest)
 		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := s.generateTokens(user.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create session
-	session := &models.UserSession{
-		ID:        generateSessionID(),
-		UserID:    user.ID,
-		Token:     accessToken,
-		ExpiresAt: time.Now().Add(s.jwtExpiry),
-	}
-
-	if err := s.sessionRepo.Create(ctx, session); err != nil {
-		return nil, err
-	}
-
-	return &models.AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(s.jwtExpiry.Seconds()),
-	}, nil
+	return s.startSession(ctx, user)
 }
 
 func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
@@ -95,89 +78,193 @@ func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 		return nil, errors.NewUnauthorizedError("invalid credentials", err)
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := s.generateTokens(user.ID)
+	return s.startSession(ctx, user)
+}
+
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	claims, err := s.tokens.ParseRefreshToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create session
-	session := &models.UserSession{
-		ID:        generateSessionID(),
-		UserID:    user.ID,
-		Token:     accessToken,
-		ExpiresAt: time.Now().Add(s.jwtExpiry),
+	stored, err := s.sessionRepo.GetRefreshTokenByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, errors.NewUnauthorizedError("invalid refresh token", err)
+		}
+		return nil, err
+	}
+	if stored.RevokedAt != nil {
+		return nil, errors.NewUnauthorizedError("refresh token revoked", nil)
+	}
+	if stored.RotatedAt != nil {
+		return nil, s.revokeFamily(ctx, stored)
 	}
 
-	if err := s.sessionRepo.Create(ctx, session); err != nil {
+	session, err := s.activeSession(ctx, claims.SessionID)
+	if err != nil {
 		return nil, err
 	}
 
-	return &models.AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(s.jwtExpiry.Seconds()),
-	}, nil
-}
-
-func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
-	// Validate refresh token (simplified - in production, use proper JWT validation)
-	session, err := s.sessionRepo.GetByToken(ctx, refreshToken)
+	// Get user
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, errors.NewUnauthorizedError("invalid refresh token", err)
 	}
 
-	// Get user
-	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	accessToken, newRefreshToken, next, err := s.generateTokens(user, session, stored.FamilyID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate new tokens
-	accessToken, newRefreshToken, err := s.generateTokens(user.ID)
-	if err != nil {
+	// Two concurrent refreshes with the same token means one of them is a replay.
+	if err := s.sessionRepo.RotateRefreshToken(ctx, stored.ID, next); err != nil {
+		if isConflict(err) {
+			return nil, s.revokeFamily(ctx, stored)
+		}
 		return nil, err
 	}
 
 	// Update session
-	session.Token = accessToken
-	session.ExpiresAt = time.Now().Add(s.jwtExpiry)
 	if err := s.sessionRepo.Update(ctx, session); err != nil {
 		return nil, err
 	}
 
-	return &models.AuthResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		ExpiresIn:    int64(s.jwtExpiry.Seconds()),
-	}, nil
+	return s.authResponse(user, accessToken, newRefreshToken), nil
+}
+
+// revokeFamily handles replay of an already-rotated refresh token: the legitimate holder
+// and the attacker cannot be told apart, so every token and session in the family dies.
+func (s *authService) revokeFamily(ctx context.Context, stored *models.RefreshToken) error {
+	s.logger.WithFields(logger.Fields{
+		"user_id":    stored.UserID,
+		"session_id": stored.SessionID,
+		"family_id":  stored.FamilyID,
+	}).Warn("Refresh token reuse detected, revoking token family")
+
+	if err := s.sessionRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	s.sessions.set(stored.SessionID, false)
+
+	return errors.NewUnauthorizedError("refresh token reuse detected", nil)
 }
 
 func (s *authService) Logout(ctx context.Context, token string) error {
-	return s.sessionRepo.Delete(ctx, token)
+	claims, err := s.tokens.ParseAccessToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.DeleteByID(ctx, claims.SessionID); err != nil {
+		return err
+	}
+	s.sessions.set(claims.SessionID, false)
+
+	return nil
 }
 
+// ValidateToken verifies the token signature and expiry locally; the session lookup only
+// serves revocation and is cached, so authenticated requests rarely touch the database.
 func (s *authService) ValidateToken(ctx context.Context, token string) (*models.User, error) {
-	session, err := s.sessionRepo.GetByToken(ctx, token)
+	claims, err := s.tokens.ParseAccessToken(token)
 	if err != nil {
-		return nil, errors.NewUnauthorizedError("invalid token", err)
+		return nil, err
 	}
 
-	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	active, cached := s.sessions.get(claims.SessionID)
+	if !cached {
+		_, err := s.activeSession(ctx, claims.SessionID)
+		if err != nil && !isUnauthorized(err) {
+			return nil, err
+		}
+		active = err == nil
+		s.sessions.set(claims.SessionID, active)
+	}
+	if !active {
+		return nil, errors.NewUnauthorizedError("session revoked", nil)
+	}
+
+	return &models.User{
+		ID:       claims.UserID,
+		Username: claims.Username,
+		IsActive: true,
+	}, nil
+}
+
+func (s *authService) startSession(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	// Create session
+	session := &models.UserSession{
+		ID:     generateSessionID(),
+		UserID: user.ID,
+	}
+
+	accessToken, refreshToken, stored, err := s.generateTokens(user, session, generateSessionID())
 	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return s.authResponse(user, accessToken, refreshToken), nil
+}
+
+// generateTokens signs a new token pair for the session, records the access token hash and
+// refresh expiry on it and returns the refresh token record to persist; the caller persists both.
+func (s *authService) generateTokens(user *models.User, session *models.UserSession, familyID string) (string, string, *models.RefreshToken, error) {
+	accessToken, _, err := s.tokens.IssueAccessToken(user, session.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	refreshToken, refreshExpiresAt, err := s.tokens.IssueRefreshToken(user, session.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	session.Token = auth.HashToken(accessToken)
+	session.ExpiresAt = refreshExpiresAt
+	session.IsActive = true
+
+	stored := &models.RefreshToken{
+		SessionID: session.ID,
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: refreshExpiresAt,
+	}
+
+	return accessToken, refreshToken, stored, nil
 }
 
-func (s *authService) generateTokens(userID int) (string, string, error) {
-	// Simplified token generation - in production, use proper JWT
-	accessToken := fmt.Sprintf("access_%d_%d", userID, time.Now().Unix())
-	refreshToken := fmt.Sprintf("refresh_%d_%d", userID, time.Now().Unix())
-	return accessToken, refreshToken, nil
+func (s *authService) activeSession(ctx context.Context, sessionID string) (*models.UserSession, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, errors.NewUnauthorizedError("session not found", err)
+		}
+		return nil, err
+	}
+	if !session.IsActive || time.Now().After(session.ExpiresAt) {
+		return nil, errors.NewUnauthorizedError("session expired or revoked", nil)
+	}
+	return session, nil
+}
+
+func (s *authService) authResponse(user *models.User, accessToken, refreshToken string) *models.AuthResponse {
+	return &models.AuthResponse{
+		User:             user,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(s.tokens.AccessTTL().Seconds()),
+		RefreshExpiresIn: int64(s.tokens.RefreshTTL().Seconds()),
+	}
 }
 
 func generateSessionID() string {
@@ -185,3 +272,13 @@ func generateSessionID() string {
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
 }
+
+func isConflict(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Code == errors.ErrCodeConflict
+}
+
+func isUnauthorized(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Code == errors.ErrCodeUnauthorized
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat_app/internal/auth"
+	"chat_app/internal/config"
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+	"chat_app/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuthService(t *testing.T) (*authService, *fakeSessions, *models.AuthResponse) {
+	tokens, err := auth.NewTokenManager(config.JWTConfig{
+		SecretKey:     "test-secret",
+		Algorithm:     "HS256",
+		Issuer:        "chat_app",
+		Expiration:    time.Minute,
+		RefreshExpiry: time.Hour,
+	})
+	require.NoError(t, err)
+
+	user := &models.User{ID: 7, Username: "alice", IsActive: true}
+	sessions := newFakeSessions()
+	service := NewAuthService(&fakeUsers{users: map[int]*models.User{user.ID: user}}, sessions, tokens,
+		logger.New("error", "json")).(*authService)
+
+	login, err := service.startSession(context.Background(), user)
+	require.NoError(t, err)
+	return service, sessions, login
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	service, sessions, login := newTestAuthService(t)
+
+	refreshed, err := service.RefreshToken(context.Background(), login.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+
+	require.Len(t, sessions.tokens, 2)
+	old, next := sessions.tokens[0], sessions.tokens[1]
+	assert.NotNil(t, old.RotatedAt)
+	assert.Nil(t, next.RotatedAt)
+	assert.Equal(t, old.FamilyID, next.FamilyID)
+	assert.Equal(t, auth.HashToken(refreshed.RefreshToken), next.TokenHash)
+
+	_, err = service.RefreshToken(context.Background(), refreshed.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestReplayedRefreshTokenRevokesItsFamily(t *testing.T) {
+	service, sessions, login := newTestAuthService(t)
+	ctx := context.Background()
+
+	refreshed, err := service.RefreshToken(ctx, login.RefreshToken)
+	require.NoError(t, err)
+
+	_, err = service.RefreshToken(ctx, login.RefreshToken)
+	assert.Equal(t, errors.ErrCodeUnauthorized, errorCode(err))
+	assert.Equal(t, []string{sessions.tokens[0].FamilyID}, sessions.revoked)
+
+	// The legitimate holder of the rotated token is logged out too
+	_, err = service.RefreshToken(ctx, refreshed.RefreshToken)
+	assert.Equal(t, errors.ErrCodeUnauthorized, errorCode(err))
+	_, err = service.ValidateToken(ctx, refreshed.AccessToken)
+	assert.Equal(t, errors.ErrCodeUnauthorized, errorCode(err))
+}
+
+func TestRefreshTokenFromRevokedFamilyIsRejected(t *testing.T) {
+	service, sessions, login := newTestAuthService(t)
+	ctx := context.Background()
+
+	require.NoError(t, sessions.RevokeRefreshTokenFamily(ctx, sessions.tokens[0].FamilyID))
+
+	_, err := service.RefreshToken(ctx, login.RefreshToken)
+	assert.Equal(t, errors.ErrCodeUnauthorized, errorCode(err))
+	assert.Len(t, sessions.tokens, 1)
+	assert.Nil(t, sessions.tokens[0].RotatedAt)
+}
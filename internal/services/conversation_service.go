@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/errors"
+)
+
+// maxConversationParticipants bounds group conversations, including their creator; larger
+// groups belong in a room
+const maxConversationParticipants = 10
+
+type conversationService struct {
+	roomRepo       repositories.RoomRepository
+	roomMemberRepo repositories.RoomMemberRepository
+	userRepo       repositories.UserRepository
+	notifier       Notifier
+}
+
+func NewConversationService(roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository, userRepo repositories.UserRepository, notifier Notifier) ConversationService {
+	return &conversationService{
+		roomRepo:       roomRepo,
+		roomMemberRepo: roomMemberRepo,
+		userRepo:       userRepo,
+		notifier:       notifier,
+	}
+}
+
+// OpenConversation returns the conversation between the user and the named users, creating
+// it the first time. There is one conversation per set of participants, so opening it again
+// finds the same one and brings the user back if they had left.
+func (s *conversationService) OpenConversation(ctx context.Context, userID int, usernames []string) (*models.Conversation, error) {
+	participants := map[int]bool{userID: true}
+	for _, username := range usernames {
+		user, err := s.userRepo.GetByUsername(ctx, strings.TrimSpace(username))
+		if err != nil {
+			return nil, err
+		}
+		participants[user.ID] = true
+	}
+
+	if len(participants) < 2 {
+		return nil, errors.NewValidationError("a conversation needs at least one other participant", nil)
+	}
+	if len(participants) > maxConversationParticipants {
+		return nil, errors.NewValidationError(fmt.Sprintf("a conversation can have at most %d participants", maxConversationParticipants), nil)
+	}
+
+	key := participantKey(participants)
+	room, err := s.roomRepo.GetByParticipantKey(ctx, key)
+	if errors.IsNotFound(err) {
+		room, err = s.createConversation(ctx, userID, key, participants)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.roomMemberRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		if err := s.roomMemberRepo.AddMember(ctx, &models.RoomMember{RoomID: room.ID, UserID: userID}); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.withParticipants(ctx, room)
+}
+
+func (s *conversationService) createConversation(ctx context.Context, userID int, key string, participants map[int]bool) (*models.Room, error) {
+	kind := models.RoomKindDirect
+	if len(participants) > 2 {
+		kind = models.RoomKindGroup
+	}
+
+	room := &models.Room{
+		IsPrivate:      true,
+		Kind:           kind,
+		ParticipantKey: key,
+		CreatedBy:      &userID,
+	}
+	// Everyone is a plain member: nobody owns a conversation or can moderate it
+	userIDs := make([]int, 0, len(participants))
+	for participant := range participants {
+		userIDs = append(userIDs, participant)
+	}
+	if err := s.roomRepo.CreateConversation(ctx, room, userIDs); err != nil {
+		// Someone opened the same conversation at the same moment; use theirs
+		if existing, lookupErr := s.roomRepo.GetByParticipantKey(ctx, key); lookupErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	return room, nil
+}
+
+// GetConversations lists the user's conversations with their participants
+func (s *conversationService) GetConversations(ctx context.Context, userID int) ([]*models.Conversation, error) {
+	rooms, err := s.roomRepo.GetConversationsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*models.Conversation, 0, len(rooms))
+	for _, room := range rooms {
+		conversation, err := s.withParticipants(ctx, room)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, nil
+}
+
+// GetConversation returns a conversation the user takes part in
+func (s *conversationService) GetConversation(ctx context.Context, conversationID, userID int) (*models.Conversation, error) {
+	room, err := s.conversation(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withParticipants(ctx, room)
+}
+
+// LeaveConversation removes the user from a conversation until they open it again
+func (s *conversationService) LeaveConversation(ctx context.Context, conversationID, userID int) error {
+	if _, err := s.conversation(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	if err := s.roomMemberRepo.RemoveMember(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	if s.notifier != nil {
+		s.notifier.RemoveFromRoom(userID, conversationID, RemovedLeft)
+	}
+	return nil
+}
+
+// conversation loads a conversation, hiding it from anyone outside it
+func (s *conversationService) conversation(ctx context.Context, conversationID, userID int) (*models.Room, error) {
+	room, err := s.roomRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if room.Kind == models.RoomKindRoom {
+		return nil, errors.NewNotFoundError("conversation not found", nil)
+	}
+
+	isMember, err := s.roomMemberRepo.IsMember(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.NewNotFoundError("conversation not found", nil)
+	}
+
+	return room, nil
+}
+
+func (s *conversationService) withParticipants(ctx context.Context, room *models.Room) (*models.Conversation, error) {
+	members, err := s.roomMemberRepo.GetMembers(ctx, room.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Conversation{Room: *room, Participants: members}, nil
+}
+
+// participantKey identifies a set of users regardless of the order they were named in
+func participantKey(participants map[int]bool) string {
+	ids := make([]int, 0, len(participants))
+	for id := range participants {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/errors"
+	"chat_app/pkg/logger"
+)
+
+// The fakes below keep rooms, messages and sanctions in memory. Each embeds the interface it
+// stands in for, so a method the services under test do not call panics instead of passing.
+
+type memberKey struct{ room, user int }
+
+type fakeMembers struct {
+	repositories.RoomMemberRepository
+	members map[memberKey]*models.RoomMember
+	// failRemove makes removing these users fail
+	failRemove map[int]bool
+}
+
+func newFakeMembers() *fakeMembers {
+	return &fakeMembers{members: map[memberKey]*models.RoomMember{}, failRemove: map[int]bool{}}
+}
+
+func (f *fakeMembers) add(roomID, userID int, role models.RoomRole) {
+	f.members[memberKey{roomID, userID}] = &models.RoomMember{RoomID: roomID, UserID: userID, Role: role}
+}
+
+func (f *fakeMembers) GetMember(ctx context.Context, roomID, userID int) (*models.RoomMember, error) {
+	member, ok := f.members[memberKey{roomID, userID}]
+	if !ok {
+		return nil, errors.NewNotFoundError("member not found", nil)
+	}
+	copied := *member
+	return &copied, nil
+}
+
+func (f *fakeMembers) GetMembers(ctx context.Context, roomID int) ([]*models.RoomMember, error) {
+	var members []*models.RoomMember
+	for key, member := range f.members {
+		if key.room == roomID {
+			copied := *member
+			members = append(members, &copied)
+		}
+	}
+	return members, nil
+}
+
+func (f *fakeMembers) IsMember(ctx context.Context, roomID, userID int) (bool, error) {
+	_, ok := f.members[memberKey{roomID, userID}]
+	return ok, nil
+}
+
+func (f *fakeMembers) UpdateRole(ctx context.Context, roomID, userID int, role models.RoomRole) error {
+	member, ok := f.members[memberKey{roomID, userID}]
+	if !ok {
+		return errors.NewNotFoundError("room member not found", nil)
+	}
+	member.Role = role
+	return nil
+}
+
+func (f *fakeMembers) GetOwnerlessRoomIDs(ctx context.Context) ([]int, error) {
+	owned, eligible := map[int]bool{}, map[int]bool{}
+	for key, member := range f.members {
+		owned[key.room] = owned[key.room] || member.Role == models.RoleOwner
+		eligible[key.room] = eligible[key.room] || member.Role != models.RoleGuest
+	}
+	var roomIDs []int
+	for roomID := range eligible {
+		if eligible[roomID] && !owned[roomID] {
+			roomIDs = append(roomIDs, roomID)
+		}
+	}
+	return roomIDs, nil
+}
+
+func (f *fakeMembers) RemoveMember(ctx context.Context, roomID, userID int) error {
+	if f.failRemove[userID] {
+		return errors.NewDatabaseError("failed to remove member", nil)
+	}
+	delete(f.members, memberKey{roomID, userID})
+	return nil
+}
+
+type fakeSanctions struct {
+	repositories.SanctionRepository
+	sanctions []*models.RoomSanction
+}
+
+func (f *fakeSanctions) Create(ctx context.Context, sanction *models.RoomSanction) error {
+	sanction.ID = len(f.sanctions) + 1
+	sanction.CreatedAt = time.Now()
+	f.sanctions = append(f.sanctions, sanction)
+	return nil
+}
+
+func (f *fakeSanctions) GetByID(ctx context.Context, id int) (*models.RoomSanction, error) {
+	if id < 1 || id > len(f.sanctions) {
+		return nil, errors.NewNotFoundError("sanction not found", nil)
+	}
+	return f.sanctions[id-1], nil
+}
+
+func (f *fakeSanctions) GetActive(ctx context.Context, roomID, userID int, kind models.SanctionKind) (*models.RoomSanction, error) {
+	for _, sanction := range f.sanctions {
+		if sanction.RoomID == roomID && sanction.UserID == userID && sanction.Kind == kind && sanction.LiftedAt == nil {
+			return sanction, nil
+		}
+	}
+	return nil, errors.NewNotFoundError("sanction not found", nil)
+}
+
+type fakeMessages struct {
+	repositories.MessageRepository
+	messages map[int]*models.Message
+}
+
+func (f *fakeMessages) GetByID(ctx context.Context, id int) (*models.Message, error) {
+	message, ok := f.messages[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("message not found", nil)
+	}
+	copied := *message
+	return &copied, nil
+}
+
+func (f *fakeMessages) Edit(ctx context.Context, message *models.Message, editedBy int) error {
+	f.messages[message.ID].Content = message.Content
+	return nil
+}
+
+func (f *fakeMessages) Delete(ctx context.Context, message *models.Message, deletedBy int) error {
+	now := time.Now()
+	message.Content = ""
+	message.DeletedAt = &now
+	f.messages[message.ID] = message
+	return nil
+}
+
+type fakeJoinRequests struct {
+	repositories.JoinRequestRepository
+	requests map[int]*models.JoinRequest
+	// failApprove makes approving fail as a rolled back transaction would
+	failApprove bool
+	members     *fakeMembers
+}
+
+func (f *fakeJoinRequests) GetByID(ctx context.Context, id int) (*models.JoinRequest, error) {
+	request, ok := f.requests[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("join request not found", nil)
+	}
+	copied := *request
+	return &copied, nil
+}
+
+func (f *fakeJoinRequests) Decide(ctx context.Context, id int, status models.JoinRequestStatus, decidedBy int) error {
+	request := f.requests[id]
+	if request.Status != models.JoinRequestPending {
+		return errors.NewConflictError("join request has already been decided", nil)
+	}
+	request.Status = status
+	request.DecidedBy = &decidedBy
+	return nil
+}
+
+func (f *fakeJoinRequests) Approve(ctx context.Context, id, decidedBy int, member *models.RoomMember) error {
+	if f.failApprove {
+		return errors.NewDatabaseError("failed to add room member", nil)
+	}
+	if err := f.Decide(ctx, id, models.JoinRequestApproved, decidedBy); err != nil {
+		return err
+	}
+	f.members.add(member.RoomID, member.UserID, models.RoleMember)
+	return nil
+}
+
+type fakeSearch struct{ repositories.SearchRepository }
+
+func (fakeSearch) Index(ctx context.Context, doc *models.SearchDocument) error { return nil }
+func (fakeSearch) Remove(ctx context.Context, messageID int) error             { return nil }
+
+type fakeAttachments struct{ AttachmentService }
+
+func (fakeAttachments) AttachTo(ctx context.Context, messages []*models.Message, userID int) error {
+	return nil
+}
+
+type fakeAudit struct {
+	AuditService
+	entries []*models.AuditEntry
+}
+
+func (f *fakeAudit) Record(ctx context.Context, entry *models.AuditEntry) {
+	f.entries = append(f.entries, entry)
+}
+
+type fakeNotifications struct {
+	NotificationService
+	sent []*models.Notification
+}
+
+func (f *fakeNotifications) Notify(ctx context.Context, notification *models.Notification) {
+	f.sent = append(f.sent, notification)
+}
+
+type fakeNotifier struct {
+	removed []memberKey
+}
+
+func (f *fakeNotifier) NotifyUser(userID int, event string, payload interface{}) {}
+func (f *fakeNotifier) NotifyRoom(roomID int, event string, payload interface{}) {}
+func (f *fakeNotifier) NotifyMessage(message *models.Message)                    {}
+func (f *fakeNotifier) RemoveFromRoom(userID, roomID int, reason string) {
+	f.removed = append(f.removed, memberKey{roomID, userID})
+}
+
+// fixture wires the message and room services to one set of fakes
+type fixture struct {
+	members   *fakeMembers
+	sanctions *fakeSanctions
+	messages  *fakeMessages
+	requests  *fakeJoinRequests
+	audit     *fakeAudit
+	notifier  *fakeNotifier
+
+	messageService MessageService
+	roomService    RoomService
+}
+
+func newFixture() *fixture {
+	f := &fixture{
+		members:   newFakeMembers(),
+		sanctions: &fakeSanctions{},
+		messages:  &fakeMessages{messages: map[int]*models.Message{}},
+		audit:     &fakeAudit{},
+		notifier:  &fakeNotifier{},
+	}
+	f.requests = &fakeJoinRequests{requests: map[int]*models.JoinRequest{}, members: f.members}
+	notifications := &fakeNotifications{}
+	f.messageService = NewMessageService(f.messages, nil, f.members, nil, f.sanctions, nil, nil, fakeSearch{},
+		fakeAttachments{}, notifications, f.audit, f.notifier, nil)
+	f.roomService = NewRoomService(nil, f.members, f.requests, f.sanctions, notifications, f.audit, f.notifier,
+		logger.New("error", "json"))
+	return f
+}
+
+func (f *fixture) addMessage(id, roomID, userID int, content string) {
+	f.messages.messages[id] = &models.Message{ID: id, RoomID: roomID, UserID: userID, Content: content}
+}
+
+func errorCode(err error) errors.ErrorCode {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Code
+	}
+	return ""
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+	users map[int]*models.User
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int) (*models.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("user not found", nil)
+	}
+	return user, nil
+}
+
+// fakeSessions keeps sessions and refresh tokens in memory and mirrors the repository's
+// rotation and family revocation rules.
+type fakeSessions struct {
+	repositories.SessionRepository
+	sessions map[string]*models.UserSession
+	tokens   []*models.RefreshToken
+	revoked  []string
+}
+
+func newFakeSessions() *fakeSessions {
+	return &fakeSessions{sessions: map[string]*models.UserSession{}}
+}
+
+func (f *fakeSessions) Create(ctx context.Context, session *models.UserSession) error {
+	copied := *session
+	f.sessions[session.ID] = &copied
+	return nil
+}
+
+func (f *fakeSessions) GetByID(ctx context.Context, id string) (*models.UserSession, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("session not found", nil)
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (f *fakeSessions) Update(ctx context.Context, session *models.UserSession) error {
+	return f.Create(ctx, session)
+}
+
+func (f *fakeSessions) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.ID = len(f.tokens) + 1
+	token.CreatedAt = time.Now()
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+func (f *fakeSessions) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, errors.NewNotFoundError("refresh token not found", nil)
+}
+
+func (f *fakeSessions) RotateRefreshToken(ctx context.Context, currentID int, next *models.RefreshToken) error {
+	current := f.tokens[currentID-1]
+	if current.RotatedAt != nil || current.RevokedAt != nil {
+		return errors.NewConflictError("refresh token already used", nil)
+	}
+	now := time.Now()
+	current.RotatedAt = &now
+	return f.CreateRefreshToken(ctx, next)
+}
+
+func (f *fakeSessions) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, token := range f.tokens {
+		if token.FamilyID != familyID {
+			continue
+		}
+		if token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+		if session, ok := f.sessions[token.SessionID]; ok {
+			session.IsActive = false
+		}
+	}
+	f.revoked = append(f.revoked, familyID)
+	return nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"chat_app/internal/config"
+	"chat_app/internal/imaging"
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/internal/storage"
+	"chat_app/pkg/logger"
+)
+
+const (
+	imageQueueSize = 256
+	// pendingImageBatch bounds how many images left over from a previous run are requeued
+	pendingImageBatch = 1000
+)
+
+// imageProcessor makes thumbnails and placeholders for uploaded images on a pool of workers,
+// so neither uploading nor sending a message waits for decoding
+type imageProcessor struct {
+	attachmentRepo repositories.AttachmentRepository
+	storage        storage.Storage
+	notifier       Notifier
+	logger         *logger.Logger
+	jobs           chan int
+	workers        int
+	thumbnailSize  int
+	maxPixels      int
+}
+
+func NewImageProcessor(attachmentRepo repositories.AttachmentRepository, store storage.Storage, notifier Notifier, logger *logger.Logger, cfg config.StorageConfig) ImageProcessor {
+	return &imageProcessor{
+		attachmentRepo: attachmentRepo,
+		storage:        store,
+		notifier:       notifier,
+		logger:         logger,
+		jobs:           make(chan int, imageQueueSize),
+		workers:        max(1, cfg.ImageWorkers),
+		thumbnailSize:  max(16, cfg.ThumbnailSize),
+		maxPixels:      cfg.MaxImagePixels,
+	}
+}
+
+// Start launches the workers and requeues images a previous run left unprocessed
+func (p *imageProcessor) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
+
+	go func() {
+		ids, err := p.attachmentRepo.GetPendingImages(ctx, pendingImageBatch)
+		if err != nil {
+			p.logger.WithError(err).Error("Failed to load pending images")
+			return
+		}
+		// Requeueing runs in the background, so it waits for room in the queue
+		for _, id := range ids {
+			select {
+			case p.jobs <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Enqueue schedules an image without waiting, so a full queue never holds up an upload. An
+// image that finds the queue full stays pending until the next start.
+func (p *imageProcessor) Enqueue(attachmentID int) {
+	select {
+	case p.jobs <- attachmentID:
+	default:
+		p.logger.WithField("attachment_id", attachmentID).Warn("Image queue full, leaving image pending")
+	}
+}
+
+func (p *imageProcessor) work(ctx context.Context) {
+	for {
+		select {
+		case id := <-p.jobs:
+			p.process(ctx, id)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// process renders an image's thumbnail and placeholder and announces the result. Images that
+// cannot be decoded are marked failed and stay downloadable as plain files.
+func (p *imageProcessor) process(ctx context.Context, attachmentID int) {
+	attachment, err := p.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		p.logger.WithFields(logger.Fields{"attachment_id": attachmentID}).WithError(err).Error("Failed to load image for processing")
+		return
+	}
+	if attachment.ImageStatus != models.ImagePending {
+		return
+	}
+
+	if err := p.render(ctx, attachment); err != nil {
+		p.logger.WithFields(logger.Fields{"attachment_id": attachmentID}).WithError(err).Warn("Image processing failed")
+		attachment.ImageStatus = models.ImageFailed
+	}
+	if err := p.attachmentRepo.SaveImage(ctx, attachment); err != nil {
+		p.logger.WithFields(logger.Fields{"attachment_id": attachmentID}).WithError(err).Error("Failed to save processed image")
+		return
+	}
+
+	// Readers of a sent image learn its thumbnail from the room; an unsent one only concerns
+	// its uploader. Either way the event carries no URLs, which are signed per reader.
+	if attachment.MessageID != nil {
+		p.notifier.NotifyRoom(attachment.RoomID, EventAttachmentProcessed, attachment)
+	} else if attachment.UploaderID != nil {
+		p.notifier.NotifyUser(*attachment.UploaderID, EventAttachmentProcessed, attachment)
+	}
+}
+
+func (p *imageProcessor) render(ctx context.Context, attachment *models.Attachment) error {
+	content, err := p.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(content, attachment.Size))
+	content.Close()
+	if err != nil {
+		return err
+	}
+
+	result, err := imaging.Process(data, p.maxPixels, p.thumbnailSize)
+	if err != nil {
+		return err
+	}
+
+	// The thumbnail lives next to the original, so both are found from the attachment's key
+	thumbnailKey := attachment.StorageKey + ".thumb.jpg"
+	if err := p.storage.Put(ctx, thumbnailKey, bytes.NewReader(result.Thumbnail), int64(len(result.Thumbnail)), imaging.TypeJPEG); err != nil {
+		return err
+	}
+
+	attachment.ImageStatus = models.ImageReady
+	attachment.Width = result.Width
+	attachment.Height = result.Height
+	attachment.ThumbnailKey = thumbnailKey
+	attachment.ThumbnailWidth = result.ThumbnailWidth
+	attachment.ThumbnailHeight = result.ThumbnailHeight
+	attachment.Placeholder = result.Placeholder
+	return nil
+}
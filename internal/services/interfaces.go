@@ -3,6 +3,46 @@ package services
 import (
 	"chat_app/internal/models"
 	"context"
+	"io"
+	"time"
+)
+
+// Notifier pushes realtime events to the open connections of a user or a room
+type Notifier interface {
+	NotifyUser(userID int, event string, payload interface{})
+	// NotifyRoom pushes an event to every connection subscribed to a room
+	NotifyRoom(roomID int, event string, payload interface{})
+	// NotifyMessage pushes a newly stored message to its room, however it was sent
+	NotifyMessage(message *models.Message)
+	// RemoveFromRoom stops a user's live connections receiving a room they no longer belong to
+	RemoveFromRoom(userID, roomID int, reason string)
+}
+
+// Events sent through a Notifier
+const (
+	EventJoinRequestDecided = "join_request_decided"
+	EventSanctioned         = "sanctioned"
+	EventSanctionLifted     = "sanction_lifted"
+	EventMessageEdited      = "message_edited"
+	EventMessageDeleted     = "message_deleted"
+	EventReactionAdded      = "reaction_added"
+	EventReactionRemoved    = "reaction_removed"
+	EventThreadUpdated      = "thread_updated"
+	// EventMention carries a mention, with its message, to the user or room it addresses
+	EventMention = "mention"
+	// EventNotification carries a new notification to every session of its user
+	EventNotification = "notification"
+	// EventNotificationsRead tells a user's sessions that notifications were read elsewhere
+	EventNotificationsRead = "notifications_read"
+	// EventAttachmentProcessed carries an image attachment whose thumbnail is ready, or failed
+	EventAttachmentProcessed = "attachment_processed"
+)
+
+// Reasons given when a user is removed from a room
+const (
+	RemovedLeft   = "left"
+	RemovedKicked = "kicked"
+	RemovedBanned = "banned"
 )
 
 type AuthService interface {
@@ -13,6 +53,27 @@ type AuthService interface {
 	ValidateToken(ctx context.Context, token string) (*models.User, error)
 }
 
+// AuditService keeps the append-only log of moderation and administrative actions
+type AuditService interface {
+	Record(ctx context.Context, entry *models.AuditEntry)
+	GetRoomLog(ctx context.Context, roomID, userID int, filter models.AuditFilter) ([]*models.AuditEntry, error)
+	GetLog(ctx context.Context, userID int, filter models.AuditFilter) ([]*models.AuditEntry, error)
+}
+
+// NotificationService records what a user missed, such as mentions, replies and moderation,
+// and pushes it to their open sessions
+type NotificationService interface {
+	Notify(ctx context.Context, notification *models.Notification)
+	NotifyMembers(ctx context.Context, notification *models.Notification, exceptUserIDs []int)
+	GetNotifications(ctx context.Context, userID int, query models.NotificationQuery) (*models.NotificationList, error)
+	GetUnreadCount(ctx context.Context, userID int) (int, error)
+	MarkRead(ctx context.Context, userID, notificationID int) (*models.NotificationsRead, error)
+	MarkAllRead(ctx context.Context, userID int) (*models.NotificationsRead, error)
+	GetMutes(ctx context.Context, userID int) ([]*models.NotificationMute, error)
+	MuteRoom(ctx context.Context, roomID, userID int) error
+	UnmuteRoom(ctx context.Context, roomID, userID int) error
+}
+
 type UserService interface {
 	GetProfile(ctx context.Context, userID int) (*models.User, error)
 	UpdateProfile(ctx context.Context, userID int, updates map[string]interface{}) (*models.User, error)
@@ -33,22 +94,74 @@ type RoomService interface {
 	JoinRoom(ctx context.Context, roomID, userID int) error
 	LeaveRoom(ctx context.Context, roomID, userID int) error
 	GetRoomMembers(ctx context.Context, roomID int) ([]*models.User, error)
+	GetMemberRoles(ctx context.Context, roomID, userID int) ([]*models.RoomMember, error)
+	GetRoomPermissions(ctx context.Context, roomID, userID int) (*models.RoomPermissions, error)
+	RequirePermission(ctx context.Context, roomID, userID int, perm models.Permission) error
+	SetMemberRole(ctx context.Context, roomID, actorID, targetID int, role models.RoomRole) (*models.RoomMember, error)
+	InviteMember(ctx context.Context, roomID, actorID, targetID int) error
+	KickMember(ctx context.Context, roomID, actorID, targetID int, reason string) error
+	ResetRoom(ctx context.Context, roomID, actorID int) (*models.RoomReset, error)
+	TransferOwnership(ctx context.Context, roomID, ownerID, newOwnerID int) (*models.RoomMember, error)
+	LeaveAllRooms(ctx context.Context, userID int) error
+	RepairOwnerlessRooms(ctx context.Context) error
+	RequestToJoin(ctx context.Context, roomID, userID int, message string) (*models.JoinRequest, error)
+	GetJoinRequests(ctx context.Context, roomID, actorID int) ([]*models.JoinRequest, error)
+	DecideJoinRequest(ctx context.Context, roomID, requestID, actorID int, approve bool) (*models.JoinRequest, error)
+	BanMember(ctx context.Context, roomID, actorID, targetID int, reason string, expiresAt *time.Time) (*models.RoomSanction, error)
+	MuteMember(ctx context.Context, roomID, actorID, targetID int, reason string, expiresAt *time.Time) (*models.RoomSanction, error)
+	GetSanctions(ctx context.Context, roomID, actorID int, kind models.SanctionKind) ([]*models.RoomSanction, error)
+	LiftSanction(ctx context.Context, roomID, sanctionID, actorID int) error
+	GetReadState(ctx context.Context, roomID, userID int) (*models.RoomReadState, error)
+	GetUnreadCounts(ctx context.Context, userID int) ([]*models.RoomReadState, error)
+	MarkRead(ctx context.Context, roomID, userID int, seq int64) (*models.RoomReadState, error)
+}
+
+// ConversationService manages direct and group conversations. They are rooms without names,
+// so messages, history and realtime delivery go through the usual room paths by ID.
+type ConversationService interface {
+	OpenConversation(ctx context.Context, userID int, usernames []string) (*models.Conversation, error)
+	GetConversations(ctx context.Context, userID int) ([]*models.Conversation, error)
+	GetConversation(ctx context.Context, conversationID, userID int) (*models.Conversation, error)
+	LeaveConversation(ctx context.Context, conversationID, userID int) error
+}
+
+type InviteService interface {
+	CreateInvite(ctx context.Context, roomID, userID int, req *models.CreateInviteRequest) (*models.RoomInvite, error)
+	ListInvites(ctx context.Context, roomID, userID int) ([]*models.RoomInvite, error)
+	RevokeInvite(ctx context.Context, roomID, inviteID, userID int) error
+	RedeemInvite(ctx context.Context, code string, userID int) (*models.Room, error)
 }
 
 type MessageService interface {
 	SendMessage(ctx context.Context, userID int, req *models.SendMessageRequest) (*models.Message, error)
-	GetMessages(ctx context.Context, roomID int, limit, offset int) ([]*models.Message, error)
+	GetMessages(ctx context.Context, roomID, userID int, limit, offset int) ([]*models.Message, error)
 	GetRecentMessages(ctx context.Context, roomID int, limit int) ([]*models.Message, error)
+	GetMessagesAfter(ctx context.Context, roomID, userID int, afterSeq int64, limit int) ([]*models.Message, error)
+	GetMessagePage(ctx context.Context, roomID, userID int, query *models.MessagePageQuery) (*models.MessagePage, error)
 	EditMessage(ctx context.Context, messageID, userID int, content string) (*models.Message, error)
-	DeleteMessage(ctx context.Context, messageID, userID int) error
-	GetMessage(ctx context.Context, messageID int) (*models.Message, error)
+	DeleteMessage(ctx context.Context, messageID, userID int) (*models.Message, error)
+	GetMessage(ctx context.Context, messageID, userID int) (*models.Message, error)
+	GetThread(ctx context.Context, messageID, userID int, limit, offset int) (*models.Thread, error)
+	AddReaction(ctx context.Context, messageID, userID int, emoji string) (*models.ReactionUpdate, error)
+	RemoveReaction(ctx context.Context, messageID, userID int, emoji string) (*models.ReactionUpdate, error)
+	GetMentions(ctx context.Context, userID, beforeID, limit int) ([]*models.Mention, error)
+	GetRevisions(ctx context.Context, messageID, userID int) ([]*models.MessageRevision, error)
+}
+
+type AttachmentService interface {
+	Upload(ctx context.Context, roomID, userID int, upload *models.AttachmentUpload) (*models.Attachment, error)
+	GetAttachment(ctx context.Context, attachmentID, userID int) (*models.Attachment, error)
+	OpenAttachment(ctx context.Context, attachmentID, userID int, expires int64, signature string) (*models.Attachment, io.ReadCloser, error)
+	OpenThumbnail(ctx context.Context, attachmentID, userID int, expires int64, signature string) (*models.Attachment, io.ReadCloser, error)
+	AttachTo(ctx context.Context, messages []*models.Message, userID int) error
+}
+
+// ImageProcessor renders thumbnails and placeholders for uploaded images in the background
+type ImageProcessor interface {
+	Start(ctx context.Context)
+	Enqueue(attachmentID int)
 }
 
-type WebSocketService interface {
-	HandleConnection(ctx context.Context, conn interface{}, user *models.User) error
-	JoinRoom(ctx context.Context, userID int, roomName string) error
-	LeaveRoom(ctx context.Context, userID int, roomName string) error
-	BroadcastMessage(ctx context.Context, message *models.Message) error
-	BroadcastToRoom(ctx context.Context, roomName string, message *models.WebSocketMessage) error
-	GetConnectedUsers(ctx context.Context, roomName string) ([]*models.User, error)
+type SearchService interface {
+	SearchMessages(ctx context.Context, userID int, query models.MessageSearchQuery) (*models.SearchResults, error)
 }
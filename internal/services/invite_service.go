@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/errors"
+)
+
+// inviteCodeBytes is the entropy of an invite code; it is shared publicly, so it has to be
+// unguessable rather than short
+const inviteCodeBytes = 12
+
+type inviteService struct {
+	inviteRepo     repositories.InviteRepository
+	roomRepo       repositories.RoomRepository
+	roomMemberRepo repositories.RoomMemberRepository
+	sanctionRepo   repositories.SanctionRepository
+	audit          AuditService
+}
+
+func NewInviteService(inviteRepo repositories.InviteRepository, roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository, sanctionRepo repositories.SanctionRepository, audit AuditService) InviteService {
+	return &inviteService{
+		inviteRepo:     inviteRepo,
+		roomRepo:       roomRepo,
+		roomMemberRepo: roomMemberRepo,
+		sanctionRepo:   sanctionRepo,
+		audit:          audit,
+	}
+}
+
+// CreateInvite issues an invite code. Members who may invite can only hand out roles below
+// their own, so an invite never grants more than its creator has.
+func (s *inviteService) CreateInvite(ctx context.Context, roomID, userID int, req *models.CreateInviteRequest) (*models.RoomInvite, error) {
+	if _, err := s.roomRepo.GetByID(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	member, err := requirePermission(ctx, s.roomMemberRepo, roomID, userID, models.PermInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	if !role.IsValid() {
+		return nil, errors.NewValidationError("unknown role "+string(role), nil)
+	}
+	if !member.Role.Outranks(role) {
+		return nil, errors.NewForbiddenError("invites can only grant roles below your own", nil)
+	}
+	if req.MaxUses != nil && *req.MaxUses <= 0 {
+		return nil, errors.NewValidationError("max_uses must be positive", nil)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.NewValidationError("expires_at must be in the future", nil)
+	}
+
+	invite := &models.RoomInvite{
+		RoomID:    roomID,
+		Code:      generateInviteCode(),
+		CreatedBy: &userID,
+		Role:      role,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := s.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(userID),
+		Action:  models.AuditInviteCreate,
+		After:   snapshot(invite),
+	})
+
+	return invite, nil
+}
+
+// ListInvites returns every invite of a room, including spent and revoked ones, to members
+// who manage the room
+func (s *inviteService) ListInvites(ctx context.Context, roomID, userID int) ([]*models.RoomInvite, error) {
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, userID, models.PermManageSettings); err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.GetByRoomID(ctx, roomID)
+}
+
+// RevokeInvite disables an invite. Its creator may revoke it, as may anyone managing the room.
+func (s *inviteService) RevokeInvite(ctx context.Context, roomID, inviteID, userID int) error {
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		return err
+	}
+	if invite.RoomID != roomID {
+		return errors.NewNotFoundError("invite not found", nil)
+	}
+
+	isCreator := invite.CreatedBy != nil && *invite.CreatedBy == userID
+	perm := models.PermManageSettings
+	if isCreator {
+		perm = models.PermInvite
+	}
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, userID, perm); err != nil {
+		return err
+	}
+
+	if err := s.inviteRepo.Revoke(ctx, inviteID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(userID),
+		Action:  models.AuditInviteRevoke,
+		Before:  snapshot(invite),
+	})
+	return nil
+}
+
+// RedeemInvite joins the user to the invite's room with the invite's role
+func (s *inviteService) RedeemInvite(ctx context.Context, code string, userID int) (*models.Room, error) {
+	invite, err := s.inviteRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInviteUsable(invite, time.Now()); err != nil {
+		return nil, err
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, invite.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.roomMemberRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return nil, errors.NewConflictError("user is already a member", nil)
+	}
+	// An invite code does not get round a ban, and a refused use does not count against it
+	if err := requireNotBanned(ctx, s.sanctionRepo, room.ID, userID); err != nil {
+		return nil, err
+	}
+
+	member := &models.RoomMember{
+		RoomID: room.ID,
+		UserID: userID,
+		Role:   invite.Role,
+	}
+	if err := s.inviteRepo.Redeem(ctx, invite.ID, member); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:  intPtr(room.ID),
+		ActorID: intPtr(userID),
+		Action:  models.AuditInviteRedeem,
+		After:   snapshot(map[string]interface{}{"invite_id": invite.ID, "role": invite.Role}),
+	})
+
+	return room, nil
+}
+
+// checkInviteUsable gives a specific reason an invite cannot be redeemed; Redeem re-checks
+// the same limits atomically
+func checkInviteUsable(invite *models.RoomInvite, now time.Time) error {
+	switch {
+	case invite.RevokedAt != nil:
+		return errors.NewConflictError("invite has been revoked", nil)
+	case invite.ExpiresAt != nil && !invite.ExpiresAt.After(now):
+		return errors.NewConflictError("invite has expired", nil)
+	case invite.MaxUses != nil && invite.Uses >= *invite.MaxUses:
+		return errors.NewConflictError("invite has no uses left", nil)
+	}
+	return nil
+}
+
+func generateInviteCode() string {
+	bytes := make([]byte, inviteCodeBytes)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -3,9 +3,10 @@ package services
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
-	"chat_app/internal/config"
 	"chat_app/internal/models"
 	"chat_app/internal/repositories"
 	"chat_app/pkg/errors"
@@ -18,47 +19,94 @@ type messageService struct {
 	messageRepo    repositories.MessageRepository
 	roomRepo       repositories.RoomRepository
 	roomMemberRepo repositories.RoomMemberRepository
+	userRepo       repositories.UserRepository
+	sanctionRepo   repositories.SanctionRepository
+	reactionRepo   repositories.ReactionRepository
+	mentionRepo    repositories.MentionRepository
+	searchRepo     repositories.SearchRepository
+	attachments    AttachmentService
+	notifications  NotificationService
+	audit          AuditService
+	notifier       Notifier
 	cache          *redis.Client
 }
 
-func NewMessageService(messageRepo repositories.MessageRepository, roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository) MessageService {
-	cfg := config.Load()
-	redisClient := config.NewRedisClient(cfg.Redis)
+const recentMessagesCacheTTL = 30 * time.Second
+
+func NewMessageService(messageRepo repositories.MessageRepository, roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository, userRepo repositories.UserRepository, sanctionRepo repositories.SanctionRepository, reactionRepo repositories.ReactionRepository, mentionRepo repositories.MentionRepository, searchRepo repositories.SearchRepository, attachments AttachmentService, notifications NotificationService, audit AuditService, notifier Notifier, cache *redis.Client) MessageService {
 	return &messageService{
 		messageRepo:    messageRepo,
 		roomRepo:       roomRepo,
 		roomMemberRepo: roomMemberRepo,
-		cache:          redisClient,
+		userRepo:       userRepo,
+		sanctionRepo:   sanctionRepo,
+		reactionRepo:   reactionRepo,
+		mentionRepo:    mentionRepo,
+		searchRepo:     searchRepo,
+		attachments:    attachments,
+		notifications:  notifications,
+		audit:          audit,
+		notifier:       notifier,
+		cache:          cache,
 	}
 }
 
 func (s *messageService) SendMessage(ctx context.Context, userID int, req *models.SendMessageRequest) (*models.Message, error) {
-	// Get room by name
-	room, err := s.roomRepo.GetByName(ctx, req.Room)
+	var room *models.Room
+	var err error
+	switch {
+	case req.RoomID > 0:
+		room, err = s.roomRepo.GetByID(ctx, req.RoomID)
+	case req.Room != "":
+		room, err = s.roomRepo.GetByName(ctx, req.Room)
+	default:
+		return nil, errors.NewValidationError("room_id or room is required", nil)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if user is a member of the room
-	isMember, err := s.roomMemberRepo.IsMember(ctx, room.ID, userID)
+	// Check the user's role lets them post in the room
+	member, err := requirePermission(ctx, s.roomMemberRepo, room.ID, userID, models.PermPost)
 	if err != nil {
-		return nil, errors.NewDatabaseError("failed to check room membership", err)
+		return nil, err
 	}
-	if !isMember {
-		return nil, errors.NewForbiddenError("user is not a member of this room", nil)
+	if err := requireNotMuted(ctx, s.sanctionRepo, room.ID, userID); err != nil {
+		return nil, err
+	}
+
+	parentID, replyTo, err := s.threadParent(ctx, room.ID, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachmentIDs, err := messageAttachmentIDs(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get user for username (simplified - in production, get from context or user service)
-	// For now, we'll use a placeholder
-	username := "user" // This should come from user service or context
+	mentions, err := s.resolveMentions(ctx, member, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create message
 	message := &models.Message{
 		RoomID:   room.ID,
 		UserID:   userID,
-		Username: username,
+		Username: user.Username,
 		Content:  req.Content,
 		Type:     req.Type,
+		ParentID: parentID,
+		Mentions: mentions,
+
+		AttachmentIDs:   attachmentIDs,
+		AttachmentCount: len(attachmentIDs),
 	}
 
 	if message.Type == "" {
@@ -69,35 +117,550 @@ func (s *messageService) SendMessage(ctx context.Context, userID int, req *model
 	if err := s.messageRepo.Create(ctx, message); err != nil {
 		return nil, err
 	}
+	s.invalidateRecent(ctx, room.ID)
+	s.index(ctx, message)
+
+	// The new message goes out to the whole room, so its attachments carry no download URLs.
+	// Failing to load them only leaves them off this copy of the message.
+	_ = s.attachments.AttachTo(ctx, []*models.Message{message}, 0)
 
+	// Having written it, the author has read everything up to their own message. A failure
+	// here only leaves a stale unread count, so it does not fail the send.
+	_ = s.roomMemberRepo.MarkRead(ctx, room.ID, userID, message.Seq)
+
+	s.notifier.NotifyMessage(message)
+	if message.ParentID != nil {
+		s.threadUpdated(ctx, *message.ParentID)
+	}
+	s.notifyMentions(message)
+	s.recordNotifications(ctx, message, replyTo)
 	return message, nil
 }
 
-func (s *messageService) GetMessages(ctx context.Context, roomID int, limit, offset int) ([]*models.Message, error) {
+// threadUpdated pushes a thread's current summary to its room after a reply came or went. The
+// parent is read back after the change, so concurrent replies cannot leave subscribers with a
+// stale count for long. Failing to load it only delays the summary until the next reply.
+func (s *messageService) threadUpdated(ctx context.Context, parentID int) {
+	parent, err := s.messageRepo.GetByID(ctx, parentID)
+	if err != nil {
+		return
+	}
+
+	s.notifier.NotifyRoom(parent.RoomID, EventThreadUpdated, &models.ThreadUpdate{
+		RoomID:      parent.RoomID,
+		ParentID:    parent.ID,
+		ReplyCount:  parent.ReplyCount,
+		LastReplyAt: parent.LastReplyAt,
+	})
+}
+
+// resolveMentions turns the mentions in a new message's text into records. Only members of
+// the room can be mentioned; other names, and the author's own, stay plain text. Mentioning
+// @room takes the mention_room permission.
+func (s *messageService) resolveMentions(ctx context.Context, author *models.RoomMember, content string) ([]*models.Mention, error) {
+	parsed := models.ParseMentions(content)
+	if parsed.Empty() {
+		return nil, nil
+	}
+	if len(parsed.Usernames) > models.MaxMessageMentions {
+		return nil, errors.NewValidationError(fmt.Sprintf("a message may mention at most %d users", models.MaxMessageMentions), nil)
+	}
+	if parsed.Room && !author.Role.Can(models.PermMentionRoom) {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("role %s may not mention @room in this room", author.Role), nil)
+	}
+
+	var mentions []*models.Mention
+	if parsed.Room {
+		mentions = append(mentions, &models.Mention{Kind: models.MentionRoom})
+	}
+	if parsed.Here {
+		mentions = append(mentions, &models.Mention{Kind: models.MentionHere})
+	}
+
+	members, err := s.roomMemberRepo.GetMembersByUsernames(ctx, author.RoomID, parsed.Usernames)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.RoomMember, len(members))
+	for _, member := range members {
+		byName[strings.ToLower(member.Username)] = member
+	}
+	for _, name := range parsed.Usernames {
+		member, ok := byName[name]
+		if !ok || member.UserID == author.UserID {
+			continue
+		}
+		userID := member.UserID
+		mentions = append(mentions, &models.Mention{Kind: models.MentionUser, UserID: &userID, Username: member.Username})
+	}
+	return mentions, nil
+}
+
+// notifyMentions sends a mention event with the message to every session of each mentioned
+// user, whatever room they are looking at. @room and @here reach the room's subscribers.
+func (s *messageService) notifyMentions(message *models.Message) {
+	for _, mention := range message.Mentions {
+		// A copy carries the message, which itself lists the mention
+		notice := *mention
+		notice.Message = message
+		if mention.Kind == models.MentionUser {
+			s.notifier.NotifyUser(*mention.UserID, EventMention, &notice)
+		} else {
+			s.notifier.NotifyRoom(message.RoomID, EventMention, &notice)
+		}
+	}
+}
+
+// recordNotifications leaves a notification for everyone a new message mentions, and for the
+// author of the message it replies to. Each user gets one: @room covers every member, and a
+// mention covers a reply.
+func (s *messageService) recordNotifications(ctx context.Context, message *models.Message, replyTo *models.Message) {
+	notified := map[int]bool{message.UserID: true}
+	notification := func(kind models.NotificationKind, userID int) *models.Notification {
+		return &models.Notification{
+			UserID:    userID,
+			Kind:      kind,
+			RoomID:    intPtr(message.RoomID),
+			MessageID: intPtr(message.ID),
+			ActorID:   intPtr(message.UserID),
+		}
+	}
+
+	for _, mention := range message.Mentions {
+		if mention.Kind == models.MentionRoom {
+			s.notifications.NotifyMembers(ctx, notification(models.NotificationMention, 0), []int{message.UserID})
+			return
+		}
+	}
+	for _, mention := range message.Mentions {
+		if mention.Kind == models.MentionUser && !notified[*mention.UserID] {
+			notified[*mention.UserID] = true
+			s.notifications.Notify(ctx, notification(models.NotificationMention, *mention.UserID))
+		}
+	}
+	if replyTo != nil && !notified[replyTo.UserID] {
+		s.notifications.Notify(ctx, notification(models.NotificationReply, replyTo.UserID))
+	}
+}
+
+// GetMentions lists the mentions addressed to the user, newest first, each with its message
+func (s *messageService) GetMentions(ctx context.Context, userID, beforeID, limit int) ([]*models.Mention, error) {
+	mentions, err := s.mentionRepo.GetForUser(ctx, userID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(mentions))
+	for i, mention := range mentions {
+		ids[i] = mention.MessageID
+	}
+	messages, err := s.messageRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decorate(ctx, messages, userID); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*models.Message, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+	for _, mention := range mentions {
+		mention.Message = byID[mention.MessageID]
+	}
+	return mentions, nil
+}
+
+// messageAttachmentIDs checks the uploads a new message references, dropping repeats. A
+// message needs text, attachments or both.
+func messageAttachmentIDs(req *models.SendMessageRequest) ([]int, error) {
+	seen := make(map[int]bool, len(req.AttachmentIDs))
+	ids := make([]int, 0, len(req.AttachmentIDs))
+	for _, id := range req.AttachmentIDs {
+		if id <= 0 {
+			return nil, errors.NewValidationError("attachment IDs must be positive", nil)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) > models.MaxMessageAttachments {
+		return nil, errors.NewValidationError(fmt.Sprintf("a message may have at most %d attachments", models.MaxMessageAttachments), nil)
+	}
+	if len(ids) == 0 && strings.TrimSpace(req.Content) == "" {
+		return nil, errors.NewValidationError("content is required", nil)
+	}
+	return ids, nil
+}
+
+// threadParent resolves the message a reply belongs under, and returns the message it answers.
+// Threads are one level deep, so a reply to a reply joins the thread of the message it answers.
+func (s *messageService) threadParent(ctx context.Context, roomID int, parentID *int) (*int, *models.Message, error) {
+	if parentID == nil {
+		return nil, nil, nil
+	}
+
+	parent, err := s.messageRepo.GetByID(ctx, *parentID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, errors.NewValidationError("parent message not found", err)
+		}
+		return nil, nil, err
+	}
+	if parent.RoomID != roomID {
+		return nil, nil, errors.NewValidationError("parent message is in another room", nil)
+	}
+	if parent.DeletedAt != nil {
+		return nil, nil, errors.NewValidationError("parent message has been deleted", nil)
+	}
+
+	if parent.ParentID != nil {
+		return parent.ParentID, parent, nil
+	}
+	return &parent.ID, parent, nil
+}
+
+// GetThread returns a message with a page of its replies. Asking for a reply returns the
+// thread it belongs to.
+func (s *messageService) GetThread(ctx context.Context, messageID, userID int, limit, offset int) (*models.Thread, error) {
+	parent, err := s.GetMessage(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.ParentID != nil {
+		if parent, err = s.messageRepo.GetByID(ctx, *parent.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	replies, err := s.messageRepo.GetReplies(ctx, parent.ID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if replies == nil {
+		replies = []*models.Message{}
+	}
+
+	if err := s.decorate(ctx, append([]*models.Message{parent}, replies...), userID); err != nil {
+		return nil, err
+	}
+
+	return &models.Thread{Parent: parent, Replies: replies}, nil
+}
+
+func (s *messageService) GetMessages(ctx context.Context, roomID, userID int, limit, offset int) ([]*models.Message, error) {
 	// Check if room exists
 	_, err := s.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
 		return nil, err
 	}
 
-	if s.cache != nil && offset == 0 {
-		cacheKey := fmt.Sprintf("room:%d:messages:recent:%d", roomID, limit)
-		if data, err := s.cache.Get(ctx, cacheKey).Bytes(); err == nil && len(data) > 0 {
-			var msgs []*models.Message
-			if err := utils.MustUnmarshal(data, &msgs); err == nil {
-				return msgs, nil
-			}
+	if err := s.requireMember(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	var messages []*models.Message
+	if offset == 0 {
+		messages, err = s.cachedRecent(ctx, roomID, limit)
+	} else {
+		messages, err = s.messageRepo.GetByRoomID(ctx, roomID, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.decorate(ctx, messages, userID); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetMessagePage returns a keyset-paginated page of a room's timeline. Thread replies are
+// left to their threads, so jumping to a reply centers the page on the message it answers.
+func (s *messageService) GetMessagePage(ctx context.Context, roomID, userID int, query *models.MessagePageQuery) (*models.MessagePage, error) {
+	if _, err := s.roomRepo.GetByID(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	if err := s.requireMember(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	set := 0
+	for _, given := range []bool{query.Before != "", query.After != "", query.Around > 0} {
+		if given {
+			set++
 		}
+	}
+	if set > 1 {
+		return nil, errors.NewValidationError("only one of before, after and around may be given", nil)
+	}
 
-		msgs, err := s.messageRepo.GetByRoomID(ctx, roomID, limit, offset)
+	var page *models.MessagePage
+	var err error
+	switch {
+	case query.After != "":
+		page, err = s.pageAfter(ctx, roomID, query)
+	case query.Around > 0:
+		page, err = s.pageAround(ctx, roomID, query)
+	default:
+		page, err = s.pageBefore(ctx, roomID, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Messages == nil {
+		page.Messages = []*models.Message{}
+	}
+	if len(page.Messages) > 0 {
+		page.OlderCursor = models.EncodeMessageCursor(roomID, page.Messages[0].Seq)
+		page.NewerCursor = models.EncodeMessageCursor(roomID, page.Messages[len(page.Messages)-1].Seq)
+	}
+
+	if err := s.decorate(ctx, page.Messages, userID); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// pageBefore loads the messages before a cursor, or the newest ones. Like the other page
+// queries it asks for one message more than it returns, to learn whether the timeline goes
+// on in that direction without a separate count.
+func (s *messageService) pageBefore(ctx context.Context, roomID int, query *models.MessagePageQuery) (*models.MessagePage, error) {
+	beforeSeq := int64(math.MaxInt64)
+	if query.Before != "" {
+		seq, err := models.DecodeMessageCursor(roomID, query.Before)
 		if err != nil {
+			return nil, errors.NewValidationError("invalid before cursor", err)
+		}
+		beforeSeq = seq
+	}
+
+	messages, err := s.messageRepo.GetTimelineBefore(ctx, roomID, beforeSeq, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagePage{HasOlder: len(messages) > query.Limit, HasNewer: query.Before != ""}
+	if page.HasOlder {
+		messages = messages[1:]
+	}
+	page.Messages = messages
+	return page, nil
+}
+
+func (s *messageService) pageAfter(ctx context.Context, roomID int, query *models.MessagePageQuery) (*models.MessagePage, error) {
+	afterSeq, err := models.DecodeMessageCursor(roomID, query.After)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid after cursor", err)
+	}
+
+	messages, err := s.messageRepo.GetTimelineAfter(ctx, roomID, afterSeq, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagePage{HasOlder: true, HasNewer: len(messages) > query.Limit}
+	if page.HasNewer {
+		messages = messages[:query.Limit]
+	}
+	page.Messages = messages
+	return page, nil
+}
+
+// pageAround loads the anchor with the messages on either side of it, the older half first
+func (s *messageService) pageAround(ctx context.Context, roomID int, query *models.MessagePageQuery) (*models.MessagePage, error) {
+	anchor, err := s.messageRepo.GetByID(ctx, query.Around)
+	if err != nil {
+		return nil, err
+	}
+	if anchor.RoomID != roomID {
+		return nil, errors.NewNotFoundError("message not found in this room", nil)
+	}
+	if anchor.ParentID != nil {
+		if anchor, err = s.messageRepo.GetByID(ctx, *anchor.ParentID); err != nil {
 			return nil, err
 		}
-		_ = s.cache.Set(ctx, cacheKey, utils.MustMarshal(msgs), 30*time.Second).Err()
-		return msgs, nil
 	}
 
-	return s.messageRepo.GetByRoomID(ctx, roomID, limit, offset)
+	olderLimit := query.Limit / 2
+	newerLimit := query.Limit - olderLimit
+
+	older, err := s.messageRepo.GetTimelineBefore(ctx, roomID, anchor.Seq, olderLimit+1)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.messageRepo.GetTimelineAfter(ctx, roomID, anchor.Seq-1, newerLimit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagePage{
+		HasOlder: len(older) > olderLimit,
+		HasNewer: len(newer) > newerLimit,
+		AnchorID: anchor.ID,
+	}
+	if page.HasOlder {
+		older = older[1:]
+	}
+	if page.HasNewer {
+		newer = newer[:newerLimit]
+	}
+	page.Messages = append(older, newer...)
+	return page, nil
+}
+
+// GetMessagesAfter returns the messages a member missed since afterSeq, oldest first
+func (s *messageService) GetMessagesAfter(ctx context.Context, roomID, userID int, afterSeq int64, limit int) ([]*models.Message, error) {
+	if _, err := s.roomRepo.GetByID(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	if err := s.requireMember(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messageRepo.GetAfterSeq(ctx, roomID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.decorate(ctx, messages, userID); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// decorate adds what a message shows beyond its own row: its mentions, the reader's reactions
+// and download URLs signed for them. It runs after the shared recent-messages cache.
+func (s *messageService) decorate(ctx context.Context, messages []*models.Message, userID int) error {
+	if err := s.attachReactions(ctx, messages, userID); err != nil {
+		return err
+	}
+	if err := s.attachMentions(ctx, messages); err != nil {
+		return err
+	}
+	return s.attachments.AttachTo(ctx, messages, userID)
+}
+
+// attachMentions loads the mentions of a page of messages in a single query. Tombstones lose
+// theirs along with their content.
+func (s *messageService) attachMentions(ctx context.Context, messages []*models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	mentions, err := s.mentionRepo.GetByMessageIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		if message.DeletedAt == nil {
+			message.Mentions = mentions[message.ID]
+		}
+	}
+	return nil
+}
+
+// attachReactions loads the reactions of a page of messages in a single query
+func (s *messageService) attachReactions(ctx context.Context, messages []*models.Message, userID int) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	summaries, err := s.reactionRepo.GetSummaries(ctx, ids, userID)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		message.Reactions = summaries[message.ID]
+	}
+	return nil
+}
+
+// AddReaction adds the user's emoji to a message in a room they belong to and tells the room
+func (s *messageService) AddReaction(ctx context.Context, messageID, userID int, emoji string) (*models.ReactionUpdate, error) {
+	message, err := s.reactableMessage(ctx, messageID, userID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reactionRepo.Add(ctx, &models.Reaction{MessageID: messageID, UserID: userID, Emoji: emoji}); err != nil {
+		return nil, err
+	}
+
+	return s.reactionUpdate(ctx, message, userID, emoji, EventReactionAdded)
+}
+
+// RemoveReaction takes back the user's emoji from a message and tells the room
+func (s *messageService) RemoveReaction(ctx context.Context, messageID, userID int, emoji string) (*models.ReactionUpdate, error) {
+	message, err := s.reactableMessage(ctx, messageID, userID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reactionRepo.Remove(ctx, messageID, userID, emoji); err != nil {
+		return nil, err
+	}
+
+	return s.reactionUpdate(ctx, message, userID, emoji, EventReactionRemoved)
+}
+
+// reactableMessage checks the emoji and that the user may react in the message's room.
+// Muted members cannot react, as reacting is a way of posting.
+func (s *messageService) reactableMessage(ctx context.Context, messageID, userID int, emoji string) (*models.Message, error) {
+	if !models.ValidReaction(emoji) {
+		return nil, errors.NewValidationError("emoji must be a unicode emoji or a :short_code:", nil)
+	}
+
+	message, err := s.GetMessage(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return nil, errors.NewConflictError("message has been deleted", nil)
+	}
+	if err := requireNotMuted(ctx, s.sanctionRepo, message.RoomID, userID); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// reactionUpdate pushes the emoji's new count on the message to its room as event
+func (s *messageService) reactionUpdate(ctx context.Context, message *models.Message, userID int, emoji, event string) (*models.ReactionUpdate, error) {
+	count, err := s.reactionRepo.Count(ctx, message.ID, emoji)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &models.ReactionUpdate{
+		RoomID:    message.RoomID,
+		MessageID: message.ID,
+		UserID:    userID,
+		Username:  user.Username,
+		Emoji:     emoji,
+		Count:     count,
+	}
+	s.notifier.NotifyRoom(message.RoomID, event, update)
+	return update, nil
 }
 
 func (s *messageService) GetRecentMessages(ctx context.Context, roomID int, limit int) ([]*models.Message, error) {
@@ -107,64 +670,241 @@ func (s *messageService) GetRecentMessages(ctx context.Context, roomID int, limi
 		return nil, err
 	}
 
-	if s.cache != nil {
-		cacheKey := fmt.Sprintf("room:%d:messages:recent:%d", roomID, limit)
-		if data, err := s.cache.Get(ctx, cacheKey).Bytes(); err == nil && len(data) > 0 {
-			var msgs []*models.Message
-			if err := utils.MustUnmarshal(data, &msgs); err == nil {
-				return msgs, nil
-			}
-		}
+	return s.cachedRecent(ctx, roomID, limit)
+}
 
-		msgs, err := s.messageRepo.GetRecent(ctx, roomID, limit)
-		if err != nil {
-			return nil, err
+// cachedRecent serves the newest page of a room from Redis. All page sizes of a room live in
+// one hash so a single DEL invalidates them when the room's history changes.
+func (s *messageService) cachedRecent(ctx context.Context, roomID int, limit int) ([]*models.Message, error) {
+	if s.cache == nil {
+		return s.messageRepo.GetRecent(ctx, roomID, limit)
+	}
+
+	cacheKey := recentMessagesCacheKey(roomID)
+	field := fmt.Sprintf("%d", limit)
+	if data, err := s.cache.HGet(ctx, cacheKey, field).Bytes(); err == nil && len(data) > 0 {
+		var msgs []*models.Message
+		if err := utils.MustUnmarshal(data, &msgs); err == nil {
+			return msgs, nil
 		}
-		_ = s.cache.Set(ctx, cacheKey, utils.MustMarshal(msgs), 30*time.Second).Err()
-		return msgs, nil
 	}
 
-	return s.messageRepo.GetRecent(ctx, roomID, limit)
+	msgs, err := s.messageRepo.GetRecent(ctx, roomID, limit)
+	if err != nil {
+		return nil, err
+	}
+	pipe := s.cache.TxPipeline()
+	pipe.HSet(ctx, cacheKey, field, utils.MustMarshal(msgs))
+	pipe.Expire(ctx, cacheKey, recentMessagesCacheTTL)
+	_, _ = pipe.Exec(ctx)
+	return msgs, nil
+}
+
+func (s *messageService) invalidateRecent(ctx context.Context, roomID int) {
+	if s.cache != nil {
+		_ = s.cache.Del(ctx, recentMessagesCacheKey(roomID)).Err()
+	}
+}
+
+// index hands a message to search backends that keep their own index. A failure only leaves
+// the message unsearchable, so it does not fail the write.
+func (s *messageService) index(ctx context.Context, message *models.Message) {
+	_ = s.searchRepo.Index(ctx, &models.SearchDocument{
+		MessageID: message.ID,
+		RoomID:    message.RoomID,
+		UserID:    message.UserID,
+		Content:   message.Content,
+		CreatedAt: message.CreatedAt,
+
+		HasAttachment: message.AttachmentCount > 0,
+	})
+}
+
+func recentMessagesCacheKey(roomID int) string {
+	return fmt.Sprintf("room:%d:messages:recent", roomID)
 }
 
+func (s *messageService) requireMember(ctx context.Context, roomID, userID int) error {
+	isMember, err := s.roomMemberRepo.IsMember(ctx, roomID, userID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to check room membership", err)
+	}
+	if !isMember {
+		return errors.NewForbiddenError("user is not a member of this room", nil)
+	}
+	return nil
+}
+
+// EditMessage replaces a message's text. Both the REST and WebSocket paths come through here,
+// so this is where blank content is turned away.
 func (s *messageService) EditMessage(ctx context.Context, messageID, userID int, content string) (*models.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errors.NewValidationError("content is required", nil)
+	}
+
 	// Get message
 	message, err := s.messageRepo.GetByID(ctx, messageID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if user is the message author
+	if err := s.requireActiveMember(ctx, message.RoomID, userID); err != nil {
+		return nil, err
+	}
+
+	// Authors may edit their own messages unless muted; others need the edit_others permission
 	if message.UserID != userID {
-		return nil, errors.NewForbiddenError("only message author can edit message", nil)
+		if err := s.requireOutranksAuthor(ctx, message, userID, models.PermEditOthers); err != nil {
+			return nil, err
+		}
+	} else if err := requireNotMuted(ctx, s.sanctionRepo, message.RoomID, userID); err != nil {
+		return nil, err
 	}
 
-	// Update message
+	// Update message; the repository keeps the previous content as a revision
+	before := *message
 	message.Content = content
-	message.UpdatedAt = time.Now()
 
-	if err := s.messageRepo.Update(ctx, message); err != nil {
+	if err := s.messageRepo.Edit(ctx, message, userID); err != nil {
 		return nil, err
 	}
+	s.invalidateRecent(ctx, message.RoomID)
+	s.index(ctx, message)
+	_ = s.attachments.AttachTo(ctx, []*models.Message{message}, 0)
+	s.notifier.NotifyRoom(message.RoomID, EventMessageEdited, message)
+
+	// Authors editing their own messages is not moderation, so only others' edits are audited
+	if message.UserID != userID {
+		s.audit.Record(ctx, &models.AuditEntry{
+			RoomID:          intPtr(message.RoomID),
+			ActorID:         intPtr(userID),
+			Action:          models.AuditMessageEdit,
+			TargetUserID:    intPtr(message.UserID),
+			TargetUsername:  message.Username,
+			TargetMessageID: intPtr(message.ID),
+			Before:          snapshot(before),
+			After:           snapshot(message),
+		})
+	}
 
 	return message, nil
 }
 
-func (s *messageService) DeleteMessage(ctx context.Context, messageID, userID int) error {
+// requireActiveMember checks a user changing a message still belongs to its room and is not
+// banned from it, so leaving or being removed also ends their hold on what they wrote there
+func (s *messageService) requireActiveMember(ctx context.Context, roomID, userID int) error {
+	if _, err := roomMembership(ctx, s.roomMemberRepo, roomID, userID); err != nil {
+		return err
+	}
+	return requireNotBanned(ctx, s.sanctionRepo, roomID, userID)
+}
+
+// requireOutranksAuthor checks a user may change someone else's message: their role must grant
+// perm and rank above the author's. An author who has left the room has no role left to protect.
+func (s *messageService) requireOutranksAuthor(ctx context.Context, message *models.Message, userID int, perm models.Permission) error {
+	actor, err := requirePermission(ctx, s.roomMemberRepo, message.RoomID, userID, perm)
+	if err != nil {
+		return err
+	}
+
+	author, err := s.roomMemberRepo.GetMember(ctx, message.RoomID, message.UserID)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if author != nil && !actor.Role.Outranks(author.Role) {
+		return errors.NewForbiddenError("you can only change messages of members below your role", nil)
+	}
+	return nil
+}
+
+// DeleteMessage leaves a tombstone in place of the message and returns it
+func (s *messageService) DeleteMessage(ctx context.Context, messageID, userID int) (*models.Message, error) {
 	// Get message
 	message, err := s.messageRepo.GetByID(ctx, messageID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if user is the message author
+	if err := s.requireActiveMember(ctx, message.RoomID, userID); err != nil {
+		return nil, err
+	}
+
+	// Authors may delete their own messages; others need the delete_others permission
 	if message.UserID != userID {
-		return errors.NewForbiddenError("only message author can delete message", nil)
+		if err := s.requireOutranksAuthor(ctx, message, userID, models.PermDeleteOthers); err != nil {
+			return nil, err
+		}
+	}
+
+	before := *message
+	if err := s.messageRepo.Delete(ctx, message, userID); err != nil {
+		return nil, err
+	}
+	s.invalidateRecent(ctx, message.RoomID)
+	_ = s.searchRepo.Remove(ctx, message.ID)
+	s.notifier.NotifyRoom(message.RoomID, EventMessageDeleted, &models.MessageDeleted{
+		MessageID: message.ID,
+		RoomID:    message.RoomID,
+		DeletedAt: *message.DeletedAt,
+	})
+	if message.ParentID != nil {
+		s.threadUpdated(ctx, *message.ParentID)
+	}
+
+	if message.UserID != userID {
+		s.audit.Record(ctx, &models.AuditEntry{
+			RoomID:          intPtr(message.RoomID),
+			ActorID:         intPtr(userID),
+			Action:          models.AuditMessageDelete,
+			TargetUserID:    intPtr(message.UserID),
+			TargetUsername:  message.Username,
+			TargetMessageID: intPtr(message.ID),
+			Before:          snapshot(before),
+		})
+		s.notifications.Notify(ctx, &models.Notification{
+			UserID:    message.UserID,
+			Kind:      models.NotificationMessageRemoved,
+			RoomID:    intPtr(message.RoomID),
+			MessageID: intPtr(message.ID),
+			ActorID:   intPtr(userID),
+		})
+	}
+
+	return message, nil
+}
+
+// GetRevisions returns what a message said before each edit and before its deletion. It is
+// for moderators, to review messages whose current content no longer shows what was said.
+func (s *messageService) GetRevisions(ctx context.Context, messageID, userID int) ([]*models.MessageRevision, error) {
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.messageRepo.Delete(ctx, messageID)
+	if _, err := requirePermission(ctx, s.roomMemberRepo, message.RoomID, userID, models.PermViewRevisions); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.messageRepo.GetRevisions(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if revisions == nil {
+		revisions = []*models.MessageRevision{}
+	}
+	return revisions, nil
 }
 
-func (s *messageService) GetMessage(ctx context.Context, messageID int) (*models.Message, error) {
-	return s.messageRepo.GetByID(ctx, messageID)
+func (s *messageService) GetMessage(ctx context.Context, messageID, userID int) (*models.Message, error) {
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.requireMember(ctx, message.RoomID, userID); err != nil {
+		return nil, err
+	}
+
+	return message, nil
 }
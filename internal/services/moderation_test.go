@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRoom    = 1
+	testActor   = 1
+	testAuthor  = 2
+	testMessage = 10
+)
+
+func TestChangingOthersMessagesFollowsRoleAndRank(t *testing.T) {
+	// author "" is someone who has since left the room
+	cases := []struct {
+		actor, author models.RoomRole
+		edit, delete  bool
+	}{
+		{models.RoleOwner, models.RoleAdmin, true, true},
+		{models.RoleOwner, models.RoleMember, true, true},
+		{models.RoleAdmin, models.RoleModerator, true, true},
+		{models.RoleAdmin, models.RoleAdmin, false, false},
+		{models.RoleAdmin, models.RoleOwner, false, false},
+		{models.RoleModerator, models.RoleMember, false, true},
+		{models.RoleModerator, models.RoleGuest, false, true},
+		{models.RoleModerator, models.RoleModerator, false, false},
+		{models.RoleModerator, models.RoleAdmin, false, false},
+		{models.RoleMember, models.RoleGuest, false, false},
+		{models.RoleGuest, models.RoleMember, false, false},
+		{models.RoleAdmin, "", true, true},
+		{models.RoleModerator, "", false, true},
+		{models.RoleMember, "", false, false},
+	}
+
+	for _, tc := range cases {
+		author := string(tc.author)
+		if author == "" {
+			author = "former member"
+		}
+		t.Run(string(tc.actor)+" on "+author, func(t *testing.T) {
+			for action, allowed := range map[string]bool{"edit": tc.edit, "delete": tc.delete} {
+				f := newFixture()
+				f.members.add(testRoom, testActor, tc.actor)
+				if tc.author != "" {
+					f.members.add(testRoom, testAuthor, tc.author)
+				}
+				f.addMessage(testMessage, testRoom, testAuthor, "hello")
+
+				var err error
+				if action == "edit" {
+					_, err = f.messageService.EditMessage(context.Background(), testMessage, testActor, "moderated")
+				} else {
+					_, err = f.messageService.DeleteMessage(context.Background(), testMessage, testActor)
+				}
+
+				if allowed {
+					assert.NoError(t, err, action)
+					assert.Len(t, f.audit.entries, 1, action)
+				} else {
+					assert.Equal(t, errors.ErrCodeForbidden, errorCode(err), action)
+					assert.Equal(t, "hello", f.messages.messages[testMessage].Content, action)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorsChangingTheirOwnMessages(t *testing.T) {
+	cases := []struct {
+		name         string
+		setup        func(f *fixture)
+		edit, delete errors.ErrorCode
+	}{
+		{
+			name: "member",
+		},
+		{
+			name:  "guest",
+			setup: func(f *fixture) { f.members.add(testRoom, testAuthor, models.RoleGuest) },
+		},
+		{
+			name: "muted",
+			setup: func(f *fixture) {
+				f.sanctions.sanctions = append(f.sanctions.sanctions, &models.RoomSanction{RoomID: testRoom, UserID: testAuthor, Kind: models.SanctionMute})
+			},
+			edit: errors.ErrCodeForbidden,
+		},
+		{
+			name: "banned",
+			setup: func(f *fixture) {
+				f.sanctions.sanctions = append(f.sanctions.sanctions, &models.RoomSanction{RoomID: testRoom, UserID: testAuthor, Kind: models.SanctionBan})
+			},
+			edit:   errors.ErrCodeForbidden,
+			delete: errors.ErrCodeForbidden,
+		},
+		{
+			name:   "left the room",
+			setup:  func(f *fixture) { delete(f.members.members, memberKey{testRoom, testAuthor}) },
+			edit:   errors.ErrCodeForbidden,
+			delete: errors.ErrCodeForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for action, want := range map[string]errors.ErrorCode{"edit": tc.edit, "delete": tc.delete} {
+				f := newFixture()
+				f.members.add(testRoom, testAuthor, models.RoleMember)
+				if tc.setup != nil {
+					tc.setup(f)
+				}
+				f.addMessage(testMessage, testRoom, testAuthor, "hello")
+
+				var err error
+				if action == "edit" {
+					_, err = f.messageService.EditMessage(context.Background(), testMessage, testAuthor, "hello again")
+				} else {
+					_, err = f.messageService.DeleteMessage(context.Background(), testMessage, testAuthor)
+				}
+
+				if want == "" {
+					assert.NoError(t, err, action)
+				} else {
+					assert.Equal(t, want, errorCode(err), action)
+				}
+				// Authors changing their own messages is not moderation
+				assert.Empty(t, f.audit.entries, action)
+			}
+		})
+	}
+}
+
+func TestEditMessageRejectsBlankContent(t *testing.T) {
+	f := newFixture()
+	f.members.add(testRoom, testAuthor, models.RoleMember)
+	f.addMessage(testMessage, testRoom, testAuthor, "hello")
+
+	_, err := f.messageService.EditMessage(context.Background(), testMessage, testAuthor, " \n\t ")
+	assert.Equal(t, errors.ErrCodeValidationError, errorCode(err))
+
+	message, err := f.messageService.EditMessage(context.Background(), testMessage, testAuthor, "  trimmed  ")
+	require.NoError(t, err)
+	assert.Equal(t, "trimmed", message.Content)
+}
+
+func TestRemovingMembersRequiresPermissionAndRank(t *testing.T) {
+	cases := []struct {
+		actor, target   models.RoomRole
+		kick, mute, ban bool
+	}{
+		{models.RoleOwner, models.RoleAdmin, true, true, true},
+		{models.RoleAdmin, models.RoleModerator, true, true, true},
+		{models.RoleAdmin, models.RoleAdmin, false, false, false},
+		{models.RoleModerator, models.RoleMember, true, true, false},
+		{models.RoleModerator, models.RoleModerator, false, false, false},
+		{models.RoleModerator, models.RoleOwner, false, false, false},
+		{models.RoleMember, models.RoleGuest, false, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.actor)+" on "+string(tc.target), func(t *testing.T) {
+			actions := map[string]struct {
+				allowed bool
+				do      func(f *fixture) error
+			}{
+				"kick": {tc.kick, func(f *fixture) error {
+					return f.roomService.KickMember(context.Background(), testRoom, testActor, testAuthor, "")
+				}},
+				"mute": {tc.mute, func(f *fixture) error {
+					_, err := f.roomService.MuteMember(context.Background(), testRoom, testActor, testAuthor, "", nil)
+					return err
+				}},
+				"ban": {tc.ban, func(f *fixture) error {
+					_, err := f.roomService.BanMember(context.Background(), testRoom, testActor, testAuthor, "", nil)
+					return err
+				}},
+			}
+
+			for name, action := range actions {
+				f := newFixture()
+				f.members.add(testRoom, testActor, tc.actor)
+				f.members.add(testRoom, testAuthor, tc.target)
+
+				err := action.do(f)
+				if action.allowed {
+					assert.NoError(t, err, name)
+				} else {
+					assert.Equal(t, errors.ErrCodeForbidden, errorCode(err), name)
+					assert.Empty(t, f.sanctions.sanctions, name)
+					assert.Empty(t, f.notifier.removed, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSanctionsTakeEffect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a muted member keeps the room but cannot edit", func(t *testing.T) {
+		f := newFixture()
+		f.members.add(testRoom, testActor, models.RoleModerator)
+		f.members.add(testRoom, testAuthor, models.RoleMember)
+		f.addMessage(testMessage, testRoom, testAuthor, "hello")
+
+		until := time.Now().Add(time.Hour)
+		sanction, err := f.roomService.MuteMember(ctx, testRoom, testActor, testAuthor, "spam", &until)
+		require.NoError(t, err)
+		assert.Equal(t, models.SanctionMute, sanction.Kind)
+		assert.Empty(t, f.notifier.removed)
+
+		_, err = f.messageService.EditMessage(ctx, testMessage, testAuthor, "more spam")
+		assert.Equal(t, errors.ErrCodeForbidden, errorCode(err))
+		_, err = f.messageService.DeleteMessage(ctx, testMessage, testAuthor)
+		assert.NoError(t, err)
+	})
+
+	t.Run("a banned member is removed and can no longer change their messages", func(t *testing.T) {
+		f := newFixture()
+		f.members.add(testRoom, testActor, models.RoleAdmin)
+		f.members.add(testRoom, testAuthor, models.RoleMember)
+		f.addMessage(testMessage, testRoom, testAuthor, "hello")
+
+		_, err := f.roomService.BanMember(ctx, testRoom, testActor, testAuthor, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []memberKey{{testRoom, testAuthor}}, f.notifier.removed)
+
+		_, err = f.messageService.EditMessage(ctx, testMessage, testAuthor, "let me back")
+		assert.Equal(t, errors.ErrCodeForbidden, errorCode(err))
+		_, err = f.messageService.DeleteMessage(ctx, testMessage, testAuthor)
+		assert.Equal(t, errors.ErrCodeForbidden, errorCode(err))
+	})
+
+	t.Run("non-members can be banned but not muted", func(t *testing.T) {
+		f := newFixture()
+		f.members.add(testRoom, testActor, models.RoleAdmin)
+
+		_, err := f.roomService.BanMember(ctx, testRoom, testActor, testAuthor, "", nil)
+		assert.NoError(t, err)
+		_, err = f.roomService.MuteMember(ctx, testRoom, testActor, testAuthor, "", nil)
+		assert.Equal(t, errors.ErrCodeNotFound, errorCode(err))
+	})
+
+	t.Run("nobody sanctions themselves", func(t *testing.T) {
+		f := newFixture()
+		f.members.add(testRoom, testActor, models.RoleOwner)
+
+		_, err := f.roomService.BanMember(ctx, testRoom, testActor, testActor, "", nil)
+		assert.Equal(t, errors.ErrCodeValidationError, errorCode(err))
+	})
+}
+
+func TestResetRoomReportsMembersItCouldNotRemove(t *testing.T) {
+	f := newFixture()
+	f.members.add(testRoom, testActor, models.RoleAdmin)
+	f.members.add(testRoom, 2, models.RoleMember)
+	f.members.add(testRoom, 3, models.RoleModerator)
+	f.members.add(testRoom, 4, models.RoleOwner)
+	f.members.add(testRoom, 5, models.RoleGuest)
+	f.members.failRemove[3] = true
+
+	reset, err := f.roomService.ResetRoom(context.Background(), testRoom, testActor)
+	require.NoError(t, err)
+	assert.Equal(t, &models.RoomReset{Removed: 2, Failed: 1}, reset)
+	assert.Len(t, f.audit.entries, 2)
+
+	for _, user := range []int{testActor, 3, 4} {
+		_, stillMember := f.members.members[memberKey{testRoom, user}]
+		assert.True(t, stillMember, "user %d", user)
+	}
+}
+
+func TestApprovingJoinRequestAddsTheRequester(t *testing.T) {
+	const testRequest = 5
+	cases := []struct {
+		name        string
+		setup       func(f *fixture)
+		want        errors.ErrorCode
+		status      models.JoinRequestStatus
+		member      bool
+		requesterAs models.RoomRole
+	}{
+		{
+			name:   "approved",
+			status: models.JoinRequestApproved,
+			member: true,
+		},
+		{
+			name:   "adding fails",
+			setup:  func(f *fixture) { f.requests.failApprove = true },
+			want:   errors.ErrCodeDatabaseError,
+			status: models.JoinRequestPending,
+		},
+		{
+			name: "banned",
+			setup: func(f *fixture) {
+				f.sanctions.sanctions = append(f.sanctions.sanctions, &models.RoomSanction{RoomID: testRoom, UserID: testAuthor, Kind: models.SanctionBan})
+			},
+			want:   errors.ErrCodeForbidden,
+			status: models.JoinRequestPending,
+		},
+		{
+			name:        "already a member",
+			setup:       func(f *fixture) { f.members.add(testRoom, testAuthor, models.RoleModerator) },
+			status:      models.JoinRequestApproved,
+			member:      true,
+			requesterAs: models.RoleModerator,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture()
+			f.members.add(testRoom, testActor, models.RoleAdmin)
+			f.requests.requests[testRequest] = &models.JoinRequest{ID: testRequest, RoomID: testRoom, UserID: testAuthor, Status: models.JoinRequestPending}
+			if tc.setup != nil {
+				tc.setup(f)
+			}
+
+			_, err := f.roomService.DecideJoinRequest(context.Background(), testRoom, testRequest, testActor, true)
+			if tc.want == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tc.want, errorCode(err))
+			}
+			assert.Equal(t, tc.status, f.requests.requests[testRequest].Status)
+
+			member, isMember := f.members.members[memberKey{testRoom, testAuthor}]
+			assert.Equal(t, tc.member, isMember)
+			if tc.requesterAs != "" {
+				assert.Equal(t, tc.requesterAs, member.Role)
+			}
+		})
+	}
+}
+
+func TestRepairOwnerlessRoomsPromotesTheSuccessor(t *testing.T) {
+	const otherRoom, guestRoom = 2, 3
+	f := newFixture()
+	// The owner's row was deleted along with their account
+	f.members.add(testRoom, 2, models.RoleMember)
+	f.members.add(testRoom, 3, models.RoleAdmin)
+	f.members.add(testRoom, 4, models.RoleGuest)
+	f.members.add(otherRoom, 2, models.RoleOwner)
+	f.members.add(otherRoom, 3, models.RoleAdmin)
+	f.members.add(guestRoom, 4, models.RoleGuest)
+
+	require.NoError(t, f.roomService.RepairOwnerlessRooms(context.Background()))
+
+	assert.Equal(t, models.RoleOwner, f.members.members[memberKey{testRoom, 3}].Role)
+	assert.Equal(t, models.RoleMember, f.members.members[memberKey{testRoom, 2}].Role)
+	assert.Equal(t, models.RoleAdmin, f.members.members[memberKey{otherRoom, 3}].Role)
+	assert.Equal(t, models.RoleGuest, f.members.members[memberKey{guestRoom, 4}].Role)
+	require.Len(t, f.audit.entries, 1)
+	assert.Equal(t, models.AuditOwnershipTransfer, f.audit.entries[0].Action)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/logger"
+)
+
+type notificationService struct {
+	notificationRepo repositories.NotificationRepository
+	roomMemberRepo   repositories.RoomMemberRepository
+	notifier         Notifier
+	logger           *logger.Logger
+}
+
+func NewNotificationService(notificationRepo repositories.NotificationRepository, roomMemberRepo repositories.RoomMemberRepository, notifier Notifier, logger *logger.Logger) NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		roomMemberRepo:   roomMemberRepo,
+		notifier:         notifier,
+		logger:           logger,
+	}
+}
+
+// Notify records a notification for its user and pushes it to their open sessions. Room
+// activity only reaches current members who have not muted the room, and nobody is notified
+// of their own actions. Like the audit log, it runs after the action it reports, so a failed
+// write is logged rather than failing the request.
+func (s *notificationService) Notify(ctx context.Context, notification *models.Notification) {
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return
+	}
+	if notification.Kind.IsRoomActivity() && notification.RoomID != nil {
+		wanted, err := s.wantsRoomActivity(ctx, notification.UserID, *notification.RoomID)
+		if err != nil {
+			s.logFailure(notification, err)
+			return
+		}
+		if !wanted {
+			return
+		}
+	}
+
+	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+		s.logFailure(notification, err)
+		return
+	}
+
+	// Reload for the names and excerpt the list shows; the bare record will do if that fails
+	if stored, err := s.notificationRepo.GetByID(ctx, notification.ID); err == nil {
+		notification = stored
+	}
+	s.notifier.NotifyUser(notification.UserID, EventNotification, notification)
+}
+
+// NotifyMembers records a notification about a message for every member of its room who has
+// not muted it, except the users listed, and pushes each their copy. A message must not be
+// given other notifications of the same kind, which would be pushed again.
+func (s *notificationService) NotifyMembers(ctx context.Context, notification *models.Notification, exceptUserIDs []int) {
+	if notification.RoomID == nil || notification.MessageID == nil {
+		return
+	}
+
+	created, err := s.notificationRepo.CreateForMembers(ctx, notification, exceptUserIDs)
+	if err != nil {
+		s.logFailure(notification, err)
+		return
+	}
+	if created == 0 {
+		return
+	}
+
+	notifications, err := s.notificationRepo.GetByMessage(ctx, *notification.MessageID, notification.Kind)
+	if err != nil {
+		s.logFailure(notification, err)
+		return
+	}
+	for _, n := range notifications {
+		s.notifier.NotifyUser(n.UserID, EventNotification, n)
+	}
+}
+
+func (s *notificationService) wantsRoomActivity(ctx context.Context, userID, roomID int) (bool, error) {
+	member, err := s.roomMemberRepo.IsMember(ctx, roomID, userID)
+	if err != nil || !member {
+		return false, err
+	}
+	muted, err := s.notificationRepo.IsMuted(ctx, userID, roomID)
+	if err != nil {
+		return false, err
+	}
+	return !muted, nil
+}
+
+func (s *notificationService) logFailure(notification *models.Notification, err error) {
+	s.logger.WithFields(logger.Fields{
+		"kind":    notification.Kind,
+		"user_id": notification.UserID,
+		"room_id": notification.RoomID,
+	}).WithError(err).Error("Failed to record notification")
+}
+
+// GetNotifications returns a page of the user's notifications with their unread count
+func (s *notificationService) GetNotifications(ctx context.Context, userID int, query models.NotificationQuery) (*models.NotificationList, error) {
+	notifications, err := s.notificationRepo.List(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NotificationList{Notifications: notifications, UnreadCount: unread}, nil
+}
+
+func (s *notificationService) GetUnreadCount(ctx context.Context, userID int) (int, error) {
+	return s.notificationRepo.CountUnread(ctx, userID)
+}
+
+// MarkRead marks one of the user's notifications read and tells their other sessions
+func (s *notificationService) MarkRead(ctx context.Context, userID, notificationID int) (*models.NotificationsRead, error) {
+	if err := s.notificationRepo.MarkRead(ctx, userID, notificationID); err != nil {
+		return nil, err
+	}
+	return s.announceRead(ctx, userID, notificationID)
+}
+
+// MarkAllRead marks all of the user's notifications read and tells their other sessions
+func (s *notificationService) MarkAllRead(ctx context.Context, userID int) (*models.NotificationsRead, error) {
+	if _, err := s.notificationRepo.MarkAllRead(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.announceRead(ctx, userID, 0)
+}
+
+func (s *notificationService) announceRead(ctx context.Context, userID, notificationID int) (*models.NotificationsRead, error) {
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	read := &models.NotificationsRead{NotificationID: notificationID, UnreadCount: unread}
+	s.notifier.NotifyUser(userID, EventNotificationsRead, read)
+	return read, nil
+}
+
+func (s *notificationService) GetMutes(ctx context.Context, userID int) ([]*models.NotificationMute, error) {
+	return s.notificationRepo.GetMutes(ctx, userID)
+}
+
+// MuteRoom silences mentions and replies in a room the user belongs to. The mute outlasts
+// their membership, so it still holds if they rejoin.
+func (s *notificationService) MuteRoom(ctx context.Context, roomID, userID int) error {
+	if _, err := roomMembership(ctx, s.roomMemberRepo, roomID, userID); err != nil {
+		return err
+	}
+	return s.notificationRepo.Mute(ctx, userID, roomID)
+}
+
+func (s *notificationService) UnmuteRoom(ctx context.Context, roomID, userID int) error {
+	return s.notificationRepo.Unmute(ctx, userID, roomID)
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/errors"
+)
+
+// roomMembership loads a user's membership, turning "not a member" into a Forbidden error
+func roomMembership(ctx context.Context, repo repositories.RoomMemberRepository, roomID, userID int) (*models.RoomMember, error) {
+	member, err := repo.GetMember(ctx, roomID, userID)
+	if errors.IsNotFound(err) {
+		return nil, errors.NewForbiddenError("user is not a member of this room", nil)
+	}
+	return member, err
+}
+
+// requireNotBanned rejects a user with an active ban from the room
+func requireNotBanned(ctx context.Context, repo repositories.SanctionRepository, roomID, userID int) error {
+	ban, err := repo.GetActive(ctx, roomID, userID, models.SanctionBan)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return errors.NewForbiddenError("user is banned from this room"+sanctionUntil(ban), nil)
+}
+
+// requireNotMuted rejects a user with an active mute in the room
+func requireNotMuted(ctx context.Context, repo repositories.SanctionRepository, roomID, userID int) error {
+	mute, err := repo.GetActive(ctx, roomID, userID, models.SanctionMute)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return errors.NewForbiddenError("user is muted in this room"+sanctionUntil(mute), nil)
+}
+
+func sanctionUntil(sanction *models.RoomSanction) string {
+	if sanction.ExpiresAt == nil {
+		return ""
+	}
+	return " until " + sanction.ExpiresAt.UTC().Format(time.RFC3339)
+}
+
+// requirePermission checks the permission matrix for the user's role in a room
+func requirePermission(ctx context.Context, repo repositories.RoomMemberRepository, roomID, userID int, perm models.Permission) (*models.RoomMember, error) {
+	member, err := roomMembership(ctx, repo, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member.Role.Can(perm) {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("role %s may not %s in this room", member.Role, perm), nil)
+	}
+	return member, nil
+}
@@ -2,22 +2,36 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"chat_app/internal/models"
 	"chat_app/internal/repositories"
 	"chat_app/pkg/errors"
+	"chat_app/pkg/logger"
 )
 
 type roomService struct {
-	roomRepo       repositories.RoomRepository
-	roomMemberRepo repositories.RoomMemberRepository
+	roomRepo        repositories.RoomRepository
+	roomMemberRepo  repositories.RoomMemberRepository
+	joinRequestRepo repositories.JoinRequestRepository
+	sanctionRepo    repositories.SanctionRepository
+	notifications   NotificationService
+	audit           AuditService
+	notifier        Notifier
+	logger          *logger.Logger
 }
 
-func NewRoomService(roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository) RoomService {
+func NewRoomService(roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository, joinRequestRepo repositories.JoinRequestRepository, sanctionRepo repositories.SanctionRepository, notifications NotificationService, audit AuditService, notifier Notifier, logger *logger.Logger) RoomService {
 	return &roomService{
-		roomRepo:       roomRepo,
-		roomMemberRepo: roomMemberRepo,
+		roomRepo:        roomRepo,
+		roomMemberRepo:  roomMemberRepo,
+		joinRequestRepo: joinRequestRepo,
+		sanctionRepo:    sanctionRepo,
+		notifications:   notifications,
+		audit:           audit,
+		notifier:        notifier,
+		logger:          logger,
 	}
 }
 
@@ -36,17 +50,18 @@ func (s *roomService) CreateRoom(ctx context.Context, userID int, name, descript
 		Name:        name,
 		Description: description,
 		IsPrivate:   isPrivate,
-		CreatedBy:   userID,
+		CreatedBy:   &userID,
 	}
 
 	if err := s.roomRepo.Create(ctx, room); err != nil {
 		return nil, err
 	}
 
-	// Add creator as member
+	// Add creator as owner
 	member := &models.RoomMember{
 		RoomID: room.ID,
 		UserID: userID,
+		Role:   models.RoleOwner,
 	}
 
 	if err := s.roomMemberRepo.AddMember(ctx, member); err != nil {
@@ -58,8 +73,17 @@ func (s *roomService) CreateRoom(ctx context.Context, userID int, name, descript
 	return room, nil
 }
 
+// GetRoom returns a named room. Conversations are private to their participants and are
+// only reachable through the conversation endpoints.
 func (s *roomService) GetRoom(ctx context.Context, roomID int) (*models.Room, error) {
-	return s.roomRepo.GetByID(ctx, roomID)
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.Kind != models.RoomKindRoom {
+		return nil, errors.NewNotFoundError("room not found", nil)
+	}
+	return room, nil
 }
 
 func (s *roomService) GetRoomByName(ctx context.Context, name string) (*models.Room, error) {
@@ -81,10 +105,10 @@ func (s *roomService) UpdateRoom(ctx context.Context, roomID int, userID int, up
 		return nil, err
 	}
 
-	// Check if user is the creator
-	if room.CreatedBy != userID {
-		return nil, errors.NewForbiddenError("only room creator can update room", nil)
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, userID, models.PermManageSettings); err != nil {
+		return nil, err
 	}
+	before := *room
 
 	// Apply updates
 	if name, ok := updates["name"].(string); ok && name != "" {
@@ -114,30 +138,64 @@ func (s *roomService) UpdateRoom(ctx context.Context, roomID int, userID int, up
 		return nil, err
 	}
 
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(userID),
+		Action:  models.AuditRoomUpdate,
+		Before:  snapshot(before),
+		After:   snapshot(room),
+	})
+
 	return room, nil
 }
 
 func (s *roomService) DeleteRoom(ctx context.Context, roomID int, userID int) error {
-	// Get room
 	room, err := s.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
 		return err
 	}
 
-	// Check if user is the creator
-	if room.CreatedBy != userID {
-		return errors.NewForbiddenError("only room creator can delete room", nil)
+	member, err := roomMembership(ctx, s.roomMemberRepo, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role != models.RoleOwner {
+		return errors.NewForbiddenError("only the room owner can delete room", nil)
+	}
+
+	if err := s.roomRepo.Delete(ctx, roomID); err != nil {
+		return err
 	}
 
-	return s.roomRepo.Delete(ctx, roomID)
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(userID),
+		Action:  models.AuditRoomDelete,
+		Before:  snapshot(room),
+	})
+	return nil
 }
 
+// JoinRoom lets a user join a public room directly. Private rooms are joined through an
+// invite or an approved join request.
 func (s *roomService) JoinRoom(ctx context.Context, roomID, userID int) error {
-	// Check if room exists
-	_, err := s.roomRepo.GetByID(ctx, roomID)
+	room, err := s.GetRoom(ctx, roomID)
 	if err != nil {
 		return err
 	}
+	if room.IsPrivate {
+		return errors.NewForbiddenError("room is private; send a join request instead", nil)
+	}
+
+	return s.addMember(ctx, roomID, userID)
+}
+
+// addMember adds a user as a plain member once authorization has been settled. Banned
+// users are kept out however they were let in.
+func (s *roomService) addMember(ctx context.Context, roomID, userID int) error {
+	if err := requireNotBanned(ctx, s.sanctionRepo, roomID, userID); err != nil {
+		return err
+	}
 
 	// Check if user is already a member
 	isMember, err := s.roomMemberRepo.IsMember(ctx, roomID, userID)
@@ -157,21 +215,668 @@ func (s *roomService) JoinRoom(ctx context.Context, roomID, userID int) error {
 	return s.roomMemberRepo.AddMember(ctx, member)
 }
 
+// LeaveRoom removes a member. An owner who leaves hands the room to their successor first,
+// so the room is never left without one while it has members.
 func (s *roomService) LeaveRoom(ctx context.Context, roomID, userID int) error {
-	// Check if user is a member
-	isMember, err := s.roomMemberRepo.IsMember(ctx, roomID, userID)
+	member, err := s.roomMemberRepo.GetMember(ctx, roomID, userID)
+	if errors.IsNotFound(err) {
+		return errors.NewNotFoundError("user is not a member of this room", nil)
+	}
 	if err != nil {
-		return errors.NewDatabaseError("failed to check membership", err)
+		return err
 	}
-	if !isMember {
-		return errors.NewNotFoundError("user is not a member of this room", nil)
+
+	if member.Role == models.RoleOwner {
+		if err := s.passOwnership(ctx, roomID, userID); err != nil {
+			return err
+		}
 	}
 
-	return s.roomMemberRepo.RemoveMember(ctx, roomID, userID)
+	return s.removeMember(ctx, roomID, userID, RemovedLeft)
+}
+
+// removeMember drops a membership and unsubscribes the user's live connections from the room
+func (s *roomService) removeMember(ctx context.Context, roomID, userID int, reason string) error {
+	if err := s.roomMemberRepo.RemoveMember(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	if s.notifier != nil {
+		s.notifier.RemoveFromRoom(userID, roomID, reason)
+	}
+	return nil
+}
+
+// passOwnership hands a room to its successor. With no one eligible the room is left
+// without an owner.
+func (s *roomService) passOwnership(ctx context.Context, roomID, ownerID int) error {
+	members, err := s.roomMemberRepo.GetMembers(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	successor := successorOf(members, ownerID)
+	if successor == nil {
+		return nil
+	}
+
+	if err := s.roomMemberRepo.TransferOwnership(ctx, roomID, ownerID, successor.UserID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:       intPtr(roomID),
+		ActorID:      intPtr(ownerID),
+		Action:       models.AuditOwnershipTransfer,
+		TargetUserID: intPtr(successor.UserID),
+		Reason:       "owner left the room",
+	})
+	return nil
+}
+
+// RepairOwnerlessRooms gives every room left without an owner to its successor. Leaving and
+// deactivating pass rooms on themselves; this catches owners whose account row was deleted
+// outright, which drops their membership without succession.
+func (s *roomService) RepairOwnerlessRooms(ctx context.Context) error {
+	roomIDs, err := s.roomMemberRepo.GetOwnerlessRoomIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, roomID := range roomIDs {
+		members, err := s.roomMemberRepo.GetMembers(ctx, roomID)
+		if err != nil {
+			return err
+		}
+		successor := successorOf(members, 0)
+		if successor == nil {
+			continue
+		}
+
+		if err := s.roomMemberRepo.UpdateRole(ctx, roomID, successor.UserID, models.RoleOwner); err != nil {
+			return err
+		}
+
+		s.audit.Record(ctx, &models.AuditEntry{
+			RoomID:         intPtr(roomID),
+			Action:         models.AuditOwnershipTransfer,
+			TargetUserID:   intPtr(successor.UserID),
+			TargetUsername: successor.Username,
+			Before:         snapshot(map[string]models.RoomRole{"role": successor.Role}),
+			Reason:         "room had no owner",
+		})
+		s.logger.WithFields(logger.Fields{
+			"room_id": roomID,
+			"user_id": successor.UserID,
+		}).Info("Promoted successor in room without an owner")
+	}
+
+	return nil
+}
+
+// successorOf picks the longest-standing admin, falling back to moderators and then members
+// when there is none. Guests are read-only and never inherit a room. members must be ordered
+// by join time, as GetMembers returns them.
+func successorOf(members []*models.RoomMember, ownerID int) *models.RoomMember {
+	var successor *models.RoomMember
+	for _, member := range members {
+		if member.UserID == ownerID || member.Role == models.RoleGuest {
+			continue
+		}
+		// The first of the best rank has been there longest
+		if successor == nil || member.Role.Outranks(successor.Role) {
+			successor = member
+		}
+	}
+	return successor
 }
 
 func (s *roomService) GetRoomMembers(ctx context.Context, roomID int) ([]*models.User, error) {
-	// This would require a join query - simplified for now
-	// In a real implementation, you'd need to join with users table
-	return []*models.User{}, nil
+	// Check if room exists
+	if _, err := s.GetRoom(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	return s.roomMemberRepo.GetMemberUsers(ctx, roomID)
+}
+
+// GetReadState returns the user's unread position in a room; it fails for non-members
+func (s *roomService) GetReadState(ctx context.Context, roomID, userID int) (*models.RoomReadState, error) {
+	state, err := s.roomMemberRepo.GetReadState(ctx, roomID, userID)
+	if errors.IsNotFound(err) {
+		return nil, errors.NewForbiddenError("User is not a member of this room", nil)
+	}
+	return state, err
+}
+
+func (s *roomService) GetUnreadCounts(ctx context.Context, userID int) ([]*models.RoomReadState, error) {
+	return s.roomMemberRepo.GetReadStates(ctx, userID)
+}
+
+// MarkRead records that the user has read a room up to seq. Sequence numbers past the
+// newest message are clamped when the state is read back.
+func (s *roomService) MarkRead(ctx context.Context, roomID, userID int, seq int64) (*models.RoomReadState, error) {
+	if seq < 0 {
+		return nil, errors.NewValidationError("seq must not be negative", nil)
+	}
+
+	if _, err := s.GetReadState(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.roomMemberRepo.MarkRead(ctx, roomID, userID, seq); err != nil {
+		return nil, err
+	}
+
+	return s.roomMemberRepo.GetReadState(ctx, roomID, userID)
+}
+
+// GetMemberRoles lists a room's members with their roles; only members may see it
+func (s *roomService) GetMemberRoles(ctx context.Context, roomID, userID int) ([]*models.RoomMember, error) {
+	if _, err := roomMembership(ctx, s.roomMemberRepo, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.roomMemberRepo.GetMembers(ctx, roomID)
+}
+
+func (s *roomService) GetRoomPermissions(ctx context.Context, roomID, userID int) (*models.RoomPermissions, error) {
+	if _, err := s.roomRepo.GetByID(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	member, err := roomMembership(ctx, s.roomMemberRepo, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.PermissionsFor(roomID, member.Role), nil
+}
+
+func (s *roomService) RequirePermission(ctx context.Context, roomID, userID int, perm models.Permission) error {
+	_, err := requirePermission(ctx, s.roomMemberRepo, roomID, userID, perm)
+	return err
+}
+
+// SetMemberRole changes another member's role. Admins and owners may change the role of
+// members below them, to any role below their own; ownership is never granted this way.
+func (s *roomService) SetMemberRole(ctx context.Context, roomID, actorID, targetID int, role models.RoomRole) (*models.RoomMember, error) {
+	if !role.IsValid() {
+		return nil, errors.NewValidationError(fmt.Sprintf("unknown role %q", role), nil)
+	}
+	if actorID == targetID {
+		return nil, errors.NewForbiddenError("you cannot change your own role", nil)
+	}
+
+	actor, err := roomMembership(ctx, s.roomMemberRepo, roomID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Role.Outranks(models.RoleModerator) {
+		return nil, errors.NewForbiddenError("only room admins and owners can change roles", nil)
+	}
+
+	target, err := s.roomMemberRepo.GetMember(ctx, roomID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Role.Outranks(target.Role) || !actor.Role.Outranks(role) {
+		return nil, errors.NewForbiddenError("you can only assign roles below your own to members below you", nil)
+	}
+
+	if err := s.roomMemberRepo.UpdateRole(ctx, roomID, targetID, role); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:       intPtr(roomID),
+		ActorID:      intPtr(actorID),
+		Action:       models.AuditMemberRoleChange,
+		TargetUserID: intPtr(targetID),
+		Before:       snapshot(map[string]models.RoomRole{"role": target.Role}),
+		After:        snapshot(map[string]models.RoomRole{"role": role}),
+	})
+
+	target.Role = role
+	return target, nil
+}
+
+// InviteMember adds a user to a room on behalf of a member allowed to invite. It is an admin
+// add: the user joins straight away and is told afterwards. Invite codes are the way to let
+// someone choose whether to join.
+func (s *roomService) InviteMember(ctx context.Context, roomID, actorID, targetID int) error {
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, models.PermInvite); err != nil {
+		return err
+	}
+
+	if _, err := s.roomRepo.GetByID(ctx, roomID); err != nil {
+		return err
+	}
+
+	if err := s.addMember(ctx, roomID, targetID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:       intPtr(roomID),
+		ActorID:      intPtr(actorID),
+		Action:       models.AuditMemberInvite,
+		TargetUserID: intPtr(targetID),
+	})
+	s.notifications.Notify(ctx, &models.Notification{
+		UserID:  targetID,
+		Kind:    models.NotificationInvite,
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(actorID),
+	})
+	return nil
+}
+
+// KickMember removes a member the actor outranks
+func (s *roomService) KickMember(ctx context.Context, roomID, actorID, targetID int, reason string) error {
+	actor, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, models.PermKick)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.roomMemberRepo.GetMember(ctx, roomID, targetID)
+	if err != nil {
+		return err
+	}
+	if !actor.Role.Outranks(target.Role) {
+		return errors.NewForbiddenError("you can only remove members below your role", nil)
+	}
+
+	if err := s.removeMember(ctx, roomID, targetID, RemovedKicked); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:       intPtr(roomID),
+		ActorID:      intPtr(actorID),
+		Action:       models.AuditMemberKick,
+		TargetUserID: intPtr(targetID),
+		Reason:       reason,
+		Before:       snapshot(target),
+	})
+	s.notifications.Notify(ctx, &models.Notification{
+		UserID:  targetID,
+		Kind:    models.NotificationKicked,
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(actorID),
+		Reason:  reason,
+	})
+	return nil
+}
+
+// BanMember keeps a user out of the room until the ban expires or is lifted, removing them if
+// they are a member. Users who are not members can be banned too, to stop them joining.
+func (s *roomService) BanMember(ctx context.Context, roomID, actorID, targetID int, reason string, expiresAt *time.Time) (*models.RoomSanction, error) {
+	sanction, member, err := s.sanction(ctx, roomID, actorID, targetID, models.SanctionBan, reason, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if member != nil {
+		if err := s.removeMember(ctx, roomID, targetID, RemovedBanned); err != nil {
+			return nil, err
+		}
+	}
+
+	return sanction, nil
+}
+
+// MuteMember stops a member posting in the room while letting them keep reading it
+func (s *roomService) MuteMember(ctx context.Context, roomID, actorID, targetID int, reason string, expiresAt *time.Time) (*models.RoomSanction, error) {
+	sanction, _, err := s.sanction(ctx, roomID, actorID, targetID, models.SanctionMute, reason, expiresAt)
+	return sanction, err
+}
+
+// sanction checks the actor may sanction the target and records it, replacing any active
+// sanction of the same kind. It returns the target's membership, nil for a non-member.
+func (s *roomService) sanction(ctx context.Context, roomID, actorID, targetID int, kind models.SanctionKind, reason string, expiresAt *time.Time) (*models.RoomSanction, *models.RoomMember, error) {
+	if actorID == targetID {
+		return nil, nil, errors.NewValidationError("you cannot "+string(kind)+" yourself", nil)
+	}
+	if len(reason) > 500 {
+		return nil, nil, errors.NewValidationError("reason must be at most 500 characters", nil)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, nil, errors.NewValidationError("expires_at must be in the future", nil)
+	}
+
+	actor, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, sanctionPermission(kind))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := s.roomMemberRepo.GetMember(ctx, roomID, targetID)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, nil, err
+	}
+	if target == nil && kind == models.SanctionMute {
+		return nil, nil, errors.NewNotFoundError("user is not a member of this room", nil)
+	}
+	if target != nil && !actor.Role.Outranks(target.Role) {
+		return nil, nil, errors.NewForbiddenError("you can only "+string(kind)+" members below your role", nil)
+	}
+
+	sanction := &models.RoomSanction{
+		RoomID:    roomID,
+		UserID:    targetID,
+		Kind:      kind,
+		Reason:    reason,
+		CreatedBy: &actorID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.sanctionRepo.Create(ctx, sanction); err != nil {
+		return nil, nil, err
+	}
+
+	created, err := s.sanctionRepo.GetByID(ctx, sanction.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	action, notificationKind := models.AuditMemberMute, models.NotificationMuted
+	if kind == models.SanctionBan {
+		action, notificationKind = models.AuditMemberBan, models.NotificationBanned
+	}
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:         intPtr(roomID),
+		ActorID:        intPtr(actorID),
+		Action:         action,
+		TargetUserID:   intPtr(targetID),
+		TargetUsername: created.Username,
+		Reason:         reason,
+		After:          snapshot(created),
+	})
+
+	if s.notifier != nil {
+		s.notifier.NotifyUser(targetID, EventSanctioned, created)
+	}
+	s.notifications.Notify(ctx, &models.Notification{
+		UserID:  targetID,
+		Kind:    notificationKind,
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(actorID),
+		Reason:  reason,
+	})
+
+	return created, target, nil
+}
+
+// sanctionPermission is what it takes to impose or lift a sanction: moderators may mute, but
+// banning needs the ban permission
+func sanctionPermission(kind models.SanctionKind) models.Permission {
+	if kind == models.SanctionBan {
+		return models.PermBan
+	}
+	return models.PermKick
+}
+
+// GetSanctions lists a room's active bans and mutes, or only those of kind when it is set
+func (s *roomService) GetSanctions(ctx context.Context, roomID, actorID int, kind models.SanctionKind) ([]*models.RoomSanction, error) {
+	if kind != "" && kind != models.SanctionBan && kind != models.SanctionMute {
+		return nil, errors.NewValidationError("unknown sanction kind "+string(kind), nil)
+	}
+
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, models.PermKick); err != nil {
+		return nil, err
+	}
+
+	return s.sanctionRepo.ListActive(ctx, roomID, kind)
+}
+
+// LiftSanction ends a ban or mute before it expires
+func (s *roomService) LiftSanction(ctx context.Context, roomID, sanctionID, actorID int) error {
+	sanction, err := s.sanctionRepo.GetByID(ctx, sanctionID)
+	if err != nil {
+		return err
+	}
+	if sanction.RoomID != roomID {
+		return errors.NewNotFoundError("sanction not found", nil)
+	}
+
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, sanctionPermission(sanction.Kind)); err != nil {
+		return err
+	}
+
+	if err := s.sanctionRepo.Lift(ctx, sanctionID, actorID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:         intPtr(roomID),
+		ActorID:        intPtr(actorID),
+		Action:         models.AuditSanctionLift,
+		TargetUserID:   intPtr(sanction.UserID),
+		TargetUsername: sanction.Username,
+		Before:         snapshot(sanction),
+	})
+
+	if s.notifier != nil {
+		s.notifier.NotifyUser(sanction.UserID, EventSanctionLifted, sanction)
+	}
+
+	return nil
+}
+
+// ResetRoom removes every member the actor outranks (for semester end) and returns how
+// many were removed and how many could not be
+func (s *roomService) ResetRoom(ctx context.Context, roomID, actorID int) (*models.RoomReset, error) {
+	actor, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, models.PermManageSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.roomMemberRepo.GetMembers(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	reset := &models.RoomReset{}
+	for _, member := range members {
+		if !actor.Role.Outranks(member.Role) {
+			continue
+		}
+		if err := s.removeMember(ctx, roomID, member.UserID, RemovedKicked); err != nil {
+			// Keep going so one failure does not leave the rest of the room in place
+			s.logger.WithFields(logger.Fields{
+				"room_id": roomID,
+				"user_id": member.UserID,
+			}).WithError(err).Error("Failed to remove member during room reset")
+			reset.Failed++
+			continue
+		}
+		reset.Removed++
+
+		// One entry per member, so each removal shows up in that user's history
+		s.audit.Record(ctx, &models.AuditEntry{
+			RoomID:         intPtr(roomID),
+			ActorID:        intPtr(actorID),
+			Action:         models.AuditRoomReset,
+			TargetUserID:   intPtr(member.UserID),
+			TargetUsername: member.Username,
+			Before:         snapshot(member),
+		})
+	}
+
+	return reset, nil
+}
+
+// TransferOwnership hands the room to another member; the previous owner becomes an admin
+func (s *roomService) TransferOwnership(ctx context.Context, roomID, ownerID, newOwnerID int) (*models.RoomMember, error) {
+	if ownerID == newOwnerID {
+		return nil, errors.NewValidationError("you already own this room", nil)
+	}
+
+	owner, err := roomMembership(ctx, s.roomMemberRepo, roomID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.Role != models.RoleOwner {
+		return nil, errors.NewForbiddenError("only the room owner can transfer ownership", nil)
+	}
+
+	target, err := s.roomMemberRepo.GetMember(ctx, roomID, newOwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roomMemberRepo.TransferOwnership(ctx, roomID, ownerID, newOwnerID); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:         intPtr(roomID),
+		ActorID:        intPtr(ownerID),
+		Action:         models.AuditOwnershipTransfer,
+		TargetUserID:   intPtr(newOwnerID),
+		TargetUsername: target.Username,
+		Before:         snapshot(map[string]models.RoomRole{"role": target.Role}),
+		After:          snapshot(map[string]models.RoomRole{"role": models.RoleOwner}),
+	})
+
+	target.Role = models.RoleOwner
+	return target, nil
+}
+
+// LeaveAllRooms takes a user out of every room they belong to, passing on the rooms they
+// own. It is used when an account is deactivated.
+func (s *roomService) LeaveAllRooms(ctx context.Context, userID int) error {
+	rooms, err := s.roomMemberRepo.GetRoomsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, room := range rooms {
+		if err := s.LeaveRoom(ctx, room.ID, userID); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RequestToJoin files a request to join a private room. Public rooms are joined directly.
+func (s *roomService) RequestToJoin(ctx context.Context, roomID, userID int, message string) (*models.JoinRequest, error) {
+	room, err := s.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsPrivate {
+		return nil, errors.NewValidationError("room is public; join it directly", nil)
+	}
+	if err := requireNotBanned(ctx, s.sanctionRepo, roomID, userID); err != nil {
+		return nil, err
+	}
+	if len(message) > 500 {
+		return nil, errors.NewValidationError("message must be at most 500 characters", nil)
+	}
+
+	isMember, err := s.roomMemberRepo.IsMember(ctx, roomID, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("failed to check membership", err)
+	}
+	if isMember {
+		return nil, errors.NewConflictError("user is already a member", nil)
+	}
+
+	request := &models.JoinRequest{
+		RoomID:  roomID,
+		UserID:  userID,
+		Message: message,
+	}
+	if err := s.joinRequestRepo.Upsert(ctx, request); err != nil {
+		return nil, err
+	}
+
+	return s.joinRequestRepo.GetByID(ctx, request.ID)
+}
+
+// GetJoinRequests lists a room's pending requests for members allowed to invite
+func (s *roomService) GetJoinRequests(ctx context.Context, roomID, actorID int) ([]*models.JoinRequest, error) {
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, models.PermInvite); err != nil {
+		return nil, err
+	}
+
+	return s.joinRequestRepo.GetPendingByRoomID(ctx, roomID)
+}
+
+// DecideJoinRequest approves or denies a pending request and tells the requester the outcome
+func (s *roomService) DecideJoinRequest(ctx context.Context, roomID, requestID, actorID int, approve bool) (*models.JoinRequest, error) {
+	if _, err := requirePermission(ctx, s.roomMemberRepo, roomID, actorID, models.PermInvite); err != nil {
+		return nil, err
+	}
+
+	request, err := s.joinRequestRepo.GetByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.RoomID != roomID {
+		return nil, errors.NewNotFoundError("join request not found", nil)
+	}
+
+	if approve {
+		err = s.approveJoinRequest(ctx, request, actorID)
+	} else {
+		err = s.joinRequestRepo.Decide(ctx, requestID, models.JoinRequestDenied, actorID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decided, err := s.joinRequestRepo.GetByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &models.AuditEntry{
+		RoomID:         intPtr(roomID),
+		ActorID:        intPtr(actorID),
+		Action:         models.AuditJoinRequestDecide,
+		TargetUserID:   intPtr(decided.UserID),
+		TargetUsername: decided.Username,
+		After:          snapshot(decided),
+	})
+
+	if s.notifier != nil {
+		s.notifier.NotifyUser(decided.UserID, EventJoinRequestDecided, decided)
+	}
+	notificationKind := models.NotificationJoinDenied
+	if approve {
+		notificationKind = models.NotificationJoinApproved
+	}
+	s.notifications.Notify(ctx, &models.Notification{
+		UserID:  decided.UserID,
+		Kind:    notificationKind,
+		RoomID:  intPtr(roomID),
+		ActorID: intPtr(actorID),
+	})
+
+	return decided, nil
+}
+
+// approveJoinRequest approves the request and adds the requester in one transaction, so a
+// failed add leaves the request pending instead of approved without a membership.
+func (s *roomService) approveJoinRequest(ctx context.Context, request *models.JoinRequest, actorID int) error {
+	// Refuse before deciding, so a banned requester's request stays pending for a denial
+	if err := requireNotBanned(ctx, s.sanctionRepo, request.RoomID, request.UserID); err != nil {
+		return err
+	}
+
+	// Someone who got in another way keeps their role; the request is only settled
+	isMember, err := s.roomMemberRepo.IsMember(ctx, request.RoomID, request.UserID)
+	if err != nil {
+		return errors.NewDatabaseError("failed to check membership", err)
+	}
+	if isMember {
+		return s.joinRequestRepo.Decide(ctx, request.ID, models.JoinRequestApproved, actorID)
+	}
+
+	return s.joinRequestRepo.Approve(ctx, request.ID, actorID, &models.RoomMember{
+		RoomID: request.RoomID,
+		UserID: request.UserID,
+	})
 }
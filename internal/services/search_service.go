@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/pkg/errors"
+)
+
+type searchService struct {
+	searchRepo     repositories.SearchRepository
+	messageRepo    repositories.MessageRepository
+	roomMemberRepo repositories.RoomMemberRepository
+	userRepo       repositories.UserRepository
+}
+
+func NewSearchService(searchRepo repositories.SearchRepository, messageRepo repositories.MessageRepository, roomMemberRepo repositories.RoomMemberRepository, userRepo repositories.UserRepository) SearchService {
+	return &searchService{
+		searchRepo:     searchRepo,
+		messageRepo:    messageRepo,
+		roomMemberRepo: roomMemberRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// SearchMessages finds messages containing every word of the query, newest first, in the
+// rooms and conversations the user belongs to
+func (s *searchService) SearchMessages(ctx context.Context, userID int, query models.MessageSearchQuery) (*models.SearchResults, error) {
+	terms := models.SearchTerms(query.Query)
+	if len(terms) == 0 {
+		return nil, errors.NewValidationError("query must contain a word of at least 3 letters or digits", nil)
+	}
+
+	criteria := models.SearchCriteria{
+		Terms:         terms,
+		Since:         query.Since,
+		Until:         query.Until,
+		HasAttachment: query.HasAttachment,
+		Limit:         query.Limit + 1,
+	}
+
+	roomIDs, err := s.searchableRooms(ctx, userID, query.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	criteria.RoomIDs = roomIDs
+
+	if query.Author != "" {
+		author, err := s.userRepo.GetByUsername(ctx, query.Author)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, errors.NewValidationError("author not found", err)
+			}
+			return nil, err
+		}
+		criteria.UserID = author.ID
+	}
+
+	if query.Cursor != "" {
+		if criteria.BeforeID, err = models.DecodeSearchCursor(query.Cursor); err != nil {
+			return nil, errors.NewValidationError("invalid cursor", err)
+		}
+	}
+
+	ids, err := s.searchRepo.Search(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &models.SearchResults{Hits: []*models.SearchHit{}}
+	if len(ids) > query.Limit {
+		ids = ids[:query.Limit]
+		results.NextCursor = models.EncodeSearchCursor(ids[len(ids)-1])
+	}
+
+	messages, err := s.messageRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	// An index kept in process may briefly lag a deletion, so tombstones are dropped here too
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+	for _, message := range messages {
+		if message.DeletedAt != nil {
+			continue
+		}
+		results.Hits = append(results.Hits, &models.SearchHit{
+			Message: message,
+			Snippet: models.HighlightSnippet(message.Content, terms),
+		})
+	}
+
+	return results, nil
+}
+
+// searchableRooms is the one room asked for, which the user must belong to, or else every
+// room and conversation they belong to
+func (s *searchService) searchableRooms(ctx context.Context, userID, roomID int) ([]int, error) {
+	if roomID != 0 {
+		isMember, err := s.roomMemberRepo.IsMember(ctx, roomID, userID)
+		if err != nil {
+			return nil, errors.NewDatabaseError("failed to check room membership", err)
+		}
+		if !isMember {
+			return nil, errors.NewForbiddenError("user is not a member of this room", nil)
+		}
+		return []int{roomID}, nil
+	}
+
+	rooms, err := s.roomMemberRepo.GetRoomsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roomIDs := make([]int, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+	}
+	return roomIDs, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// sessionCache remembers recent revocation lookups so a signed token does not cost a
+// database round trip on every request. Entries expire after ttl, which bounds how long a
+// session revoked on another instance can keep being accepted here.
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+	ttl     time.Duration
+}
+
+type sessionCacheEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{
+		entries: make(map[string]sessionCacheEntry),
+		ttl:     ttl,
+	}
+}
+
+func (c *sessionCache) get(sessionID string) (active bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[sessionID]
+	if !exists {
+		return false, false
+	}
+	if time.Since(entry.checkedAt) > c.ttl {
+		delete(c.entries, sessionID)
+		return false, false
+	}
+	return entry.active, true
+}
+
+func (c *sessionCache) set(sessionID string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) > 10000 {
+		c.evictExpired()
+	}
+	c.entries[sessionID] = sessionCacheEntry{active: active, checkedAt: time.Now()}
+}
+
+func (c *sessionCache) evictExpired() {
+	for id, entry := range c.entries {
+		if time.Since(entry.checkedAt) > c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
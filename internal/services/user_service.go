@@ -12,11 +12,17 @@ import (
 )
 
 type userService struct {
-	userRepo repositories.UserRepository
+	userRepo    repositories.UserRepository
+	sessionRepo repositories.SessionRepository
+	roomService RoomService
 }
 
-func NewUserService(userRepo repositories.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository, roomService RoomService) UserService {
+	return &userService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		roomService: roomService,
+	}
 }
 
 func (s *userService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
@@ -69,7 +75,17 @@ func (s *userService) UpdateProfile(ctx context.Context, userID int, updates map
 }
 
 func (s *userService) DeactivateAccount(ctx context.Context, userID int) error {
-	return s.userRepo.Delete(ctx, userID)
+	// Leave rooms first so owned rooms pass to a successor rather than to an inactive account
+	if err := s.roomService.LeaveAllRooms(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	// Revoke all sessions so outstanding tokens stop working
+	return s.sessionRepo.DeleteByUserID(ctx, userID)
 }
 
 func (s *userService) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error {
@@ -102,7 +118,5 @@ func (s *userService) GetUserByUsername(ctx context.Context, username string) (*
 }
 
 func (s *userService) GetAllUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
-	// This would need to be implemented in the repository
-	// For now, return empty slice
-	return []*models.User{}, nil
+	return s.userRepo.GetAll(ctx, limit, offset)
 }
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a key holds no object
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage keeps attachment contents as opaque objects under slash-separated keys
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any object already there
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open streams an object; the caller closes it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -1,10 +1,10 @@
 package ws
 
 import (
-	"bytes"
-	"log"
 	"time"
 
+	"chat_app/internal/models"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -15,19 +15,20 @@ const (
 	maxMessageSize = 1024 * 8
 )
 
+// Client is a single authenticated connection. The user is fixed at the handshake; rooms maps
+// the IDs of subscribed rooms to their names and is only touched by the read pump.
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
-	room string
+	hub     *Hub
+	gateway *Gateway
+	conn    *websocket.Conn
+	send    chan []byte
+	user    *models.User
+	rooms   map[int]string
 }
 
-var newline = []byte{'\n'}
-var space = []byte{' '}
-
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.Leave(c.room, c)
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 	c.conn.SetReadLimit(maxMessageSize)
@@ -37,12 +38,11 @@ func (c *Client) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				c.gateway.logger.WithError(err).Warn("Unexpected websocket close")
 			}
 			break
 		}
-		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
-		c.hub.Broadcast(c.room, message)
+		c.gateway.handleFrame(c, message)
 	}
 }
 
@@ -62,20 +62,8 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			// One envelope per WebSocket message so clients can decode every frame on its own
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 		case <-ticker.C:
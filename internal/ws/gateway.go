@@ -0,0 +1,450 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chat_app/internal/models"
+	"chat_app/internal/repositories"
+	"chat_app/internal/services"
+	"chat_app/pkg/errors"
+	"chat_app/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Subprotocol is the protocol the gateway speaks. Browsers cannot set headers on a
+	// WebSocket handshake, so they offer it alongside "bearer.<token>" instead.
+	Subprotocol = "chat"
+
+	bearerProtocolPrefix = "bearer."
+	// maxReplayMessages caps one replay so it fits in a client's send buffer
+	maxReplayMessages = 100
+	maxContentLength  = 1000
+	commandTimeout    = 5 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	Subprotocols:    []string{Subprotocol},
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// Gateway is the single entry point for realtime clients. It authenticates the handshake,
+// checks room membership, and persists inbound messages before fanning them out through the Hub.
+type Gateway struct {
+	hub            *Hub
+	authService    services.AuthService
+	messageService services.MessageService
+	roomRepo       repositories.RoomRepository
+	roomMemberRepo repositories.RoomMemberRepository
+	sanctionRepo   repositories.SanctionRepository
+	logger         *logger.Logger
+}
+
+func NewGateway(hub *Hub, authService services.AuthService, messageService services.MessageService, roomRepo repositories.RoomRepository, roomMemberRepo repositories.RoomMemberRepository, sanctionRepo repositories.SanctionRepository, logger *logger.Logger) *Gateway {
+	return &Gateway{
+		hub:            hub,
+		authService:    authService,
+		messageService: messageService,
+		roomRepo:       roomRepo,
+		roomMemberRepo: roomMemberRepo,
+		sanctionRepo:   sanctionRepo,
+		logger:         logger,
+	}
+}
+
+// ServeWS authenticates and authorizes the handshake, then upgrades the connection
+func (g *Gateway) ServeWS(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	token := extractToken(c)
+	if token == "" {
+		writeError(c, errors.NewUnauthorizedError("Authentication token required", nil))
+		return
+	}
+
+	user, err := g.authService.ValidateToken(ctx, token)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	// The initial room is optional; clients subscribe to any number later with subscribe frames
+	var room *models.Room
+	if roomName := c.Query("room"); roomName != "" {
+		room, err = g.resolveRoom(ctx, user.ID, 0, roomName)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	var lastSeq *int64
+	if value := c.Query("last_seq"); value != "" {
+		seq, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seq < 0 {
+			writeError(c, errors.NewValidationError("last_seq must be a non-negative integer", err))
+			return
+		}
+		lastSeq = &seq
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	client := &Client{
+		hub:     g.hub,
+		gateway: g,
+		conn:    conn,
+		send:    make(chan []byte, 256),
+		user:    user,
+		rooms:   make(map[int]string),
+	}
+	g.hub.Register(client)
+	if room != nil {
+		if _, err := g.subscribeRoom(ctx, client, room, lastSeq); err != nil {
+			g.replyError(client, withFrameID(err, ""))
+		}
+	}
+
+	go client.writePump()
+	go client.readPump()
+}
+
+// resolveRoom looks up a room by ID, or by name when no ID is given, and checks that the user
+// belongs to it
+func (g *Gateway) resolveRoom(ctx context.Context, userID, roomID int, name string) (*models.Room, error) {
+	var room *models.Room
+	var err error
+	if roomID > 0 {
+		room, err = g.roomRepo.GetByID(ctx, roomID)
+	} else {
+		room, err = g.roomRepo.GetByName(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := g.roomMemberRepo.IsMember(ctx, room.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.NewForbiddenError("User is not a member of this room", nil)
+	}
+
+	return room, nil
+}
+
+// handleFrame validates a client command, executes it and answers with an ack or error frame
+func (g *Gateway) handleFrame(c *Client, data []byte) {
+	cmd, err := ParseCommand(data)
+	if err != nil {
+		g.replyError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var result interface{}
+	switch payload := cmd.Payload.(type) {
+	case *SendPayload:
+		result, err = g.send(ctx, c, payload)
+	case *EditPayload:
+		result, err = g.edit(ctx, c, payload)
+	case *DeletePayload:
+		result, err = g.delete(ctx, c, payload)
+	case *SubscribePayload:
+		result, err = g.subscribe(ctx, c, payload)
+	case *UnsubscribePayload:
+		result, err = g.unsubscribe(c, payload)
+	case *TypingPayload:
+		result, err = g.typing(ctx, c, payload)
+	case *ReadPayload:
+		result, err = g.read(ctx, c, payload)
+	case *ReactPayload:
+		result, err = g.react(ctx, c, payload)
+	case *UnreactPayload:
+		result, err = g.unreact(ctx, c, payload)
+	}
+
+	if err != nil {
+		if _, ok := err.(*ProtocolError); !ok {
+			g.logger.WithFields(logger.Fields{"user_id": c.user.ID, "frame_type": cmd.Type}).WithError(err).Warn("Websocket command failed")
+		}
+		g.replyError(c, withFrameID(err, cmd.ID))
+		return
+	}
+
+	g.reply(c, FrameAck, cmd.ID, result)
+}
+
+func (g *Gateway) send(ctx context.Context, c *Client, p *SendPayload) (interface{}, error) {
+	if _, ok := c.rooms[p.RoomID]; !ok {
+		return nil, errNotSubscribed
+	}
+
+	message, err := g.messageService.SendMessage(ctx, c.user.ID, &models.SendMessageRequest{
+		RoomID:   p.RoomID,
+		Content:  p.Content,
+		ParentID: p.ParentID,
+
+		AttachmentIDs: p.AttachmentIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The message service pushes the message, and any thread it joins, to the room
+	return message, nil
+}
+
+func (g *Gateway) edit(ctx context.Context, c *Client, p *EditPayload) (interface{}, error) {
+	// The message service pushes message_edited to the room
+	return g.messageService.EditMessage(ctx, p.MessageID, c.user.ID, p.Content)
+}
+
+func (g *Gateway) delete(ctx context.Context, c *Client, p *DeletePayload) (interface{}, error) {
+	// The message service pushes message_deleted, and any thread update, to the room
+	message, err := g.messageService.DeleteMessage(ctx, p.MessageID, c.user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MessageDeleted{MessageID: message.ID, RoomID: message.RoomID, DeletedAt: *message.DeletedAt}, nil
+}
+
+func (g *Gateway) react(ctx context.Context, c *Client, p *ReactPayload) (interface{}, error) {
+	// The message service pushes reaction_added to the room
+	return g.messageService.AddReaction(ctx, p.MessageID, c.user.ID, p.Emoji)
+}
+
+func (g *Gateway) unreact(ctx context.Context, c *Client, p *UnreactPayload) (interface{}, error) {
+	// The message service pushes reaction_removed to the room
+	return g.messageService.RemoveReaction(ctx, p.MessageID, c.user.ID, p.Emoji)
+}
+
+func (g *Gateway) subscribe(ctx context.Context, c *Client, p *SubscribePayload) (interface{}, error) {
+	room, err := g.resolveRoom(ctx, c.user.ID, p.RoomID, p.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.subscribeRoom(ctx, c, room, p.LastSeq)
+}
+
+// subscribeRoom adds room to the client's subscriptions. With lastSeq set, live delivery is
+// held while the gap since lastSeq is loaded, so the client sees the replay first and nothing
+// twice. The unread count starts from the user's stored read marker.
+func (g *Gateway) subscribeRoom(ctx context.Context, c *Client, room *models.Room, lastSeq *int64) (interface{}, error) {
+	state, err := g.roomMemberRepo.GetReadState(ctx, room.ID, c.user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := SubscribeResultPayload{
+		RoomID:      room.ID,
+		Room:        room.Name,
+		LastSeq:     state.LastSeq,
+		LastReadSeq: state.LastReadSeq,
+		Unread:      state.Unread,
+	}
+	if lastSeq == nil {
+		c.rooms[room.ID] = room.Name
+		g.hub.Join(room.ID, c, state.LastReadSeq, state.LastSeq)
+		return result, nil
+	}
+
+	g.hub.JoinReplay(room.ID, c, state.LastReadSeq, state.LastSeq)
+	frames, replayedTo, truncated, err := g.loadGap(ctx, c, room.ID, *lastSeq)
+	if err != nil {
+		g.hub.Resume(room.ID, c, nil, *lastSeq)
+		if _, subscribed := c.rooms[room.ID]; !subscribed {
+			g.hub.Leave(room.ID, c)
+		}
+		return nil, err
+	}
+	result.Truncated = truncated
+
+	c.rooms[room.ID] = room.Name
+	g.hub.Resume(room.ID, c, frames, replayedTo)
+	result.Replayed = len(frames)
+	return result, nil
+}
+
+// loadGap returns the frames a client missed after lastSeq, served from the Redis stream when
+// it covers the whole gap and from the database otherwise
+func (g *Gateway) loadGap(ctx context.Context, c *Client, room int, lastSeq int64) ([][]byte, int64, bool, error) {
+	if frames, ok := g.hub.Backfill(ctx, room, lastSeq, maxReplayMessages); ok {
+		return frames, lastSeq + int64(len(frames)), false, nil
+	}
+
+	missed, err := g.messageService.GetMessagesAfter(ctx, room, c.user.ID, lastSeq, maxReplayMessages+1)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	truncated := false
+	if len(missed) > maxReplayMessages {
+		missed = missed[:maxReplayMessages]
+		truncated = true
+	}
+
+	replayedTo := lastSeq
+	frames := make([][]byte, 0, len(missed))
+	for _, message := range missed {
+		frame, err := newFrame(EventMessage, "", message)
+		if err != nil {
+			// Stop at the first unencodable message; the client sees a truncated replay
+			truncated = true
+			break
+		}
+		frames = append(frames, frame)
+		replayedTo = message.Seq
+	}
+
+	return frames, replayedTo, truncated, nil
+}
+
+func (g *Gateway) unsubscribe(c *Client, p *UnsubscribePayload) (interface{}, error) {
+	roomName, ok := c.rooms[p.RoomID]
+	if !ok {
+		return nil, errNotSubscribed
+	}
+
+	g.hub.Leave(p.RoomID, c)
+	delete(c.rooms, p.RoomID)
+	return RoomPayload{RoomID: p.RoomID, Room: roomName}, nil
+}
+
+// typing relays a typing indicator. Muted users cannot post, so they do not announce typing;
+// the hub drops indicators from connections it has already removed from the room.
+func (g *Gateway) typing(ctx context.Context, c *Client, p *TypingPayload) (interface{}, error) {
+	if _, ok := c.rooms[p.RoomID]; !ok {
+		return nil, errNotSubscribed
+	}
+
+	if p.IsTyping && g.sanctionRepo != nil {
+		_, err := g.sanctionRepo.GetActive(ctx, p.RoomID, c.user.ID, models.SanctionMute)
+		if err == nil {
+			return nil, errors.NewForbiddenError("user is muted in this room", nil)
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	frame, err := newFrame(EventTyping, "", TypingEventPayload{
+		RoomID:   p.RoomID,
+		UserID:   c.user.ID,
+		Username: c.user.Username,
+		IsTyping: p.IsTyping,
+	})
+	if err != nil {
+		return nil, err
+	}
+	g.hub.BroadcastFrom(p.RoomID, c, frame)
+	return nil, nil
+}
+
+// read stores the user's read marker and refreshes the unread count on this connection. Other
+// sessions of the same user pick the marker up when they next subscribe.
+func (g *Gateway) read(ctx context.Context, c *Client, p *ReadPayload) (interface{}, error) {
+	if _, ok := c.rooms[p.RoomID]; !ok {
+		return nil, errNotSubscribed
+	}
+
+	if err := g.roomMemberRepo.MarkRead(ctx, p.RoomID, c.user.ID, p.Seq); err != nil {
+		return nil, err
+	}
+	state, err := g.roomMemberRepo.GetReadState(ctx, p.RoomID, c.user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	g.hub.MarkRead(p.RoomID, c, state.LastReadSeq)
+	return state, nil
+}
+
+func (g *Gateway) reply(c *Client, frameType FrameType, id string, payload interface{}) {
+	frame, err := newFrame(frameType, id, payload)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to encode websocket reply")
+		return
+	}
+	g.hub.Send(c, frame)
+}
+
+// replyError turns protocol and service failures into an error frame for the sender
+func (g *Gateway) replyError(c *Client, err error) {
+	payload := ErrorPayload{Code: string(errors.ErrCodeInternalError), Message: "Internal server error"}
+	id := ""
+
+	switch e := err.(type) {
+	case *ProtocolError:
+		payload = ErrorPayload{Code: e.Code, Message: e.Message}
+		id = e.ID
+	case *frameError:
+		id = e.id
+		if appErr, ok := e.err.(*errors.AppError); ok {
+			payload = ErrorPayload{Code: string(appErr.Code), Message: appErr.Message}
+		}
+	}
+
+	g.reply(c, FrameError, id, payload)
+}
+
+var errNotSubscribed = &ProtocolError{Code: CodeNotSubscribed, Message: "Subscribe to the room first"}
+
+// frameError ties a service failure to the command that caused it
+type frameError struct {
+	id  string
+	err error
+}
+
+func (e *frameError) Error() string { return e.err.Error() }
+
+func withFrameID(err error, id string) error {
+	if pe, ok := err.(*ProtocolError); ok {
+		return &ProtocolError{ID: id, Code: pe.Code, Message: pe.Message}
+	}
+	return &frameError{id: id, err: err}
+}
+
+// extractToken reads the access token from the Authorization header, a "bearer.<token>"
+// subprotocol entry, or the token query parameter, in that order.
+func extractToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+
+	for _, protocol := range websocket.Subprotocols(c.Request) {
+		if strings.HasPrefix(protocol, bearerProtocolPrefix) {
+			return strings.TrimPrefix(protocol, bearerProtocolPrefix)
+		}
+	}
+
+	return c.Query("token")
+}
+
+// writeError rejects a handshake before the upgrade using the same error shape as the REST API
+func writeError(c *gin.Context, err error) {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.NewInternalError("Internal server error", err)
+	}
+	c.AbortWithStatusJSON(appErr.HTTPStatus, gin.H{
+		"success": false,
+		"error":   gin.H{"code": appErr.Code, "message": appErr.Message},
+	})
+}
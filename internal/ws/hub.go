@@ -2,118 +2,476 @@ package ws
 
 import (
 	"context"
+	"strconv"
 	"sync"
 
 	"chat_app/internal/metrics"
+	"chat_app/internal/models"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type Hub struct {
-	rooms      map[string]map[*Client]bool
-	register   chan *subscription
-	unregister chan *subscription
-	broadcast  chan *messageEnvelope
-	mu         sync.RWMutex
-	pubsub     *redis.Client
+	// clients maps every connected client to the rooms it is subscribed to
+	clients map[*Client]map[int]*roomSubscription
+	rooms   map[int]map[*Client]bool
+	// users indexes connections by user so events can be addressed to all of a user's sessions
+	users map[int]map[*Client]bool
+	// roomSeqs is the newest sequence number seen per room, used to count unread messages
+	roomSeqs map[int]int64
+	// subscriptions carries every membership change on one channel so they apply in the
+	// order a connection issued them
+	subscriptions chan *subscription
+	broadcast     chan *messageEnvelope
+	direct        chan *directMessage
+	mu            sync.RWMutex
+	pubsub        *redis.Client
+	// instanceID tags everything this hub publishes so its own messages are recognised
+	// when Redis echoes them back
+	instanceID string
+	transport  transport
+	outbound   chan *relayEnvelope
+	relayed    uint64
+	seen       *recentIDs
+	seenMu     sync.Mutex
 }
 
+// roomSubscription is one client's state in one room
+type roomSubscription struct {
+	// replaying holds live broadcasts back until the client has been sent its gap
+	replaying bool
+	pending   []*messageEnvelope
+	// lastRead is the user's read marker, advanced by reads and by their own messages
+	lastRead int64
+}
+
+type subscriptionAction int
+
+const (
+	actionConnect subscriptionAction = iota
+	actionDisconnect
+	actionJoin
+	actionLeave
+	actionResume
+	actionRead
+)
+
 type subscription struct {
+	action subscriptionAction
 	client *Client
-	room   string
+	room   int
+	// replay marks a join whose live traffic is held back until the matching resume
+	replay bool
+	// frames and seq belong to a resume: the replayed gap and the last sequence it covered.
+	// For joins and reads seq is the user's read marker.
+	frames [][]byte
+	seq    int64
+	// roomSeq is the room's newest sequence number as known when a join was prepared
+	roomSeq int64
+}
+
+// directMessage is a reply addressed to one client. It goes through Run so it can never be
+// written to a send channel the hub has already closed.
+type directMessage struct {
+	client *Client
+	data   []byte
 }
 
 type messageEnvelope struct {
-	room string
+	room int
+	// seq is the room sequence number of a persisted message, 0 for transient events
+	seq  int64
 	data []byte
+	// sender is the author of a persisted message; their own messages never count as unread
+	sender int
+	// user addresses the message to one user's connections instead of a room. A user message
+	// with a room also unsubscribes those connections from it.
+	user int
+	// from is the local client a transient event came from; it is dropped if that client has
+	// since left the room
+	from *Client
+	// origin is the instance a message was relayed from; empty for local broadcasts
+	origin string
 }
 
+// maxPendingFrames bounds how much live traffic is held for one replaying room. A client
+// that falls further behind is dropped like any slow client and resumes on reconnect.
+const maxPendingFrames = 128
+
+// channelPrefix namespaces the per-room Pub/Sub channels, which are keyed by room ID so a
+// rename does not split a room's subscribers across instances.
+const channelPrefix = "chat:"
+
+func channelName(room int) string { return channelPrefix + strconv.Itoa(room) }
+
+func userChannelName(user int) string { return channelPrefix + "user:" + strconv.Itoa(user) }
+
 func NewHub() *Hub {
 	return &Hub{
-		rooms:      make(map[string]map[*Client]bool),
-		register:   make(chan *subscription, 1024),
-		unregister: make(chan *subscription, 1024),
-		broadcast:  make(chan *messageEnvelope, 4096),
+		clients:       make(map[*Client]map[int]*roomSubscription),
+		rooms:         make(map[int]map[*Client]bool),
+		users:         make(map[int]map[*Client]bool),
+		roomSeqs:      make(map[int]int64),
+		subscriptions: make(chan *subscription, 1024),
+		broadcast:     make(chan *messageEnvelope, 4096),
+		direct:        make(chan *directMessage, 1024),
+		instanceID:    newInstanceID(),
 	}
 }
 
-func (h *Hub) Run() {
+// Run serves subscriptions and deliveries until ctx ends
+func (h *Hub) Run(ctx context.Context) {
 	for {
 		select {
-		case sub := <-h.register:
-			if _, ok := h.rooms[sub.room]; !ok {
-				h.rooms[sub.room] = make(map[*Client]bool)
+		case <-ctx.Done():
+			return
+		case sub := <-h.subscriptions:
+			h.apply(sub)
+		case msg := <-h.direct:
+			h.reply(msg)
+		case msg := <-h.broadcast:
+			h.deliver(msg)
+		}
+	}
+}
+
+// reply hands a client its ack or error. A client too slow to take it is dropped like on a
+// broadcast, rather than left waiting for a reply that never comes.
+func (h *Hub) reply(msg *directMessage) {
+	if _, ok := h.clients[msg.client]; ok {
+		h.push(msg.client, msg.data)
+	}
+}
+
+// deliver fans a message out to this instance's clients. Only local broadcasts are relayed
+// to other instances; relayed ones are never published again, which would loop forever.
+func (h *Hub) deliver(msg *messageEnvelope) {
+	if msg.from != nil {
+		if _, subscribed := h.clients[msg.from][msg.room]; !subscribed {
+			return
+		}
+	}
+
+	if msg.origin == "" {
+		h.relay(msg)
+	}
+
+	if msg.user != 0 {
+		for c := range h.users[msg.user] {
+			if msg.room != 0 {
+				h.detach(c, msg.room)
 			}
-			h.rooms[sub.room][sub.client] = true
-			// update room state in Redis (optional)
-			h.updateRoomState(sub.room, 1)
-		case sub := <-h.unregister:
-			if clients, ok := h.rooms[sub.room]; ok {
-				if _, exists := clients[sub.client]; exists {
-					delete(clients, sub.client)
-					close(sub.client.send)
-					if len(clients) == 0 {
-						delete(h.rooms, sub.room)
-					}
-				}
+			h.push(c, msg.data)
+		}
+		return
+	}
+
+	if msg.seq > h.roomSeqs[msg.room] {
+		h.roomSeqs[msg.room] = msg.seq
+	}
+
+	clients, ok := h.rooms[msg.room]
+	if !ok {
+		return
+	}
+	for c := range clients {
+		state := h.clients[c][msg.room]
+		if state.replaying {
+			if len(state.pending) >= maxPendingFrames {
+				h.drop(c)
+			} else {
+				state.pending = append(state.pending, msg)
 			}
-			// update room state in Redis (optional)
-			h.updateRoomState(sub.room, -1)
-		case msg := <-h.broadcast:
-			if clients, ok := h.rooms[msg.room]; ok {
-				for c := range clients {
-					select {
-					case c.send <- msg.data:
-					default:
-						// backpressure: drop slow client
-						close(c.send)
-						delete(clients, c)
-					}
-				}
-				metrics.MessagesBroadcastTotal.WithLabelValues(msg.room).Inc()
-				if h.pubsub != nil {
-					_ = h.pubsub.Publish(context.Background(), "chat:"+msg.room, msg.data).Err()
-				}
+			continue
+		}
+		if !h.push(c, msg.data) {
+			continue
+		}
+		if msg.seq > 0 {
+			h.notifyUnread(c, msg.room, state, msg)
+		}
+	}
+	metrics.MessagesBroadcastTotal.WithLabelValues(strconv.Itoa(msg.room)).Inc()
+}
+
+// push queues a frame for a client and drops the client when its buffer is full
+func (h *Hub) push(c *Client, frame []byte) bool {
+	select {
+	case c.send <- frame:
+		return true
+	default:
+		// backpressure: drop slow client
+		h.drop(c)
+		return false
+	}
+}
+
+// notifyUnread tells a client how many messages it has not read in a room after a new one
+// arrived there. A user's own message moves their read marker instead.
+func (h *Hub) notifyUnread(c *Client, room int, state *roomSubscription, msg *messageEnvelope) {
+	if c.user != nil && c.user.ID == msg.sender {
+		if msg.seq > state.lastRead {
+			state.lastRead = msg.seq
+		}
+		return
+	}
+	h.sendUnread(c, room, state)
+}
+
+func (h *Hub) sendUnread(c *Client, room int, state *roomSubscription) {
+	unread := h.roomSeqs[room] - state.lastRead
+	if unread < 0 {
+		unread = 0
+	}
+	frame, err := newFrame(EventUnread, "", UnreadPayload{RoomID: room, Unread: unread, LastSeq: h.roomSeqs[room]})
+	if err != nil {
+		return
+	}
+	h.push(c, frame)
+}
+
+func (h *Hub) apply(sub *subscription) {
+	switch sub.action {
+	case actionConnect:
+		h.clients[sub.client] = make(map[int]*roomSubscription)
+		if c := sub.client; c.user != nil {
+			if _, ok := h.users[c.user.ID]; !ok {
+				h.users[c.user.ID] = make(map[*Client]bool)
+			}
+			h.users[c.user.ID][c] = true
+		}
+		metrics.WSConnections.Inc()
+	case actionDisconnect:
+		h.drop(sub.client)
+	case actionJoin:
+		h.join(sub)
+	case actionLeave:
+		h.detach(sub.client, sub.room)
+	case actionResume:
+		h.resume(sub)
+	case actionRead:
+		if state, ok := h.clients[sub.client][sub.room]; ok {
+			if sub.seq > state.lastRead {
+				state.lastRead = sub.seq
 			}
+			h.sendUnread(sub.client, sub.room, state)
 		}
 	}
 }
 
-func (h *Hub) Join(room string, c *Client)  { h.register <- &subscription{client: c, room: room} }
-func (h *Hub) Leave(room string, c *Client) { h.unregister <- &subscription{client: c, room: room} }
-func (h *Hub) Broadcast(room string, payload []byte) {
-	h.broadcast <- &messageEnvelope{room: room, data: payload}
+// join adds a room to a client's subscriptions. Joining a room it already has only updates
+// the read marker and, for a replaying join, starts holding live traffic again.
+func (h *Hub) join(sub *subscription) {
+	rooms, ok := h.clients[sub.client]
+	if !ok {
+		return
+	}
+
+	if sub.roomSeq > h.roomSeqs[sub.room] {
+		h.roomSeqs[sub.room] = sub.roomSeq
+	}
+
+	state, subscribed := rooms[sub.room]
+	if !subscribed {
+		state = &roomSubscription{}
+		rooms[sub.room] = state
+		if _, ok := h.rooms[sub.room]; !ok {
+			h.rooms[sub.room] = make(map[*Client]bool)
+			if h.transport != nil {
+				h.transport.watch(sub.room)
+			}
+		}
+		h.rooms[sub.room][sub.client] = true
+		// update room state in Redis (optional)
+		h.updateRoomState(sub.room, 1)
+	}
+
+	if sub.seq > state.lastRead {
+		state.lastRead = sub.seq
+	}
+	if sub.replay {
+		state.replaying = true
+		state.pending = nil
+	}
 }
 
-// EnableRedis enables cross-instance broadcasting via Redis Pub/Sub and starts a subscriber.
-func (h *Hub) EnableRedis(client *redis.Client) {
-	h.pubsub = client
-	go func() {
-		if client == nil {
+// resume delivers a room's replayed gap followed by the live traffic held since the client
+// joined, skipping held messages the replay already covered
+func (h *Hub) resume(sub *subscription) {
+	state, ok := h.clients[sub.client][sub.room]
+	if !ok || !state.replaying {
+		return
+	}
+	queue := state.pending
+	state.replaying = false
+	state.pending = nil
+
+	frames := sub.frames
+	for _, msg := range queue {
+		if msg.seq == 0 || msg.seq > sub.seq {
+			frames = append(frames, msg.data)
+		}
+		if c := sub.client; c.user != nil && c.user.ID == msg.sender && msg.seq > state.lastRead {
+			state.lastRead = msg.seq
+		}
+	}
+
+	for _, frame := range frames {
+		if !h.push(sub.client, frame) {
 			return
 		}
-		ctx := context.Background()
-		// subscribe to all chat:* channels
-		p := client.PSubscribe(ctx, "chat:*")
-		ch := p.Channel()
-		for msg := range ch {
-			room := msg.Channel[len("chat:"):]
-			h.broadcast <- &messageEnvelope{room: room, data: []byte(msg.Payload)}
+	}
+	h.sendUnread(sub.client, sub.room, state)
+}
+
+// detach removes one room from a client's subscriptions without closing the connection
+func (h *Hub) detach(c *Client, room int) {
+	rooms, ok := h.clients[c]
+	if !ok {
+		return
+	}
+	if _, subscribed := rooms[room]; !subscribed {
+		return
+	}
+	delete(rooms, room)
+
+	if clients, ok := h.rooms[room]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.rooms, room)
+			delete(h.roomSeqs, room)
+			if h.transport != nil {
+				h.transport.unwatch(room)
+			}
+		}
+	}
+	// update room state in Redis (optional)
+	h.updateRoomState(room, -1)
+}
+
+// drop forgets a client entirely and closes its send channel, which ends its write pump
+func (h *Hub) drop(c *Client) {
+	rooms, ok := h.clients[c]
+	if !ok {
+		return
+	}
+	for room := range rooms {
+		h.detach(c, room)
+	}
+	if c.user != nil {
+		if sessions, ok := h.users[c.user.ID]; ok {
+			delete(sessions, c)
+			if len(sessions) == 0 {
+				delete(h.users, c.user.ID)
+			}
 		}
-	}()
+	}
+	delete(h.clients, c)
+	close(c.send)
+	metrics.WSConnections.Dec()
+}
+
+// Register and Unregister track a connection's lifetime; Join and Leave add and remove rooms
+// from its subscriptions. JoinReplay subscribes while holding back the room's live traffic
+// until Resume hands over the replayed gap. readSeq and roomSeq seed the unread count.
+func (h *Hub) Register(c *Client) { h.subscriptions <- &subscription{action: actionConnect, client: c} }
+func (h *Hub) Unregister(c *Client) {
+	h.subscriptions <- &subscription{action: actionDisconnect, client: c}
+}
+func (h *Hub) Join(room int, c *Client, readSeq, roomSeq int64) {
+	h.subscriptions <- &subscription{action: actionJoin, client: c, room: room, seq: readSeq, roomSeq: roomSeq}
+}
+func (h *Hub) JoinReplay(room int, c *Client, readSeq, roomSeq int64) {
+	h.subscriptions <- &subscription{action: actionJoin, client: c, room: room, replay: true, seq: readSeq, roomSeq: roomSeq}
+}
+func (h *Hub) Resume(room int, c *Client, frames [][]byte, lastSeq int64) {
+	h.subscriptions <- &subscription{action: actionResume, client: c, room: room, frames: frames, seq: lastSeq}
+}
+func (h *Hub) Leave(room int, c *Client) {
+	h.subscriptions <- &subscription{action: actionLeave, client: c, room: room}
+}
+
+// MarkRead moves a client's read marker in a room and sends it the new unread count
+func (h *Hub) MarkRead(room int, c *Client, readSeq int64) {
+	h.subscriptions <- &subscription{action: actionRead, client: c, room: room, seq: readSeq}
+}
+
+func (h *Hub) Broadcast(room int, payload []byte) {
+	h.broadcast <- &messageEnvelope{room: room, data: payload}
+}
+
+// BroadcastFrom fans out a transient event a client sent, unless the hub has removed that
+// client from the room by the time it is delivered
+func (h *Hub) BroadcastFrom(room int, c *Client, payload []byte) {
+	h.broadcast <- &messageEnvelope{room: room, from: c, data: payload}
+}
+
+// BroadcastMessage fans out a persisted message, tagged with its room sequence number so
+// replaying clients do not receive it twice, and with its author for unread counting
+func (h *Hub) BroadcastMessage(room int, seq int64, sender int, payload []byte) {
+	h.broadcast <- &messageEnvelope{room: room, seq: seq, sender: sender, data: payload}
+}
+
+// SendToUser delivers a payload to every connection of a user, on any instance
+func (h *Hub) SendToUser(userID int, payload []byte) {
+	h.broadcast <- &messageEnvelope{user: userID, data: payload}
+}
+
+// NotifyUser pushes an event frame to every connection of a user. It lets services that
+// cannot depend on this package send realtime notifications.
+func (h *Hub) NotifyUser(userID int, event string, payload interface{}) {
+	frame, err := newFrame(FrameType(event), "", payload)
+	if err != nil {
+		return
+	}
+	h.SendToUser(userID, frame)
+}
+
+// NotifyRoom pushes an event frame to a room's subscribers on every instance
+func (h *Hub) NotifyRoom(roomID int, event string, payload interface{}) {
+	frame, err := newFrame(FrameType(event), "", payload)
+	if err != nil {
+		return
+	}
+	h.Broadcast(roomID, frame)
+}
+
+// NotifyMessage pushes a newly stored message to its room's subscribers on every instance
+func (h *Hub) NotifyMessage(message *models.Message) {
+	frame, err := newFrame(EventMessage, "", message)
+	if err != nil {
+		return
+	}
+	h.BroadcastMessage(message.RoomID, message.Seq, message.UserID, frame)
+}
+
+// RemoveFromRoom unsubscribes every connection of a user from a room, on any instance, and
+// tells them why with a room_removed event
+func (h *Hub) RemoveFromRoom(userID, roomID int, reason string) {
+	frame, err := newFrame(EventRoomRemoved, "", RoomRemovedPayload{RoomID: roomID, Reason: reason})
+	if err != nil {
+		return
+	}
+	h.broadcast <- &messageEnvelope{user: userID, room: roomID, data: frame}
+}
+
+// Send delivers a payload to a single client if it is still connected
+func (h *Hub) Send(c *Client, payload []byte) {
+	h.direct <- &directMessage{client: c, data: payload}
 }
 
-func (h *Hub) updateRoomState(room string, delta int64) {
+func (h *Hub) updateRoomState(room int, delta int64) {
 	if h.pubsub == nil {
 		return
 	}
 	ctx := context.Background()
 	// Maintain a set of rooms
-	_ = h.pubsub.SAdd(ctx, "rooms", room).Err()
+	key := strconv.Itoa(room)
+	_ = h.pubsub.SAdd(ctx, "rooms", key).Err()
 	// Track member counts per room
-	count, err := h.pubsub.HIncrBy(ctx, "room:members", room, delta).Result()
+	count, err := h.pubsub.HIncrBy(ctx, "room:members", key, delta).Result()
 	if err == nil && count <= 0 {
-		_ = h.pubsub.HDel(ctx, "room:members", room).Err()
-		_ = h.pubsub.SRem(ctx, "rooms", room).Err()
+		_ = h.pubsub.HDel(ctx, "room:members", key).Err()
+		_ = h.pubsub.SRem(ctx, "rooms", key).Err()
 	}
 }
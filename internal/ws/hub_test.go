@@ -0,0 +1,192 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chat_app/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient() *Client {
+	return &Client{send: make(chan []byte, 16)}
+}
+
+// drain returns the frames queued for a client, leaving out unread events
+func drain(c *Client) []string {
+	frames, _ := drainAll(c)
+	return frames
+}
+
+// drainAll returns the queued frames and, separately, the unread events among them
+func drainAll(c *Client) ([]string, []UnreadPayload) {
+	var frames []string
+	var unread []UnreadPayload
+	for {
+		select {
+		case frame, ok := <-c.send:
+			if !ok {
+				return frames, unread
+			}
+			var env Envelope
+			if json.Unmarshal(frame, &env) == nil && env.Type == EventUnread {
+				var payload UnreadPayload
+				_ = json.Unmarshal(env.Payload, &payload)
+				unread = append(unread, payload)
+				continue
+			}
+			frames = append(frames, string(frame))
+		default:
+			return frames, unread
+		}
+	}
+}
+
+func isUnreadFrame(frame []byte) bool {
+	var env Envelope
+	return json.Unmarshal(frame, &env) == nil && env.Type == EventUnread
+}
+
+func TestResumeReplaysGapBeforeHeldLiveTraffic(t *testing.T) {
+	hub := NewHub()
+	client := newTestClient()
+	hub.apply(&subscription{action: actionConnect, client: client})
+	hub.apply(&subscription{action: actionJoin, client: client, room: 1, replay: true})
+
+	// Live traffic that arrives while the gap is loaded is held back
+	hub.deliver(&messageEnvelope{room: 1, seq: 3, data: []byte("m3")})
+	hub.deliver(&messageEnvelope{room: 1, data: []byte("typing")})
+	hub.deliver(&messageEnvelope{room: 1, seq: 4, data: []byte("m4")})
+	assert.Empty(t, drain(client))
+
+	// m3 was also part of the replay and must not be delivered twice
+	hub.apply(&subscription{action: actionResume, client: client, room: 1, frames: [][]byte{[]byte("m2"), []byte("m3")}, seq: 3})
+	assert.Equal(t, []string{"m2", "m3", "typing", "m4"}, drain(client))
+
+	hub.deliver(&messageEnvelope{room: 1, seq: 5, data: []byte("m5")})
+	assert.Equal(t, []string{"m5"}, drain(client))
+}
+
+func TestReplayingClientThatFallsTooFarBehindIsDropped(t *testing.T) {
+	hub := NewHub()
+	client := newTestClient()
+	hub.apply(&subscription{action: actionConnect, client: client})
+	hub.apply(&subscription{action: actionJoin, client: client, room: 1, replay: true})
+
+	for seq := int64(1); seq <= maxPendingFrames+1; seq++ {
+		hub.deliver(&messageEnvelope{room: 1, seq: seq, data: []byte("m")})
+	}
+
+	_, connected := hub.clients[client]
+	assert.False(t, connected)
+	_, open := <-client.send
+	assert.False(t, open)
+}
+
+func TestClientTooSlowForItsReplyIsDropped(t *testing.T) {
+	hub := NewHub()
+	client := &Client{send: make(chan []byte, 1)}
+	hub.apply(&subscription{action: actionConnect, client: client})
+
+	hub.reply(&directMessage{client: client, data: []byte("ack")})
+	hub.reply(&directMessage{client: client, data: []byte("error")})
+
+	_, connected := hub.clients[client]
+	assert.False(t, connected)
+	assert.Equal(t, []string{"ack"}, drain(client))
+}
+
+func TestClientReceivesEverySubscribedRoom(t *testing.T) {
+	hub := NewHub()
+	client := newTestClient()
+	hub.apply(&subscription{action: actionConnect, client: client})
+	hub.apply(&subscription{action: actionJoin, client: client, room: 1})
+	hub.apply(&subscription{action: actionJoin, client: client, room: 2})
+
+	hub.deliver(&messageEnvelope{room: 1, data: []byte("one")})
+	hub.deliver(&messageEnvelope{room: 2, data: []byte("two")})
+	hub.deliver(&messageEnvelope{room: 3, data: []byte("three")})
+	assert.Equal(t, []string{"one", "two"}, drain(client))
+
+	hub.apply(&subscription{action: actionLeave, client: client, room: 2})
+	hub.deliver(&messageEnvelope{room: 1, data: []byte("still here")})
+	hub.deliver(&messageEnvelope{room: 2, data: []byte("after leave")})
+	assert.Equal(t, []string{"still here"}, drain(client))
+	_, watched := hub.rooms[2]
+	assert.False(t, watched)
+}
+
+func TestUnreadCountsFollowReadMarkerAndOwnMessages(t *testing.T) {
+	hub := NewHub()
+	client := &Client{send: make(chan []byte, 16), user: &models.User{ID: 7}}
+	hub.apply(&subscription{action: actionConnect, client: client})
+	// The user had read up to 3 of the room's 5 messages
+	hub.apply(&subscription{action: actionJoin, client: client, room: 1, seq: 3, roomSeq: 5})
+
+	hub.deliver(&messageEnvelope{room: 1, seq: 6, sender: 2, data: []byte("m6")})
+	frames, unread := drainAll(client)
+	assert.Equal(t, []string{"m6"}, frames)
+	require.Len(t, unread, 1)
+	assert.Equal(t, UnreadPayload{RoomID: 1, Unread: 3, LastSeq: 6}, unread[0])
+
+	// Sending a message reads everything before it
+	hub.deliver(&messageEnvelope{room: 1, seq: 7, sender: 7, data: []byte("m7")})
+	_, unread = drainAll(client)
+	assert.Empty(t, unread)
+
+	hub.deliver(&messageEnvelope{room: 1, seq: 8, sender: 2, data: []byte("m8")})
+	_, unread = drainAll(client)
+	require.Len(t, unread, 1)
+	assert.Equal(t, int64(1), unread[0].Unread)
+
+	hub.apply(&subscription{action: actionRead, client: client, room: 1, seq: 8})
+	_, unread = drainAll(client)
+	require.Len(t, unread, 1)
+	assert.Equal(t, int64(0), unread[0].Unread)
+}
+
+func TestSendToUserReachesEverySessionOfThatUser(t *testing.T) {
+	hub := NewHub()
+	laptop := &Client{send: make(chan []byte, 16), user: &models.User{ID: 7}}
+	phone := &Client{send: make(chan []byte, 16), user: &models.User{ID: 7}}
+	other := &Client{send: make(chan []byte, 16), user: &models.User{ID: 8}}
+	for _, c := range []*Client{laptop, phone, other} {
+		hub.apply(&subscription{action: actionConnect, client: c})
+	}
+
+	hub.deliver(&messageEnvelope{user: 7, data: []byte("approved")})
+	assert.Equal(t, []string{"approved"}, drain(laptop))
+	assert.Equal(t, []string{"approved"}, drain(phone))
+	assert.Empty(t, drain(other))
+
+	hub.apply(&subscription{action: actionDisconnect, client: laptop})
+	hub.apply(&subscription{action: actionDisconnect, client: phone})
+	_, indexed := hub.users[7]
+	assert.False(t, indexed)
+}
+
+func TestRemovedUserStopsReceivingRoomAndCannotTypeIntoIt(t *testing.T) {
+	hub := NewHub()
+	banned := &Client{send: make(chan []byte, 16), user: &models.User{ID: 7}}
+	member := &Client{send: make(chan []byte, 16), user: &models.User{ID: 8}}
+	for _, c := range []*Client{banned, member} {
+		hub.apply(&subscription{action: actionConnect, client: c})
+		hub.apply(&subscription{action: actionJoin, client: c, room: 1})
+		hub.apply(&subscription{action: actionJoin, client: c, room: 2})
+	}
+
+	hub.deliver(&messageEnvelope{user: 7, room: 1, data: []byte("removed")})
+	assert.Equal(t, []string{"removed"}, drain(banned))
+	_, inRoom := hub.clients[banned][1]
+	assert.False(t, inRoom)
+
+	hub.deliver(&messageEnvelope{room: 1, from: banned, data: []byte("typing")})
+	assert.Empty(t, drain(member))
+
+	hub.deliver(&messageEnvelope{room: 1, data: []byte("after")})
+	hub.deliver(&messageEnvelope{room: 2, data: []byte("other room")})
+	assert.Equal(t, []string{"other room"}, drain(banned))
+	assert.Equal(t, []string{"after", "other room"}, drain(member))
+}
@@ -0,0 +1,329 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"chat_app/internal/models"
+)
+
+// ProtocolVersion is the envelope version this server speaks. Frames carrying any other
+// version are rejected so incompatible clients fail loudly instead of half working.
+// Version 2 replaced the single active room with per-room subscriptions.
+const ProtocolVersion = 2
+
+type FrameType string
+
+// Commands are sent by clients and always answered with an ack or an error frame that
+// echoes the command's id. Events are pushed by the server and carry no id.
+const (
+	FrameSend        FrameType = "send"
+	FrameEdit        FrameType = "edit"
+	FrameDelete      FrameType = "delete"
+	FrameSubscribe   FrameType = "subscribe"
+	FrameUnsubscribe FrameType = "unsubscribe"
+	FrameTyping      FrameType = "typing"
+	FrameRead        FrameType = "read"
+	FrameReact       FrameType = "react"
+	FrameUnreact     FrameType = "unreact"
+
+	FrameAck   FrameType = "ack"
+	FrameError FrameType = "error"
+
+	EventMessage         FrameType = "message"
+	EventMessageEdited   FrameType = "message_edited"
+	EventMessageDeleted  FrameType = "message_deleted"
+	EventTyping          FrameType = "typing"
+	EventUnread          FrameType = "unread"
+	EventRoomRemoved     FrameType = "room_removed"
+	EventThreadUpdated   FrameType = "thread_updated"
+	EventReactionAdded   FrameType = "reaction_added"
+	EventReactionRemoved FrameType = "reaction_removed"
+)
+
+const maxFrameIDLength = 64
+
+// Envelope wraps every frame in both directions
+type Envelope struct {
+	Version   int             `json:"v"`
+	Type      FrameType       `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Timestamp time.Time       `json:"ts"`
+}
+
+// SendPayload posts a message to a subscribed room, or with ParentID a reply in a thread
+type SendPayload struct {
+	RoomID        int    `json:"room_id"`
+	Content       string `json:"content"`
+	ParentID      *int   `json:"parent_id,omitempty"`
+	AttachmentIDs []int  `json:"attachment_ids,omitempty"`
+}
+
+type EditPayload struct {
+	MessageID int    `json:"message_id"`
+	Content   string `json:"content"`
+}
+
+type DeletePayload struct {
+	MessageID int `json:"message_id"`
+}
+
+// SubscribePayload adds a room, given by id or name, to the connection. A reconnecting client
+// sends the last sequence number it saw in that room and is replayed everything after it
+// before live delivery resumes.
+type SubscribePayload struct {
+	RoomID  int    `json:"room_id,omitempty"`
+	Room    string `json:"room,omitempty"`
+	LastSeq *int64 `json:"last_seq,omitempty"`
+}
+
+type UnsubscribePayload struct {
+	RoomID int `json:"room_id"`
+}
+
+type TypingPayload struct {
+	RoomID   int  `json:"room_id"`
+	IsTyping bool `json:"is_typing"`
+}
+
+// ReadPayload moves the user's read marker in a room up to Seq
+type ReadPayload struct {
+	RoomID int   `json:"room_id"`
+	Seq    int64 `json:"seq"`
+}
+
+// ReactPayload adds an emoji, unicode or a :short_code:, to a message
+type ReactPayload struct {
+	MessageID int    `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// UnreactPayload takes back an emoji added with react
+type UnreactPayload ReactPayload
+
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type RoomPayload struct {
+	RoomID int    `json:"room_id"`
+	Room   string `json:"room"`
+}
+
+// SubscribeResultPayload acknowledges a subscribe. Truncated means the gap was larger than
+// one replay; the client should fetch the rest with GET /messages?after_seq= from its newest
+// sequence.
+type SubscribeResultPayload struct {
+	RoomID      int    `json:"room_id"`
+	Room        string `json:"room"`
+	Replayed    int    `json:"replayed"`
+	Truncated   bool   `json:"truncated"`
+	LastSeq     int64  `json:"last_seq"`
+	LastReadSeq int64  `json:"last_read_seq"`
+	Unread      int64  `json:"unread"`
+}
+
+type TypingEventPayload struct {
+	RoomID   int    `json:"room_id"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	IsTyping bool   `json:"is_typing"`
+}
+
+// UnreadPayload is pushed whenever a subscribed room's unread count may have changed
+type UnreadPayload struct {
+	RoomID  int   `json:"room_id"`
+	Unread  int64 `json:"unread"`
+	LastSeq int64 `json:"last_seq"`
+}
+
+// ThreadUpdatedPayload is pushed to a room whenever a reply is added to or removed from one of
+// its threads, so clients can update the thread summary without loading the replies
+type ThreadUpdatedPayload = models.ThreadUpdate
+
+// RoomRemovedPayload tells a user they no longer receive a room, because they left it on
+// another connection, were kicked or were banned
+type RoomRemovedPayload struct {
+	RoomID int    `json:"room_id"`
+	Reason string `json:"reason"`
+}
+
+// Error codes carried in error frames. Service failures reuse the AppError code instead.
+const (
+	CodeMalformedFrame     = "MALFORMED_FRAME"
+	CodeUnsupportedVersion = "UNSUPPORTED_VERSION"
+	CodeUnknownFrameType   = "UNKNOWN_FRAME_TYPE"
+	CodeInvalidPayload     = "INVALID_PAYLOAD"
+	CodeNotSubscribed      = "NOT_SUBSCRIBED"
+)
+
+// ProtocolError is a frame that failed validation. ID is set whenever the envelope was
+// readable so the client can still correlate the rejection with its command.
+type ProtocolError struct {
+	ID      string
+	Code    string
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Command is a validated inbound frame. Payload holds one of the *Payload types above,
+// matching Type.
+type Command struct {
+	ID      string
+	Type    FrameType
+	Payload interface{}
+}
+
+// ParseCommand decodes and validates a client frame against the protocol schema
+func ParseCommand(data []byte) (*Command, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, &ProtocolError{Code: CodeMalformedFrame, Message: "Frame is not a valid envelope"}
+	}
+
+	if env.Version != ProtocolVersion {
+		return nil, &ProtocolError{ID: env.ID, Code: CodeUnsupportedVersion, Message: fmt.Sprintf("Protocol version %d is required", ProtocolVersion)}
+	}
+	if env.ID == "" || len(env.ID) > maxFrameIDLength {
+		return nil, &ProtocolError{Code: CodeInvalidPayload, Message: fmt.Sprintf("Frame id must be between 1 and %d characters", maxFrameIDLength)}
+	}
+
+	var payload interface{}
+	switch env.Type {
+	case FrameSend:
+		payload = &SendPayload{}
+	case FrameEdit:
+		payload = &EditPayload{}
+	case FrameDelete:
+		payload = &DeletePayload{}
+	case FrameSubscribe:
+		payload = &SubscribePayload{}
+	case FrameUnsubscribe:
+		payload = &UnsubscribePayload{}
+	case FrameTyping:
+		payload = &TypingPayload{}
+	case FrameRead:
+		payload = &ReadPayload{}
+	case FrameReact:
+		payload = &ReactPayload{}
+	case FrameUnreact:
+		payload = &UnreactPayload{}
+	default:
+		return nil, &ProtocolError{ID: env.ID, Code: CodeUnknownFrameType, Message: fmt.Sprintf("Unknown frame type %q", env.Type)}
+	}
+
+	if len(env.Payload) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(env.Payload))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(payload); err != nil {
+			return nil, &ProtocolError{ID: env.ID, Code: CodeInvalidPayload, Message: err.Error()}
+		}
+	}
+
+	if msg := validatePayload(payload); msg != "" {
+		return nil, &ProtocolError{ID: env.ID, Code: CodeInvalidPayload, Message: msg}
+	}
+
+	return &Command{ID: env.ID, Type: env.Type, Payload: payload}, nil
+}
+
+func validatePayload(payload interface{}) string {
+	switch p := payload.(type) {
+	case *SendPayload:
+		p.Content = strings.TrimSpace(p.Content)
+		if p.RoomID <= 0 {
+			return "room_id is required"
+		}
+		if p.ParentID != nil && *p.ParentID <= 0 {
+			return "parent_id must be positive"
+		}
+		if len(p.AttachmentIDs) > models.MaxMessageAttachments {
+			return fmt.Sprintf("at most %d attachment_ids are allowed", models.MaxMessageAttachments)
+		}
+		// A message carrying attachments may go without text
+		if len(p.AttachmentIDs) > 0 && p.Content == "" {
+			return ""
+		}
+		return validateContent(p.Content)
+	case *EditPayload:
+		p.Content = strings.TrimSpace(p.Content)
+		if p.MessageID <= 0 {
+			return "message_id is required"
+		}
+		return validateContent(p.Content)
+	case *DeletePayload:
+		if p.MessageID <= 0 {
+			return "message_id is required"
+		}
+	case *SubscribePayload:
+		p.Room = strings.TrimSpace(p.Room)
+		if p.RoomID < 0 || (p.RoomID == 0 && p.Room == "") {
+			return "room_id or room is required"
+		}
+		if len(p.Room) > 100 {
+			return "room must be at most 100 characters"
+		}
+		if p.LastSeq != nil && *p.LastSeq < 0 {
+			return "last_seq must not be negative"
+		}
+	case *UnsubscribePayload:
+		if p.RoomID <= 0 {
+			return "room_id is required"
+		}
+	case *TypingPayload:
+		if p.RoomID <= 0 {
+			return "room_id is required"
+		}
+	case *ReadPayload:
+		if p.RoomID <= 0 {
+			return "room_id is required"
+		}
+		if p.Seq < 0 {
+			return "seq must not be negative"
+		}
+	case *ReactPayload:
+		return validateReaction(p.MessageID, p.Emoji)
+	case *UnreactPayload:
+		return validateReaction(p.MessageID, p.Emoji)
+	}
+	return ""
+}
+
+func validateReaction(messageID int, emoji string) string {
+	if messageID <= 0 {
+		return "message_id is required"
+	}
+	if !models.ValidReaction(emoji) {
+		return "emoji must be a unicode emoji or a :short_code:"
+	}
+	return ""
+}
+
+func validateContent(content string) string {
+	if content == "" || len(content) > maxContentLength {
+		return fmt.Sprintf("content must be between 1 and %d characters", maxContentLength)
+	}
+	return ""
+}
+
+// newFrame encodes a server frame. Payloads are plain structs so encoding cannot fail in
+// practice; an error is still returned rather than hidden.
+func newFrame(frameType FrameType, id string, payload interface{}) ([]byte, error) {
+	env := Envelope{Version: ProtocolVersion, Type: frameType, ID: id, Timestamp: time.Now().UTC()}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		env.Payload = raw
+	}
+	return json.Marshal(env)
+}
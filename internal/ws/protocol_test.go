@@ -0,0 +1,77 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommandDecodesTypedPayload(t *testing.T) {
+	cmd, err := ParseCommand([]byte(`{"v":2,"type":"send","id":"c-1","payload":{"room_id":4,"content":"  hello  "}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "c-1", cmd.ID)
+	assert.Equal(t, FrameSend, cmd.Type)
+	payload, ok := cmd.Payload.(*SendPayload)
+	require.True(t, ok)
+	assert.Equal(t, 4, payload.RoomID)
+	assert.Equal(t, "hello", payload.Content)
+}
+
+func TestParseCommandAllowsAttachmentsWithoutContent(t *testing.T) {
+	cmd, err := ParseCommand([]byte(`{"v":2,"type":"send","id":"c-2","payload":{"room_id":4,"content":" ","attachment_ids":[7]}}`))
+	require.NoError(t, err)
+
+	payload, ok := cmd.Payload.(*SendPayload)
+	require.True(t, ok)
+	assert.Equal(t, []int{7}, payload.AttachmentIDs)
+	assert.Empty(t, payload.Content)
+}
+
+func TestParseCommandRejectsInvalidFrames(t *testing.T) {
+	cases := map[string]struct {
+		frame string
+		id    string
+		code  string
+	}{
+		"not json":        {`hello`, "", CodeMalformedFrame},
+		"wrong version":   {`{"v":1,"type":"send","id":"a","payload":{"room_id":1,"content":"x"}}`, "a", CodeUnsupportedVersion},
+		"missing id":      {`{"v":2,"type":"send","payload":{"content":"x"}}`, "", CodeInvalidPayload},
+		"unknown type":    {`{"v":2,"type":"shout","id":"b"}`, "b", CodeUnknownFrameType},
+		"server frame":    {`{"v":2,"type":"ack","id":"c"}`, "c", CodeUnknownFrameType},
+		"empty content":   {`{"v":2,"type":"send","id":"d","payload":{"room_id":1,"content":"   "}}`, "d", CodeInvalidPayload},
+		"unknown field":   {`{"v":2,"type":"delete","id":"e","payload":{"message_id":1,"force":true}}`, "e", CodeInvalidPayload},
+		"missing message": {`{"v":2,"type":"edit","id":"f","payload":{"content":"x"}}`, "f", CodeInvalidPayload},
+		"send no room":    {`{"v":2,"type":"send","id":"g","payload":{"content":"x"}}`, "g", CodeInvalidPayload},
+		"subscribe none":  {`{"v":2,"type":"subscribe","id":"h","payload":{}}`, "h", CodeInvalidPayload},
+		"negative read":   {`{"v":2,"type":"read","id":"i","payload":{"room_id":1,"seq":-1}}`, "i", CodeInvalidPayload},
+		"zero parent":     {`{"v":2,"type":"send","id":"j","payload":{"room_id":1,"content":"x","parent_id":0}}`, "j", CodeInvalidPayload},
+		"text reaction":   {`{"v":2,"type":"react","id":"k","payload":{"message_id":1,"emoji":"lol"}}`, "k", CodeInvalidPayload},
+		"unreact no msg":  {`{"v":2,"type":"unreact","id":"l","payload":{"emoji":":+1:"}}`, "l", CodeInvalidPayload},
+		"many files":      {`{"v":2,"type":"send","id":"m","payload":{"room_id":1,"attachment_ids":[1,2,3,4,5,6,7,8,9,10,11]}}`, "m", CodeInvalidPayload},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCommand([]byte(tc.frame))
+			var protocolErr *ProtocolError
+			require.ErrorAs(t, err, &protocolErr)
+			assert.Equal(t, tc.code, protocolErr.Code)
+			assert.Equal(t, tc.id, protocolErr.ID)
+		})
+	}
+}
+
+func TestNewFrameCarriesVersionAndID(t *testing.T) {
+	data, err := newFrame(FrameAck, "c-9", RoomPayload{RoomID: 3, Room: "General"})
+	require.NoError(t, err)
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(data, &env))
+	assert.Equal(t, ProtocolVersion, env.Version)
+	assert.Equal(t, FrameAck, env.Type)
+	assert.Equal(t, "c-9", env.ID)
+	assert.JSONEq(t, `{"room_id":3,"room":"General"}`, string(env.Payload))
+}
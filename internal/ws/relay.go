@@ -0,0 +1,262 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"chat_app/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// relayEnvelope is what hubs exchange through Redis. Origin and ID identify a message across
+// the cluster so every instance delivers it to its own clients exactly once.
+type relayEnvelope struct {
+	Origin string          `json:"origin"`
+	ID     string          `json:"id"`
+	Room   int             `json:"room"`
+	Seq    int64           `json:"seq,omitempty"`
+	Sender int             `json:"sender,omitempty"`
+	User   int             `json:"user,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// transport carries broadcasts between hub instances
+type transport interface {
+	// start begins handing envelopes published by other instances to receive, until ctx ends
+	start(ctx context.Context, receive func(*relayEnvelope)) error
+	publish(ctx context.Context, envelope *relayEnvelope) error
+	// watch and unwatch report which rooms have local clients. They are called from the
+	// hub goroutine and must not block.
+	watch(room int)
+	unwatch(room int)
+}
+
+// backfiller is implemented by transports that retain history
+type backfiller interface {
+	// backfill returns the frames of room's messages after afterSeq, oldest first. ok is
+	// false when the retained history cannot prove the result is complete.
+	backfill(ctx context.Context, room int, afterSeq int64, limit int) (frames [][]byte, ok bool, err error)
+}
+
+const (
+	outboundBufferSize = 4096
+	// relayDedupWindow is how many recent relay IDs are remembered to drop redeliveries
+	relayDedupWindow = 4096
+
+	TransportPubSub  = "pubsub"
+	TransportStreams = "streams"
+)
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic("ws: failed to generate instance ID: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// EnableRedis shares broadcasts with other instances over the transport selected in cfg.
+// The transport is listening before it returns, so nothing published afterwards is missed,
+// and stops when ctx ends. Call it before Run.
+func (h *Hub) EnableRedis(ctx context.Context, client *redis.Client, cfg config.RedisConfig) error {
+	if client == nil {
+		return nil
+	}
+
+	var t transport
+	switch cfg.Transport {
+	case "", TransportPubSub:
+		t = newPubSubTransport(client)
+	case TransportStreams:
+		streams, err := newStreamTransport(client, cfg)
+		if err != nil {
+			return err
+		}
+		t = streams
+	default:
+		return fmt.Errorf("unknown redis transport %q", cfg.Transport)
+	}
+
+	if err := t.start(ctx, h.receive); err != nil {
+		return err
+	}
+
+	h.pubsub = client
+	h.transport = t
+	h.seen = newRecentIDs(relayDedupWindow)
+	h.outbound = make(chan *relayEnvelope, outboundBufferSize)
+	go h.publishLoop(ctx)
+	return nil
+}
+
+// relay queues a local broadcast for the other instances. It runs on the hub goroutine, so
+// publishing happens elsewhere and a slow Redis can only cost relayed messages, not the hub.
+func (h *Hub) relay(msg *messageEnvelope) {
+	if h.outbound == nil {
+		return
+	}
+
+	h.relayed++
+	envelope := &relayEnvelope{
+		Origin: h.instanceID,
+		ID:     h.instanceID + "-" + strconv.FormatUint(h.relayed, 10),
+		Room:   msg.room,
+		Seq:    msg.seq,
+		Sender: msg.sender,
+		User:   msg.user,
+		Data:   msg.data,
+	}
+
+	select {
+	case h.outbound <- envelope:
+	default:
+	}
+}
+
+func (h *Hub) publishLoop(ctx context.Context) {
+	for {
+		select {
+		case envelope := <-h.outbound:
+			_ = h.transport.publish(ctx, envelope)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// receive feeds an envelope from another instance into the hub unless it is our own echo
+// or a redelivery
+func (h *Hub) receive(envelope *relayEnvelope) {
+	if envelope.ID == "" || envelope.Origin == h.instanceID {
+		return
+	}
+
+	h.seenMu.Lock()
+	fresh := h.seen.add(envelope.ID)
+	h.seenMu.Unlock()
+	if !fresh {
+		return
+	}
+
+	h.broadcast <- &messageEnvelope{
+		room:   envelope.Room,
+		seq:    envelope.Seq,
+		sender: envelope.Sender,
+		user:   envelope.User,
+		data:   envelope.Data,
+		origin: envelope.Origin,
+	}
+}
+
+// Backfill loads a room's messages after afterSeq from the transport's retained history.
+// ok is false when the transport keeps no history or cannot cover the whole gap, in which
+// case the caller should fall back to the database.
+func (h *Hub) Backfill(ctx context.Context, room int, afterSeq int64, limit int) ([][]byte, bool) {
+	b, supported := h.transport.(backfiller)
+	if !supported {
+		return nil, false
+	}
+
+	frames, ok, err := b.backfill(ctx, room, afterSeq, limit)
+	if err != nil || !ok {
+		return nil, false
+	}
+	return frames, true
+}
+
+// contiguousAfter orders envelopes by sequence and checks they are exactly the messages
+// afterSeq+1, afterSeq+2, ... with none missing
+func contiguousAfter(envelopes []*relayEnvelope, afterSeq int64) ([][]byte, bool) {
+	sort.Slice(envelopes, func(i, j int) bool { return envelopes[i].Seq < envelopes[j].Seq })
+
+	frames := make([][]byte, 0, len(envelopes))
+	expected := afterSeq + 1
+	for _, envelope := range envelopes {
+		if envelope.Seq != expected {
+			return nil, false
+		}
+		frames = append(frames, envelope.Data)
+		expected++
+	}
+	return frames, true
+}
+
+// recentIDs is a fixed-size set that forgets the oldest ID once full
+type recentIDs struct {
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{ids: make(map[string]struct{}, size), order: make([]string, size)}
+}
+
+// add records id and reports whether it was new
+func (r *recentIDs) add(id string) bool {
+	if _, ok := r.ids[id]; ok {
+		return false
+	}
+	if old := r.order[r.next]; old != "" {
+		delete(r.ids, old)
+	}
+	r.order[r.next] = id
+	r.next = (r.next + 1) % len(r.order)
+	r.ids[id] = struct{}{}
+	return true
+}
+
+// pubSubTransport is fire-and-forget: every instance receives every room and anything
+// published while an instance is down or disconnected is lost to it
+type pubSubTransport struct {
+	client *redis.Client
+}
+
+func newPubSubTransport(client *redis.Client) *pubSubTransport {
+	return &pubSubTransport{client: client}
+}
+
+func (t *pubSubTransport) start(ctx context.Context, receive func(*relayEnvelope)) error {
+	p := t.client.PSubscribe(ctx, channelPrefix+"*")
+	if _, err := p.Receive(ctx); err != nil {
+		p.Close()
+		return err
+	}
+
+	// Closing the subscription ends its channel and with it the loop below
+	go func() {
+		<-ctx.Done()
+		p.Close()
+	}()
+	go func() {
+		for msg := range p.Channel() {
+			var envelope relayEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				continue
+			}
+			receive(&envelope)
+		}
+	}()
+	return nil
+}
+
+func (t *pubSubTransport) publish(ctx context.Context, envelope *relayEnvelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	channel := channelName(envelope.Room)
+	if envelope.User != 0 {
+		channel = userChannelName(envelope.User)
+	}
+	return t.client.Publish(ctx, channel, payload).Err()
+}
+
+func (t *pubSubTransport) watch(room int)   {}
+func (t *pubSubTransport) unwatch(room int) {}
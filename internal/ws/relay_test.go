@@ -0,0 +1,222 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"chat_app/internal/config"
+	"chat_app/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startRelayedHub runs a hub wired to the shared Redis with one client in room 1. The client
+// is attached before Run starts so the test does not race the hub goroutine.
+func startRelayedHub(t *testing.T, server *miniredis.Miniredis) (*Hub, *Client) {
+	return startHubWithTransport(t, server, config.RedisConfig{}, 1)
+}
+
+func startHubWithTransport(t *testing.T, server *miniredis.Miniredis, cfg config.RedisConfig, room int) (*Hub, *Client) {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, hub.EnableRedis(ctx, rdb, cfg))
+
+	client := &Client{send: make(chan []byte, 64), user: &models.User{ID: 100 + room}}
+	hub.apply(&subscription{action: actionConnect, client: client})
+	hub.apply(&subscription{action: actionJoin, client: client, room: room})
+
+	go hub.Run(ctx)
+	return hub, client
+}
+
+// collect gathers everything a client receives until the line has been quiet for a while.
+// Unread counts are left to the hub tests.
+func collect(c *Client) []string {
+	var frames []string
+	for {
+		select {
+		case frame := <-c.send:
+			if !isUnreadFrame(frame) {
+				frames = append(frames, string(frame))
+			}
+		case <-time.After(300 * time.Millisecond):
+			return frames
+		}
+	}
+}
+
+func TestRedisFanOutDeliversExactlyOnceAcrossInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	hubA, clientA := startRelayedHub(t, server)
+	hubB, clientB := startRelayedHub(t, server)
+
+	hubA.BroadcastMessage(1, 1, 0, []byte(`{"from":"a"}`))
+	hubB.Broadcast(1, []byte(`{"from":"b"}`))
+
+	assert.ElementsMatch(t, []string{`{"from":"a"}`, `{"from":"b"}`}, collect(clientA))
+	assert.ElementsMatch(t, []string{`{"from":"a"}`, `{"from":"b"}`}, collect(clientB))
+}
+
+func TestRedisFanOutDropsRedeliveredEnvelopes(t *testing.T) {
+	server := miniredis.RunT(t)
+	_, client := startRelayedHub(t, server)
+
+	payload, err := json.Marshal(relayEnvelope{Origin: "other", ID: "other-1", Room: 1, Data: json.RawMessage(`{"n":1}`)})
+	require.NoError(t, err)
+
+	publisher := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer publisher.Close()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, publisher.Publish(context.Background(), channelName(1), payload).Err())
+	}
+
+	assert.Equal(t, []string{`{"n":1}`}, collect(client))
+}
+
+func streamsConfig(group string) config.RedisConfig {
+	return config.RedisConfig{Transport: TransportStreams, StreamMaxLen: 100, ConsumerGroup: group}
+}
+
+func TestStreamsFanOutDeliversExactlyOnceAndOnlyReadsWatchedRooms(t *testing.T) {
+	server := miniredis.RunT(t)
+	hubA, clientA := startHubWithTransport(t, server, streamsConfig("a"), 1)
+	hubB, clientB := startHubWithTransport(t, server, streamsConfig("b"), 1)
+	_, clientC := startHubWithTransport(t, server, streamsConfig("c"), 2)
+
+	// Let every read loop position its group before anything is published
+	require.Eventually(t, func() bool {
+		return server.Exists("chat:stream:room:1") && server.Exists("chat:stream:room:2")
+	}, 2*time.Second, 10*time.Millisecond)
+
+	hubA.BroadcastMessage(1, 1, 0, []byte(`{"from":"a"}`))
+	hubB.BroadcastMessage(1, 2, 0, []byte(`{"from":"b"}`))
+
+	assert.ElementsMatch(t, []string{`{"from":"a"}`, `{"from":"b"}`}, collect(clientA))
+	assert.ElementsMatch(t, []string{`{"from":"a"}`, `{"from":"b"}`}, collect(clientB))
+	assert.Empty(t, collect(clientC))
+
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+	groups, err := rdb.XInfoGroups(context.Background(), "chat:stream:room:1").Result()
+	require.NoError(t, err)
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, names)
+}
+
+func TestStreamsTransportRequiresConsumerGroup(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+
+	assert.Error(t, NewHub().EnableRedis(context.Background(), rdb, streamsConfig("")))
+}
+
+func TestStreamsGroupResumesAfterRestart(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	// The group read up to 1000-0 before the instance went down; 2000-0 came in meanwhile
+	require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: "chat:stream:room:1", ID: "1000-0",
+		Values: map[string]interface{}{"origin": "x", "id": "x-1", "room": 1, "seq": 1, "data": `{"n":1}`}}).Err())
+	require.NoError(t, rdb.XGroupCreate(ctx, "chat:stream:room:1", "a", "1000-0").Err())
+	require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: "chat:stream:room:1", ID: "2000-0",
+		Values: map[string]interface{}{"origin": "x", "id": "x-2", "room": 1, "seq": 2, "data": `{"n":2}`}}).Err())
+
+	_, client := startHubWithTransport(t, server, streamsConfig("a"), 1)
+	assert.Equal(t, []string{`{"n":2}`}, collect(client))
+}
+
+func TestStreamsBackfillCoversOnlyCompleteGaps(t *testing.T) {
+	server := miniredis.RunT(t)
+	hub, _ := startHubWithTransport(t, server, streamsConfig("a"), 1)
+
+	for seq := int64(1); seq <= 3; seq++ {
+		hub.BroadcastMessage(1, seq, 0, []byte(`{"seq":`+strconv.FormatInt(seq, 10)+`}`))
+	}
+	require.Eventually(t, func() bool {
+		entries, err := server.Stream("chat:stream:room:1")
+		return err == nil && len(entries) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	frames, ok := hub.Backfill(context.Background(), 1, 1, 10)
+	require.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte(`{"seq":2}`), []byte(`{"seq":3}`)}, frames)
+
+	// Nothing in the stream proves message 1 is the room's first, so the database must answer
+	_, ok = hub.Backfill(context.Background(), 1, 0, 10)
+	assert.False(t, ok)
+
+	// Gaps larger than one replay are left to the database as well
+	_, ok = hub.Backfill(context.Background(), 1, 1, 1)
+	assert.False(t, ok)
+}
+
+func TestStreamsBackfillLeavesChangedMessagesToTheDatabase(t *testing.T) {
+	server := miniredis.RunT(t)
+	hub, _ := startHubWithTransport(t, server, streamsConfig("a"), 1)
+
+	for seq := 1; seq <= 3; seq++ {
+		hub.NotifyMessage(&models.Message{ID: 100 + seq, RoomID: 1, Seq: int64(seq), Content: "first"})
+	}
+	waitForEntries := func(n int) {
+		require.Eventually(t, func() bool {
+			entries, err := server.Stream("chat:stream:room:1")
+			return err == nil && len(entries) == n
+		}, 2*time.Second, 10*time.Millisecond)
+	}
+	waitForEntries(3)
+
+	frames, ok := hub.Backfill(context.Background(), 1, 1, 10)
+	require.True(t, ok)
+	assert.Len(t, frames, 2)
+
+	// Editing a message before the gap changes nothing the replay holds
+	hub.NotifyRoom(1, "message_edited", &models.Message{ID: 101, RoomID: 1, Seq: 1, Content: "edited"})
+	waitForEntries(4)
+	_, ok = hub.Backfill(context.Background(), 1, 1, 10)
+	assert.True(t, ok)
+
+	hub.NotifyRoom(1, "message_deleted", &models.MessageDeleted{MessageID: 103, RoomID: 1, DeletedAt: time.Now()})
+	waitForEntries(5)
+	_, ok = hub.Backfill(context.Background(), 1, 1, 10)
+	assert.False(t, ok)
+}
+
+func TestUserEventsReachOtherInstancesOverEitherTransport(t *testing.T) {
+	for name, cfg := range map[string]func(string) config.RedisConfig{
+		"pubsub":  func(string) config.RedisConfig { return config.RedisConfig{} },
+		"streams": streamsConfig,
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			hubA, _ := startHubWithTransport(t, server, cfg("a"), 1)
+			_, clientB := startHubWithTransport(t, server, cfg("b"), 2)
+
+			if name == "streams" {
+				require.Eventually(t, func() bool { return server.Exists(userStreamKey) }, 2*time.Second, 10*time.Millisecond)
+			}
+
+			// clientB is user 102 and in no room hubA knows about
+			hubA.SendToUser(102, []byte(`{"to":"b"}`))
+			hubA.SendToUser(101, []byte(`{"to":"a"}`))
+			assert.Equal(t, []string{`{"to":"b"}`}, collect(clientB))
+		})
+	}
+}
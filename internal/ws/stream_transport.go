@@ -0,0 +1,418 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chat_app/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	streamKeyPrefix = "chat:stream:"
+	streamConsumer  = "hub"
+	// streamBlock bounds how long a read waits, and so how quickly a newly watched stream is
+	// added to the read set. Entries are never missed meanwhile, only delayed.
+	streamBlock     = 500 * time.Millisecond
+	streamReadCount = 100
+	streamRetry     = time.Second
+	backfillPage    = 200
+	defaultMaxLen   = 10000
+)
+
+// streamTransport relays broadcasts through Redis Streams. Each instance reads with its own
+// consumer group, so every instance sees every entry while a restarted or lagging instance
+// picks up where its group left off. Only streams of rooms with local clients are read, plus
+// the one stream carrying events addressed to users.
+type streamTransport struct {
+	client *redis.Client
+	group  string
+	maxLen int64
+	shards int
+
+	mu sync.Mutex
+	// watched counts local rooms per stream key; a sharded stream serves several rooms
+	watched map[string]int
+	// positions holds streams whose group may still have to be created, starting at the time
+	// they were first watched
+	positions map[string]time.Time
+	// read marks streams this instance has read since it started
+	read map[string]bool
+	// stale holds streams watched again after a pause, with the time they were watched again.
+	// Their group resumes where it stopped, so entries from the pause are skipped.
+	stale   map[string]time.Time
+	changed chan struct{}
+}
+
+// userStreamKey carries events addressed to users rather than rooms. Every instance reads it,
+// since any of them may hold one of the user's connections.
+const userStreamKey = streamKeyPrefix + "users"
+
+// newStreamTransport needs a consumer group that survives restarts. A group named afresh on
+// every start would never resume where the last one stopped and would be left behind in Redis.
+func newStreamTransport(client *redis.Client, cfg config.RedisConfig) (*streamTransport, error) {
+	if cfg.ConsumerGroup == "" {
+		return nil, errors.New("redis streams transport needs a consumer group, set REDIS_CONSUMER_GROUP")
+	}
+	maxLen := int64(cfg.StreamMaxLen)
+	if maxLen <= 0 {
+		maxLen = defaultMaxLen
+	}
+
+	return &streamTransport{
+		client:    client,
+		group:     cfg.ConsumerGroup,
+		maxLen:    maxLen,
+		shards:    cfg.StreamShards,
+		watched:   map[string]int{userStreamKey: 1},
+		positions: map[string]time.Time{userStreamKey: time.Now()},
+		read:      map[string]bool{},
+		stale:     map[string]time.Time{},
+		changed:   make(chan struct{}, 1),
+	}, nil
+}
+
+func (t *streamTransport) streamKey(room int) string {
+	if t.shards > 0 {
+		return fmt.Sprintf("%sshard:%d", streamKeyPrefix, room%t.shards)
+	}
+	return fmt.Sprintf("%sroom:%d", streamKeyPrefix, room)
+}
+
+func (t *streamTransport) start(ctx context.Context, receive func(*relayEnvelope)) error {
+	if err := t.client.Ping(ctx).Err(); err != nil {
+		return err
+	}
+	go t.readLoop(ctx, receive)
+	return nil
+}
+
+func (t *streamTransport) publish(ctx context.Context, envelope *relayEnvelope) error {
+	key := t.streamKey(envelope.Room)
+	if envelope.User != 0 {
+		key = userStreamKey
+	}
+	return t.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: t.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"origin": envelope.Origin,
+			"id":     envelope.ID,
+			"room":   envelope.Room,
+			"seq":    envelope.Seq,
+			"sender": envelope.Sender,
+			"user":   envelope.User,
+			"data":   string(envelope.Data),
+		},
+	}).Err()
+}
+
+func (t *streamTransport) watch(room int) {
+	key := t.streamKey(room)
+
+	t.mu.Lock()
+	t.watched[key]++
+	if t.watched[key] == 1 {
+		now := time.Now()
+		t.positions[key] = now
+		if t.read[key] {
+			t.stale[key] = now
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (t *streamTransport) unwatch(room int) {
+	key := t.streamKey(room)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.watched[key] <= 1 {
+		delete(t.watched, key)
+		delete(t.positions, key)
+		delete(t.stale, key)
+		return
+	}
+	t.watched[key]--
+}
+
+// snapshot returns the streams to read, first positioning the group of any newly watched one
+func (t *streamTransport) snapshot(ctx context.Context) []string {
+	t.mu.Lock()
+	pending := make(map[string]time.Time, len(t.positions))
+	for key, since := range t.positions {
+		pending[key] = since
+	}
+	t.mu.Unlock()
+
+	for key, since := range pending {
+		if err := t.positionGroup(ctx, key, since); err != nil {
+			continue
+		}
+		t.mu.Lock()
+		// The stream may have been unwatched and watched again meanwhile
+		if current, ok := t.positions[key]; ok && current.Equal(since) {
+			delete(t.positions, key)
+		}
+		t.mu.Unlock()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]string, 0, len(t.watched))
+	for key := range t.watched {
+		if _, waiting := t.positions[key]; !waiting {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// positionGroup creates this instance's group at the moment the stream was first watched, so
+// entries from before the room had local clients are skipped and none after it are missed.
+// A group that already exists resumes from the last entry it delivered, which is how a
+// restarted instance catches up on what it missed. A second of slack absorbs clock skew; the
+// hub drops anything delivered twice.
+func (t *streamTransport) positionGroup(ctx context.Context, key string, since time.Time) error {
+	start := streamID(since)
+
+	err := t.client.XGroupCreateMkStream(ctx, key, t.group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func streamID(since time.Time) string {
+	return fmt.Sprintf("%d-0", since.Add(-time.Second).UnixMilli())
+}
+
+// fresh reports whether an entry read from key should be delivered, dropping the ones a stream
+// watched again accumulated while it had no local clients
+func (t *streamTransport) fresh(key, id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.read[key] = true
+	since, ok := t.stale[key]
+	if !ok {
+		return true
+	}
+	if ms, err := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64); err == nil && ms < since.Add(-time.Second).UnixMilli() {
+		return false
+	}
+	delete(t.stale, key)
+	return true
+}
+
+func (t *streamTransport) readLoop(ctx context.Context, receive func(*relayEnvelope)) {
+	for ctx.Err() == nil {
+		keys := t.snapshot(ctx)
+		if len(keys) == 0 {
+			select {
+			case <-t.changed:
+			case <-time.After(streamRetry):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		streams := make([]string, 0, 2*len(keys))
+		streams = append(streams, keys...)
+		for range keys {
+			streams = append(streams, ">")
+		}
+
+		results, err := t.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    t.group,
+			Consumer: streamConsumer,
+			Streams:  streams,
+			Count:    streamReadCount,
+			Block:    streamBlock,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil {
+				if strings.HasPrefix(err.Error(), "NOGROUP") {
+					t.repositionAll()
+				}
+				select {
+				case <-time.After(streamRetry):
+				case <-ctx.Done():
+				}
+			}
+			continue
+		}
+
+		for _, stream := range results {
+			ids := make([]string, 0, len(stream.Messages))
+			for _, message := range stream.Messages {
+				if !t.fresh(stream.Stream, message.ID) {
+					ids = append(ids, message.ID)
+					continue
+				}
+				if envelope, ok := decodeStreamEntry(message.Values); ok {
+					receive(envelope)
+				}
+				ids = append(ids, message.ID)
+			}
+			if len(ids) > 0 {
+				_ = t.client.XAck(ctx, stream.Stream, t.group, ids...).Err()
+			}
+		}
+	}
+}
+
+// repositionAll recreates groups after Redis lost them, e.g. when a stream key was deleted
+func (t *streamTransport) repositionAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for key := range t.watched {
+		t.positions[key] = now
+	}
+}
+
+// backfill walks the room's stream from the newest entry back to afterSeq. The result is
+// only trusted when it holds every sequence number in the gap; messages that never went
+// through the hub, or were trimmed away, make the caller fall back to the database. So does
+// a message changed since it was sent, which the stream only holds as it first was.
+func (t *streamTransport) backfill(ctx context.Context, room int, afterSeq int64, limit int) ([][]byte, bool, error) {
+	key := t.streamKey(room)
+	end := "+"
+	var found []*relayEnvelope
+	changed := map[int]bool{}
+
+	for {
+		entries, err := t.client.XRevRangeN(ctx, key, end, "-", backfillPage).Result()
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, entry := range entries {
+			envelope, ok := decodeStreamEntry(entry.Values)
+			if !ok || envelope.Room != room {
+				continue
+			}
+			if envelope.Seq == 0 {
+				if id := changedMessage(envelope.Data); id != 0 {
+					changed[id] = true
+				}
+				continue
+			}
+			if envelope.Seq <= afterSeq {
+				for _, message := range found {
+					if changed[sentMessage(message.Data)] {
+						return nil, false, nil
+					}
+				}
+				frames, complete := contiguousAfter(found, afterSeq)
+				return frames, complete, nil
+			}
+			found = append(found, envelope)
+			if len(found) > limit {
+				return nil, false, nil
+			}
+		}
+
+		if len(entries) < backfillPage {
+			return nil, false, nil
+		}
+		end = previousStreamID(entries[len(entries)-1].ID)
+		if end == "" {
+			return nil, false, nil
+		}
+	}
+}
+
+// frameRef holds the fields room events use to name the message they are about
+type frameRef struct {
+	ID        int `json:"id"`
+	MessageID int `json:"message_id"`
+	ParentID  int `json:"parent_id"`
+}
+
+func decodeFrameRef(data []byte) (FrameType, frameRef) {
+	var frame Envelope
+	var ref frameRef
+	if json.Unmarshal(data, &frame) != nil || json.Unmarshal(frame.Payload, &ref) != nil {
+		return "", frameRef{}
+	}
+	return frame.Type, ref
+}
+
+// changedMessage returns the message an event edited, deleted, reacted to or replied to, or
+// zero for events that change no message
+func changedMessage(data []byte) int {
+	kind, ref := decodeFrameRef(data)
+	switch kind {
+	case EventMessageEdited:
+		return ref.ID
+	case EventMessageDeleted, EventReactionAdded, EventReactionRemoved:
+		return ref.MessageID
+	case EventThreadUpdated:
+		return ref.ParentID
+	}
+	return 0
+}
+
+// sentMessage returns the ID of the message a message frame carries
+func sentMessage(data []byte) int {
+	if kind, ref := decodeFrameRef(data); kind == EventMessage {
+		return ref.ID
+	}
+	return 0
+}
+
+func decodeStreamEntry(values map[string]interface{}) (*relayEnvelope, bool) {
+	origin, _ := values["origin"].(string)
+	id, _ := values["id"].(string)
+	room, err := strconv.Atoi(fmt.Sprint(values["room"]))
+	if err != nil || id == "" {
+		return nil, false
+	}
+	seq, err := strconv.ParseInt(fmt.Sprint(values["seq"]), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	sender, _ := strconv.Atoi(fmt.Sprint(values["sender"]))
+	user, _ := strconv.Atoi(fmt.Sprint(values["user"]))
+	data, _ := values["data"].(string)
+
+	return &relayEnvelope{Origin: origin, ID: id, Room: room, Seq: seq, Sender: sender, User: user, Data: []byte(data)}, true
+}
+
+// previousStreamID returns the largest entry ID below id, for an inclusive XREVRANGE bound
+func previousStreamID(id string) string {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return ""
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return ""
+	}
+
+	if seq > 0 {
+		return fmt.Sprintf("%d-%d", ms, seq-1)
+	}
+	if ms == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", ms-1, uint64(1<<64-1))
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
 )
@@ -17,6 +18,7 @@ const (
 	ErrCodeDatabaseError     ErrorCode = "DATABASE_ERROR"
 	ErrCodeValidationError   ErrorCode = "VALIDATION_ERROR"
 	ErrCodeRateLimitExceeded ErrorCode = "RATE_LIMIT_EXCEEDED"
+	ErrCodeTooLarge          ErrorCode = "TOO_LARGE"
 )
 
 type AppError struct {
@@ -118,3 +120,18 @@ func NewRateLimitError(message string, cause error) *AppError {
 		Cause:      cause,
 	}
 }
+
+func NewTooLargeError(message string, cause error) *AppError {
+	return &AppError{
+		Code:       ErrCodeTooLarge,
+		Message:    message,
+		HTTPStatus: http.StatusRequestEntityTooLarge,
+		Cause:      cause,
+	}
+}
+
+// IsNotFound reports whether err, or an error it wraps, is a not-found AppError
+func IsNotFound(err error) bool {
+	var appErr *AppError
+	return stderrors.As(err, &appErr) && appErr.Code == ErrCodeNotFound
+}
@@ -10,6 +10,11 @@ class ChatApp {
     this.messageHistory = [];
     this.isTyping = false;
     this.typingUsers = new Set();
+    // One connection carries every room the user has opened: name -> id and back
+    this.roomIds = {};
+    this.roomNames = {};
+    this.lastSeq = {};
+    this.pendingCommands = {};
 
     this.initializeElements();
     this.loadUserData();
@@ -117,9 +122,12 @@ class ChatApp {
     }
 
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+    // The room is joined from onopen so reconnects can pass last_seq
     const wsUrl = `${protocol}//${window.location.host}/ws`;
+    const token = Utils.getStorage('authToken');
 
-    this.ws = new WebSocket(wsUrl);
+    // Browsers cannot set an Authorization header on the handshake, so the token rides along as a subprotocol
+    this.ws = new WebSocket(wsUrl, token ? ['chat', `bearer.${token}`] : ['chat']);
     this.updateConnectionStatus('connecting');
 
     this.ws.onopen = () => {
@@ -127,9 +135,10 @@ class ChatApp {
       this.updateConnectionStatus('connected');
       Utils.showNotification('Connected to server', 'success');
 
-      if (this.currentRoom) {
-        this.joinRoom(this.currentRoom);
-      }
+      // Resubscribe to every open room so each replays what it missed
+      const rooms = new Set(Object.keys(this.roomIds));
+      if (this.currentRoom) rooms.add(this.currentRoom);
+      rooms.forEach(room => this.joinRoom(room));
     };
 
     this.ws.onmessage = (event) => {
@@ -240,14 +249,16 @@ class ChatApp {
 
   joinRoom(roomName) {
     if (this.ws && this.ws.readyState === WebSocket.OPEN) {
-      const joinMessage = {
-        type: 'join',
-        room: roomName,
-        user: this.user
-      };
-
-      this.ws.send(JSON.stringify(joinMessage));
-      console.log(`Joining room: ${roomName}`);
+      // Resume from the last message seen in this room so nothing sent while offline is lost
+      const lastSeq = this.lastSeq[roomName];
+      const payload = lastSeq === undefined ? { room: roomName } : { room: roomName, last_seq: lastSeq };
+      this.sendCommand('subscribe', payload, (result) => {
+        this.roomIds[result.room] = result.room_id;
+        this.roomNames[result.room_id] = result.room;
+        this.updateUnreadBadge(result.room, result.unread);
+        if (result.room === this.currentRoom) this.markRead(result.room_id, result.last_seq);
+      });
+      console.log(`Subscribing to room: ${roomName}`);
 
       // Request message history
       this.requestMessageHistory(roomName);
@@ -279,16 +290,10 @@ class ChatApp {
       return;
     }
 
-    const message = {
-      type: 'message',
-      room: this.currentRoom,
-      content: content,
-      sender: this.user.username,
-      timestamp: new Date()
-    };
-
     try {
-      this.ws.send(JSON.stringify(message));
+      const roomId = this.roomIds[this.currentRoom];
+      if (!roomId) return;
+      this.sendCommand('send', { room_id: roomId, content });
       this.messageInput.value = '';
       this.autoResizeTextarea();
       this.stopTyping();
@@ -298,24 +303,86 @@ class ChatApp {
     }
   }
 
-  handleMessage(message) {
-    console.log('Received message:', message);
+  // Every frame is a protocol envelope: { v, type, id, payload, ts }. onAck, if given, is
+  // called with the ack's payload.
+  sendCommand(type, payload, onAck) {
+    this.commandCounter = (this.commandCounter || 0) + 1;
+    const id = `${Date.now()}-${this.commandCounter}`;
+    if (onAck) this.pendingCommands[id] = onAck;
+    this.ws.send(JSON.stringify({ v: 2, type, id, payload }));
+    return id;
+  }
 
-    if (Array.isArray(message)) {
-      // Message history
-      this.messageHistory = message;
-      this.renderMessages(message);
-      return;
+  markRead(roomId, seq) {
+    if (this.ws && this.ws.readyState === WebSocket.OPEN && roomId && seq > 0) {
+      this.sendCommand('read', { room_id: roomId, seq });
     }
+  }
 
-    if (message.type === 'typing') {
-      this.handleTypingMessage(message);
-      return;
-    }
+  updateUnreadBadge(roomName, unread) {
+    const roomBtn = document.querySelector(`[data-room="${roomName}"]`);
+    if (!roomBtn) return;
+    roomBtn.dataset.unread = unread > 0 ? String(unread) : '';
+    roomBtn.classList.toggle('has-unread', unread > 0 && roomName !== this.currentRoom);
+  }
 
-    if (message.room === this.currentRoom) {
-      this.messageHistory.push(message);
-      this.renderMessage(message);
+  handleMessage(frame) {
+    const payload = frame.payload || {};
+
+    switch (frame.type) {
+      case 'ack': {
+        const onAck = this.pendingCommands[frame.id];
+        delete this.pendingCommands[frame.id];
+        if (onAck) onAck(payload);
+        break;
+      }
+      case 'unread': {
+        const roomName = this.roomNames[payload.room_id];
+        if (roomName) this.updateUnreadBadge(roomName, payload.unread);
+        break;
+      }
+      case 'message': {
+        const roomName = this.roomNames[payload.room_id];
+        if (roomName && (this.lastSeq[roomName] || 0) < payload.seq) {
+          this.lastSeq[roomName] = payload.seq;
+        }
+        // Other subscribed rooms only update their unread badge
+        if (roomName !== this.currentRoom) break;
+        this.markRead(payload.room_id, payload.seq);
+        // Thread replies stay in their thread, as in the room history
+        if (payload.parent_id) break;
+        const message = {
+          id: payload.id,
+          sender: payload.username,
+          content: payload.content,
+          timestamp: payload.created_at,
+          type: payload.type
+        };
+        this.messageHistory.push(message);
+        this.renderMessage(message);
+        break;
+      }
+      case 'room_removed': {
+        const roomName = this.roomNames[payload.room_id];
+        if (!roomName) break;
+        delete this.roomIds[roomName];
+        delete this.roomNames[payload.room_id];
+        this.updateUnreadBadge(roomName, 0);
+        if (payload.reason !== 'left') {
+          Utils.showNotification(`You were ${payload.reason === 'banned' ? 'banned' : 'removed'} from ${roomName}`, 'error');
+        }
+        break;
+      }
+      case 'typing':
+        if (this.roomNames[payload.room_id] !== this.currentRoom) break;
+        this.handleTypingMessage({ user: payload.username, isTyping: payload.is_typing });
+        break;
+      case 'error':
+        delete this.pendingCommands[frame.id];
+        Utils.showNotification(payload.message || 'Request failed', 'error');
+        break;
+      default:
+        break;
     }
   }
 
@@ -400,15 +467,9 @@ class ChatApp {
   }
 
   sendTypingStatus(isTyping) {
-    if (this.ws && this.ws.readyState === WebSocket.OPEN && this.currentRoom) {
-      const message = {
-        type: 'typing',
-        room: this.currentRoom,
-        user: this.user.username,
-        isTyping: isTyping
-      };
-
-      this.ws.send(JSON.stringify(message));
+    const roomId = this.roomIds[this.currentRoom];
+    if (this.ws && this.ws.readyState === WebSocket.OPEN && roomId) {
+      this.sendCommand('typing', { room_id: roomId, is_typing: isTyping });
     }
   }
 